@@ -1,40 +1,75 @@
 package adapter
 
 import (
+	"context"
+	"github.com/chippyash/go-cache-manager/event"
 	"github.com/chippyash/go-cache-manager/storage"
-	"regexp"
-	"strings"
+	"github.com/chippyash/go-cache-manager/tracing"
+	errs "github.com/pkg/errors"
+	"iter"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // AbstractAdapter is the abstract base adapter on which all adapters are built.
-// It implements the Storage interface
+// It implements the Storage, StorageContext, ReadThrough, Expirable, Taggable, Flushable, ClearExpiredable,
+// Clearable, Iterable, Capable, event.Capable and tracing.Traceable interfaces.
+// Every operation whose function is set fires pre, post and exception events to the listeners attached to its
+// event manager, see GetEventManager, is traced if there is a tracer, see SetTracer, and is logged if it fails or is
+// slow, see SetLogging
 type AbstractAdapter struct {
 	Name             string
 	Client           any
 	chained          storage.Storage
 	options          storage.StorageOptions
-	getItem          func(key string) (any, error)
-	getItems         func(keys []string) (map[string]any, error)
-	hasItem          func(key string) bool
-	hasItems         func(keys []string) map[string]bool
-	setItem          func(key string, value any) (bool, error)
-	setItems         func(values map[string]any) ([]string, error)
-	checkAndSetItem  func(key string, value any) (bool, error)
-	checkAndSetItems func(values map[string]any) ([]string, error)
-	touchItem        func(key string) bool
-	touchItems       func(keys []string) []string
-	removeItem       func(key string) bool
-	removeItems      func(keys []string) []string
-	increment        func(key string, n int64) (int64, error)
-	decrement        func(key string, n int64) (int64, error)
+	getItem          func(ctx context.Context, key string) (any, error)
+	getItems         func(ctx context.Context, keys []string) (map[string]any, error)
+	hasItem          func(ctx context.Context, key string) bool
+	hasItems         func(ctx context.Context, keys []string) map[string]bool
+	setItem          func(ctx context.Context, key string, value any) (bool, error)
+	setItems         func(ctx context.Context, values map[string]any) ([]string, error)
+	checkAndSetItem  func(ctx context.Context, key string, value any) (bool, error)
+	checkAndSetItems func(ctx context.Context, values map[string]any) ([]string, error)
+	touchItem        func(ctx context.Context, key string) bool
+	touchItems       func(ctx context.Context, keys []string) []string
+	removeItem       func(ctx context.Context, key string) bool
+	removeItems      func(ctx context.Context, keys []string) []string
+	increment        func(ctx context.Context, key string, n int64) (int64, error)
+	decrement        func(ctx context.Context, key string, n int64) (int64, error)
+	setItemWithTTL   func(ctx context.Context, key string, value any, ttl time.Duration) (bool, error)
+	setItemsWithTTL  func(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error)
+	touchItemWithTTL func(ctx context.Context, key string, ttl time.Duration) bool
+	getTTL           func(ctx context.Context, key string) (time.Duration, error)
+	setTags          func(ctx context.Context, key string, tags []string) (bool, error)
+	getTags          func(ctx context.Context, key string) ([]string, error)
+	clearByTags      func(ctx context.Context, tags []string, disjunction bool) (bool, error)
+	flush            func(ctx context.Context) error
+	clearExpired     func(ctx context.Context) error
+	clearByPrefix    func(ctx context.Context, prefix string) error
+	clearByPattern   func(ctx context.Context, pattern string) error
+	iterate          func(ctx context.Context, opts storage.IterateOptions) iter.Seq2[string, any]
+	iterateKeys      func(ctx context.Context, opts storage.IterateOptions) iter.Seq[string]
 	open             func() (storage.Storage, error)
 	close            func() error
+	flights          flightGroup
+	refreshes        sync.Map
+	getDeltas        func(ctx context.Context, keys []string) map[string]Delta
+	setDeltas        func(ctx context.Context, deltas map[string]time.Duration)
+	capabilities     storage.Capabilities
+	events           atomic.Pointer[event.Manager]
+	tracer           tracing.Tracer
+	logging          *Logging
+	keys             atomic.Pointer[KeyPolicy]
 }
 
 /** Storage Interface **/
 
+// SetOptions sets the options. Invalid options are logged, if logging is set, and returned as an error by Open
 func (a *AbstractAdapter) SetOptions(opts storage.StorageOptions) {
 	a.options = opts
+	a.keys.Store(NewKeyPolicy(opts))
+	a.LogError(context.TODO(), "invalid options", opts.Validate())
 }
 
 func (a *AbstractAdapter) GetOptions() storage.StorageOptions {
@@ -42,59 +77,193 @@ func (a *AbstractAdapter) GetOptions() storage.StorageOptions {
 }
 
 func (a *AbstractAdapter) GetItem(key string) (any, error) {
-	return a.getItem(key)
+	return a.GetItemCtx(context.TODO(), key)
 }
 
 func (a *AbstractAdapter) GetItems(keys []string) (map[string]any, error) {
-	return a.getItems(keys)
+	return a.GetItemsCtx(context.TODO(), keys)
 }
 
 func (a *AbstractAdapter) HasItem(key string) bool {
-	return a.hasItem(key)
+	return a.HasItemCtx(context.TODO(), key)
 }
 
 func (a *AbstractAdapter) HasItems(keys []string) map[string]bool {
-	return a.hasItems(keys)
+	return a.HasItemsCtx(context.TODO(), keys)
 }
 
 func (a *AbstractAdapter) SetItem(key string, value any) (bool, error) {
-	return a.setItem(key, value)
+	return a.SetItemCtx(context.TODO(), key, value)
 }
 
 func (a *AbstractAdapter) SetItems(values map[string]any) ([]string, error) {
-	return a.setItems(values)
+	return a.SetItemsCtx(context.TODO(), values)
 }
 
 func (a *AbstractAdapter) CheckAndSetItem(key string, value any) (bool, error) {
-	return a.checkAndSetItem(key, value)
+	return a.CheckAndSetItemCtx(context.TODO(), key, value)
 }
 
 func (a *AbstractAdapter) CheckAndSetItems(values map[string]any) ([]string, error) {
-	return a.checkAndSetItems(values)
+	return a.CheckAndSetItemsCtx(context.TODO(), values)
 }
 
 func (a *AbstractAdapter) TouchItem(key string) bool {
-	return a.touchItem(key)
+	return a.TouchItemCtx(context.TODO(), key)
 }
 
 func (a *AbstractAdapter) TouchItems(keys []string) []string {
-	return a.touchItems(keys)
+	return a.TouchItemsCtx(context.TODO(), keys)
 }
 
 func (a *AbstractAdapter) RemoveItem(key string) bool {
-	return a.removeItem(key)
+	return a.RemoveItemCtx(context.TODO(), key)
 }
 
 func (a *AbstractAdapter) RemoveItems(keys []string) []string {
-	return a.removeItems(keys)
+	return a.RemoveItemsCtx(context.TODO(), keys)
 }
 
 func (a *AbstractAdapter) Increment(key string, n int64) (int64, error) {
-	return a.increment(key, n)
+	return a.IncrementCtx(context.TODO(), key, n)
 }
 
 func (a *AbstractAdapter) Decrement(key string, n int64) (int64, error) {
-	return a.decrement(key, n)
+	return a.DecrementCtx(context.TODO(), key, n)
+}
+
+/** StorageContext Interface **/
+
+func (a *AbstractAdapter) GetItemCtx(ctx context.Context, key string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return trigger(ctx, a, storage.OpGetItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (any, error) {
+		return a.getItem(ctx, p.Key)
+	})
+}
+
+func (a *AbstractAdapter) GetItemsCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return map[string]any{}, err
+	}
+	return trigger(ctx, a, storage.OpGetItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) (map[string]any, error) {
+		return a.getItems(ctx, p.Keys)
+	})
+}
+
+func (a *AbstractAdapter) HasItemCtx(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	ret, _ := trigger(ctx, a, storage.OpHasItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.hasItem(ctx, p.Key), nil
+	})
+	return ret
+}
+
+func (a *AbstractAdapter) HasItemsCtx(ctx context.Context, keys []string) map[string]bool {
+	if ctx.Err() != nil {
+		return map[string]bool{}
+	}
+	ret, _ := trigger(ctx, a, storage.OpHasItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) (map[string]bool, error) {
+		return a.hasItems(ctx, p.Keys), nil
+	})
+	return ret
+}
+
+func (a *AbstractAdapter) SetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return trigger(ctx, a, storage.OpSetItem, event.Params{Key: key, Value: value}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.setItem(ctx, p.Key, p.Value)
+	})
+}
+
+func (a *AbstractAdapter) SetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	return trigger(ctx, a, storage.OpSetItems, event.Params{Values: values}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return a.setItems(ctx, p.Values)
+	})
+}
+
+func (a *AbstractAdapter) CheckAndSetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return trigger(ctx, a, storage.OpCheckAndSetItem, event.Params{Key: key, Value: value}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.checkAndSetItem(ctx, p.Key, p.Value)
+	})
+}
+
+func (a *AbstractAdapter) CheckAndSetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	return trigger(ctx, a, storage.OpCheckAndSetItems, event.Params{Values: values}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return a.checkAndSetItems(ctx, p.Values)
+	})
+}
+
+func (a *AbstractAdapter) TouchItemCtx(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	ret, _ := trigger(ctx, a, storage.OpTouchItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.touchItem(ctx, p.Key), nil
+	})
+	return ret
+}
+
+func (a *AbstractAdapter) TouchItemsCtx(ctx context.Context, keys []string) []string {
+	if ctx.Err() != nil {
+		return []string{}
+	}
+	ret, _ := trigger(ctx, a, storage.OpTouchItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return a.touchItems(ctx, p.Keys), nil
+	})
+	return ret
+}
+
+func (a *AbstractAdapter) RemoveItemCtx(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	ret, _ := trigger(ctx, a, storage.OpRemoveItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.removeItem(ctx, p.Key), nil
+	})
+	return ret
+}
+
+func (a *AbstractAdapter) RemoveItemsCtx(ctx context.Context, keys []string) []string {
+	if ctx.Err() != nil {
+		return []string{}
+	}
+	ret, _ := trigger(ctx, a, storage.OpRemoveItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return a.removeItems(ctx, p.Keys), nil
+	})
+	return ret
+}
+
+func (a *AbstractAdapter) IncrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return trigger(ctx, a, storage.OpIncrement, event.Params{Key: key, N: n}, func(ctx context.Context, p *event.Params) (int64, error) {
+		return a.increment(ctx, p.Key, p.N)
+	})
+}
+
+func (a *AbstractAdapter) DecrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return trigger(ctx, a, storage.OpDecrement, event.Params{Key: key, N: n}, func(ctx context.Context, p *event.Params) (int64, error) {
+		return a.decrement(ctx, p.Key, p.N)
+	})
 }
 
 /** Chainable Interface **/
@@ -108,7 +277,19 @@ func (a *AbstractAdapter) GetChained() storage.Storage {
 	return a.chained
 }
 
+// GetChainedCtx returns the chained adapter as a StorageContext, or nil if there is no chained adapter
+func (a *AbstractAdapter) GetChainedCtx() storage.StorageContext {
+	if a.chained == nil {
+		return nil
+	}
+	return storage.WithContext(a.chained)
+}
+
+// Open validates the options, and opens the adapter
 func (a *AbstractAdapter) Open() (storage.Storage, error) {
+	if err := a.options.Validate(); err != nil {
+		return nil, errs.Wrap(err, "invalid options")
+	}
 	return a.open()
 }
 
@@ -118,102 +299,150 @@ func (a *AbstractAdapter) Close() error {
 
 /** Utility functions **/
 
-// NamespacedKey returns the key suffixed with namespace if any
-func (a *AbstractAdapter) NamespacedKey(key string) string {
-	ns := a.options[storage.OptNamespace].(string)
-	if ns != "" {
-		key = ns + key
+// KeyPolicy returns the policy that maps keys to backend keys, created from the options when they were set, or
+// created again if the key options have since been changed in the map returned by GetOptions
+func (a *AbstractAdapter) KeyPolicy() *KeyPolicy {
+	if p := a.keys.Load(); p != nil && p.current(a.options) {
+		return p
 	}
-	return key
+	p := NewKeyPolicy(a.options)
+	a.keys.Store(p)
+	return p
 }
 
+// NamespacedKey returns the backend key for the key: the key prefixed with the namespace if any, encoded or hashed
+// by the KeyPolicy
+func (a *AbstractAdapter) NamespacedKey(key string) string {
+	return a.KeyPolicy().BackendKey(key)
+}
+
+// NamespacedPrefix returns the prefix of the backend keys of the keys with the prefix, see KeyPolicy.BackendPrefix
+func (a *AbstractAdapter) NamespacedPrefix(prefix string) string {
+	return a.KeyPolicy().BackendPrefix(prefix)
+}
+
+// StripNamespace returns the key for a backend key returned by NamespacedKey
 func (a *AbstractAdapter) StripNamespace(key string) string {
-	ns := a.options[storage.OptNamespace].(string)
-	if ns != "" {
-		key = strings.Replace(key, ns, "", 1)
-	}
-	return key
+	return a.KeyPolicy().Key(key)
 }
 
-// ValidateKey validates the key against the regex pattern in options[storage.OptKeyPattern] if any
+// Codec returns the value codec in options[storage.OptCodec] if any, else nil
+func (a *AbstractAdapter) Codec() storage.Codec {
+	return a.options.Codec()
+}
+
+// ValidateKey validates the backend key returned by NamespacedKey against the regex pattern in
+// options[storage.OptKeyPattern] if any. The pattern is matched against the namespaced key before it is encoded, but
+// after it is hashed, as the hash cannot be reversed, so adapters validate keys with ValidateItemKey. Backend keys that
+// start with ReservedKeyPrefix are invalid
 func (a *AbstractAdapter) ValidateKey(key string) bool {
-	p := a.options[storage.OptKeyPattern].(string)
-	if p == "" {
-		return true
-	}
-	re := regexp.MustCompile(p)
-	return re.MatchString(key)
+	return a.KeyPolicy().Valid(a.StripNamespace(key))
+}
+
+// ValidateItemKey validates the key against the regex pattern in options[storage.OptKeyPattern] if any. The pattern
+// is matched against the namespaced key before it is encoded or hashed. Keys whose backend key starts with
+// ReservedKeyPrefix are invalid
+func (a *AbstractAdapter) ValidateItemKey(key string) bool {
+	return a.KeyPolicy().Valid(key)
 }
 
 /** Setters for the Storage interface functions **/
 
 func (a *AbstractAdapter) SetGetItemFunc(f func(key string) (any, error)) *AbstractAdapter {
-	a.getItem = f
+	a.getItem = func(_ context.Context, key string) (any, error) {
+		return f(key)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetGetItemsFunc(f func(keys []string) (map[string]any, error)) *AbstractAdapter {
-	a.getItems = f
+	a.getItems = func(_ context.Context, keys []string) (map[string]any, error) {
+		return f(keys)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetSetItemFunc(f func(key string, value any) (bool, error)) *AbstractAdapter {
-	a.setItem = f
+	a.setItem = func(_ context.Context, key string, value any) (bool, error) {
+		return f(key, value)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetSetItemsFunc(f func(values map[string]any) ([]string, error)) *AbstractAdapter {
-	a.setItems = f
+	a.setItems = func(_ context.Context, values map[string]any) ([]string, error) {
+		return f(values)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetHasItemFunc(f func(key string) bool) *AbstractAdapter {
-	a.hasItem = f
+	a.hasItem = func(_ context.Context, key string) bool {
+		return f(key)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetHasItemsFunc(f func(keys []string) map[string]bool) *AbstractAdapter {
-	a.hasItems = f
+	a.hasItems = func(_ context.Context, keys []string) map[string]bool {
+		return f(keys)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetCheckAndSetItemFunc(f func(key string, value any) (bool, error)) *AbstractAdapter {
-	a.checkAndSetItem = f
+	a.checkAndSetItem = func(_ context.Context, key string, value any) (bool, error) {
+		return f(key, value)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetCheckAndSetItemsFunc(f func(values map[string]any) ([]string, error)) *AbstractAdapter {
-	a.checkAndSetItems = f
+	a.checkAndSetItems = func(_ context.Context, values map[string]any) ([]string, error) {
+		return f(values)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetTouchItemFunc(f func(key string) bool) *AbstractAdapter {
-	a.touchItem = f
+	a.touchItem = func(_ context.Context, key string) bool {
+		return f(key)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetTouchItemsFunc(f func(keys []string) []string) *AbstractAdapter {
-	a.touchItems = f
+	a.touchItems = func(_ context.Context, keys []string) []string {
+		return f(keys)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetRemoveItemFunc(f func(key string) bool) *AbstractAdapter {
-	a.removeItem = f
+	a.removeItem = func(_ context.Context, key string) bool {
+		return f(key)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetRemoveItemsFunc(f func(keys []string) []string) *AbstractAdapter {
-	a.removeItems = f
+	a.removeItems = func(_ context.Context, keys []string) []string {
+		return f(keys)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetIncrementFunc(f func(key string, n int64) (int64, error)) *AbstractAdapter {
-	a.increment = f
+	a.increment = func(_ context.Context, key string, n int64) (int64, error) {
+		return f(key, n)
+	}
 	return a
 }
 
 func (a *AbstractAdapter) SetDecrementFunc(f func(key string, n int64) (int64, error)) *AbstractAdapter {
-	a.decrement = f
+	a.decrement = func(_ context.Context, key string, n int64) (int64, error) {
+		return f(key, n)
+	}
 	return a
 }
 
@@ -226,3 +455,75 @@ func (a *AbstractAdapter) SetCloseFunc(f func() error) *AbstractAdapter {
 	a.close = f
 	return a
 }
+
+/** Setters for the StorageContext interface functions **/
+
+func (a *AbstractAdapter) SetGetItemCtxFunc(f func(ctx context.Context, key string) (any, error)) *AbstractAdapter {
+	a.getItem = f
+	return a
+}
+
+func (a *AbstractAdapter) SetGetItemsCtxFunc(f func(ctx context.Context, keys []string) (map[string]any, error)) *AbstractAdapter {
+	a.getItems = f
+	return a
+}
+
+func (a *AbstractAdapter) SetSetItemCtxFunc(f func(ctx context.Context, key string, value any) (bool, error)) *AbstractAdapter {
+	a.setItem = f
+	return a
+}
+
+func (a *AbstractAdapter) SetSetItemsCtxFunc(f func(ctx context.Context, values map[string]any) ([]string, error)) *AbstractAdapter {
+	a.setItems = f
+	return a
+}
+
+func (a *AbstractAdapter) SetHasItemCtxFunc(f func(ctx context.Context, key string) bool) *AbstractAdapter {
+	a.hasItem = f
+	return a
+}
+
+func (a *AbstractAdapter) SetHasItemsCtxFunc(f func(ctx context.Context, keys []string) map[string]bool) *AbstractAdapter {
+	a.hasItems = f
+	return a
+}
+
+func (a *AbstractAdapter) SetCheckAndSetItemCtxFunc(f func(ctx context.Context, key string, value any) (bool, error)) *AbstractAdapter {
+	a.checkAndSetItem = f
+	return a
+}
+
+func (a *AbstractAdapter) SetCheckAndSetItemsCtxFunc(f func(ctx context.Context, values map[string]any) ([]string, error)) *AbstractAdapter {
+	a.checkAndSetItems = f
+	return a
+}
+
+func (a *AbstractAdapter) SetTouchItemCtxFunc(f func(ctx context.Context, key string) bool) *AbstractAdapter {
+	a.touchItem = f
+	return a
+}
+
+func (a *AbstractAdapter) SetTouchItemsCtxFunc(f func(ctx context.Context, keys []string) []string) *AbstractAdapter {
+	a.touchItems = f
+	return a
+}
+
+func (a *AbstractAdapter) SetRemoveItemCtxFunc(f func(ctx context.Context, key string) bool) *AbstractAdapter {
+	a.removeItem = f
+	return a
+}
+
+func (a *AbstractAdapter) SetRemoveItemsCtxFunc(f func(ctx context.Context, keys []string) []string) *AbstractAdapter {
+	a.removeItems = f
+	return a
+}
+
+func (a *AbstractAdapter) SetIncrementCtxFunc(f func(ctx context.Context, key string, n int64) (int64, error)) *AbstractAdapter {
+	a.increment = f
+	return a
+}
+
+func (a *AbstractAdapter) SetDecrementCtxFunc(f func(ctx context.Context, key string, n int64) (int64, error)) *AbstractAdapter {
+	a.decrement = f
+	return a
+}
@@ -0,0 +1,62 @@
+package bucket
+
+import (
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"strings"
+)
+
+func init() {
+	storage.Register("s3", FromDSN)
+	storage.RegisterParams("s3", storage.ParamsFactory{
+		Params: map[string]storage.Kind{
+			"bucket":   storage.KindString,
+			"suffix":   storage.KindString,
+			"mimeType": storage.KindString,
+			"region":   storage.KindString,
+		},
+		Required: []string{"bucket"},
+		New:      FromParams,
+	})
+}
+
+// FromDSN creates an S3 adapter from a DSN, e.g. "s3://bucket/prefix/?suffix=.json&region=eu-west-2".
+// The mimeType parameter defaults to MimeTypeJson if the suffix is .json, else MimeTypeText
+func FromDSN(dsn storage.DSN) (storage.Storage, error) {
+	if err := dsn.Validate("suffix", "mimeType", "region"); err != nil {
+		return nil, err
+	}
+	if dsn.Host == "" {
+		return nil, errs.Wrap(errors.ErrInvalidDSN, "missing bucket")
+	}
+	suffix := dsn.Param("suffix", "")
+	mimeType := MimeTypeText
+	if strings.EqualFold(suffix, ".json") {
+		mimeType = MimeTypeJson
+	}
+	return New(
+		dsn.Host,
+		strings.TrimPrefix(dsn.Path, "/"),
+		suffix,
+		dsn.Param("mimeType", mimeType),
+		dsn.Param("region", ""),
+	)
+}
+
+// FromParams creates an S3 adapter from named parameters, e.g. the options of a tier in a configuration file.
+// The namespace is the key prefix, and the mimeType defaults to MimeTypeJson if the suffix is .json, else MimeTypeText
+func FromParams(p storage.Params) (storage.Storage, error) {
+	suffix := p.String("suffix", "")
+	mimeType := MimeTypeText
+	if strings.EqualFold(suffix, ".json") {
+		mimeType = MimeTypeJson
+	}
+	return New(
+		p.String("bucket", ""),
+		p.String("namespace", ""),
+		suffix,
+		p.String("mimeType", mimeType),
+		p.String("region", ""),
+	)
+}
@@ -0,0 +1,17 @@
+package bucket
+
+import (
+	"github.com/chippyash/go-cache-manager/storage"
+)
+
+func init() {
+	storage.DefineOption(OptS3Bucket, "bucket", func(bucket string) string {
+		if bucket == "" {
+			return "must not be empty"
+		}
+		return ""
+	})
+	storage.DefineOption[string](OptS3Suffix, "suffix", nil)
+	storage.DefineOption[string](OptS3MimeType, "mimeType", nil)
+	storage.DefineOption[string](OptS3Region, "region", nil)
+}
@@ -4,18 +4,24 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	adapter2 "github.com/chippyash/go-cache-manager/adapter"
 	"github.com/chippyash/go-cache-manager/errors"
 	"github.com/chippyash/go-cache-manager/storage"
 	errs "github.com/pkg/errors"
 	"io"
+	"iter"
+	"net/url"
+	"strings"
+	"time"
 )
 
 const (
 	//OptS3Bucket s3 bucket name
-	OptS3Bucket = iota + storage.OptDataTypes + 1
+	OptS3Bucket = iota + storage.OptRangeS3
 	//OptS3Suffix object key suffix e.g. '.json'
 	OptS3Suffix
 	//OptS3MimeType object mime type e.g. 'application/json'. Use MimeTypeJson or MimeTypeText
@@ -23,12 +29,37 @@ const (
 	//OptS3Region AWS region e.g. 'eu-west-2'
 	OptS3Region
 )
+
+// keyMetadata is the object metadata holding the key of a hashed object key, escaped as metadata is ASCII only
+const keyMetadata = "gcm-key"
+
+// errStopIteration stops a listing when the iterator consumer stops
+var errStopIteration = errs.New("stop iteration")
+
 const (
 	MimeTypeJson = "application/json"
 	MimeTypeText = "text/plain"
 )
 
-func New(bucket string, prefix string, suffix string, mimeType string, region string) (storage.Storage, error) {
+// hashSuffixed reduces the length above which key hashing hashes a key by the length of the object key suffix, as
+// the suffix is added to the backend key to make the object key, whose length is checked against OptMaxKeyLength
+func hashSuffixed(opts storage.StorageOptions) {
+	h, ok := opts.KeyHashing()
+	if !ok {
+		return
+	}
+	if h.MaxLength == 0 {
+		h.MaxLength = opts.MaxKeyLength()
+	}
+	if h.MaxLength > 0 {
+		h.MaxLength = max(h.MaxLength-len(opts.String(OptS3Suffix, "")), 1)
+	}
+	opts[storage.OptKeyHashing] = h
+}
+
+// New returns an S3 bucket adapter, or an error if the options are invalid or the AWS config cannot be loaded.
+// The options, e.g. storage.WithCodec(), change the defaults
+func New(bucket string, prefix string, suffix string, mimeType string, region string, options ...storage.Option) (storage.Storage, error) {
 	//set the options
 	dTypes := storage.DataTypes{
 		storage.TypeUnknown:   false,
@@ -39,7 +70,7 @@ func New(bucket string, prefix string, suffix string, mimeType string, region st
 		storage.TypeInteger32: false,
 		storage.TypeInteger64: false,
 		storage.TypeUint:      false,
-		storage.TypeUint8:     true,
+		storage.TypeUint8:     false,
 		storage.TypeUint16:    false,
 		storage.TypeUint32:    false,
 		storage.TypeUint64:    false,
@@ -48,7 +79,7 @@ func New(bucket string, prefix string, suffix string, mimeType string, region st
 		storage.TypeString:    true,
 		storage.TypeDuration:  false,
 		storage.TypeTime:      false,
-		storage.TypeBytes:     false,
+		storage.TypeBytes:     true,
 	}
 
 	opts := storage.StorageOptions{
@@ -59,14 +90,19 @@ func New(bucket string, prefix string, suffix string, mimeType string, region st
 		storage.OptMaxKeyLength:   0,
 		storage.OptMaxValueLength: 0,
 		storage.OptDataTypes:      dTypes,
+		storage.OptCodec:          nil,
 		OptS3Bucket:               bucket,
 		OptS3Suffix:               suffix,
 		OptS3MimeType:             mimeType,
 		OptS3Region:               region,
+	}.Apply(options...)
+	if err := opts.Validate(); err != nil {
+		return nil, errs.Wrap(err, "invalid options")
 	}
+	hashSuffixed(opts)
 
 	//aws setup
-	awsConfig, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	awsConfig, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(opts.String(OptS3Region, "")))
 	if err != nil {
 		return nil, errs.Wrap(err, "failed to load aws config")
 	}
@@ -76,48 +112,208 @@ func New(bucket string, prefix string, suffix string, mimeType string, region st
 	adapter.Client = s3.NewFromConfig(awsConfig)
 	adapter.SetOptions(opts)
 
+	//expired returns true if the object Expires time has passed
+	expired := func(expires *time.Time) bool {
+		return expires != nil && !expires.After(time.Now())
+	}
+
+	//listObjects calls fn with each page of object keys with the namespaced prefix that have the object key suffix.
+	//maxKeys is the page size, or the S3 default of 1000 if it is 0
+	listObjects := func(ctx context.Context, prefix string, maxKeys int32, fn func(keys []string) error) error {
+		bckt := adapter.GetOptions().String(OptS3Bucket, "")
+		prefix = adapter.NamespacedPrefix(prefix)
+		suffix := adapter.GetOptions().String(OptS3Suffix, "")
+		input := &s3.ListObjectsV2Input{
+			Bucket: &bckt,
+			Prefix: &prefix,
+		}
+		if maxKeys > 0 {
+			input.MaxKeys = &maxKeys
+		}
+		for {
+			out, err := adapter.Client.(S3Iface).ListObjectsV2(ctx, input)
+			if err != nil {
+				return errs.Wrap(err, "failed to list objects")
+			}
+			keys := make([]string, 0, len(out.Contents))
+			for _, obj := range out.Contents {
+				if obj.Key != nil && strings.HasSuffix(*obj.Key, suffix) {
+					keys = append(keys, *obj.Key)
+				}
+			}
+			if len(keys) > 0 {
+				if err = fn(keys); err != nil {
+					return err
+				}
+			}
+			if out.IsTruncated == nil || !*out.IsTruncated {
+				return nil
+			}
+			input.ContinuationToken = out.NextContinuationToken
+		}
+	}
+	//deleteObjects deletes the objects. A list page is at most 1000 objects, which is also the DeleteObjects limit
+	deleteObjects := func(ctx context.Context, keys []string) error {
+		bckt := adapter.GetOptions().String(OptS3Bucket, "")
+		ids := make([]types.ObjectIdentifier, len(keys))
+		for i := range keys {
+			ids[i] = types.ObjectIdentifier{Key: &keys[i]}
+		}
+		out, err := adapter.Client.(S3Iface).DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: &bckt,
+			Delete: &types.Delete{
+				Objects: ids,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return errs.Wrap(err, "failed to delete objects")
+		}
+		if len(out.Errors) > 0 {
+			return fmt.Errorf("failed to delete %d objects: %s", len(out.Errors), aws.ToString(out.Errors[0].Message))
+		}
+		return nil
+	}
+
+	//objectKey returns the item key for the object key, looking up the key of a hashed key in the object metadata
+	objectKey := func(ctx context.Context, objKey string) string {
+		nsKey := strings.TrimSuffix(objKey, adapter.GetOptions().String(OptS3Suffix, ""))
+		if adapter.KeyPolicy().Hashed(nsKey) {
+			bckt := adapter.GetOptions().String(OptS3Bucket, "")
+			out, err := adapter.Client.(S3Iface).HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: &bckt,
+				Key:    &objKey,
+			})
+			if err == nil {
+				if key, err := url.QueryUnescape(out.Metadata[keyMetadata]); err == nil && key != "" {
+					return key
+				}
+			}
+		}
+		return adapter.StripNamespace(nsKey)
+	}
+
 	//isString := func(mimeType string) bool {
 	//	return strings.HasPrefix(mimeType, "text")
 	//}
 
+	//setItem puts the object with the ttl, passing the ttl on to the chained adapter
+	setItem := func(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+		if !adapter.GetOptions().Writable() {
+			return false, errors.ErrNotWritable
+		}
+		if err := adapter.CheckDataType(key, value); err != nil {
+			return false, err
+		}
+		nsKey := adapter.NamespacedKey(key)
+		if !adapter.ValidateItemKey(key) {
+			return false, errors.ErrKeyInvalid
+		}
+		nsKey = nsKey + adapter.GetOptions().String(OptS3Suffix, "")
+		if err := adapter.CheckKeyLength(key, nsKey); err != nil {
+			return false, err
+		}
+		bckt := adapter.GetOptions().String(OptS3Bucket, "")
+		mtype := adapter.GetOptions().String(OptS3MimeType, "")
+		//convert value []byte dependent on its actual type or use the codec if there is one
+		var v []byte
+		switch val := value.(type) {
+		case string:
+			v = []byte(val)
+		case []byte:
+			v = val
+		}
+		if codec := adapter.Codec(); codec != nil {
+			b, err := codec.Encode(value)
+			if err != nil {
+				return false, err
+			}
+			v = b
+		}
+		if err := adapter.CheckValueSize(key, len(v)); err != nil {
+			return false, err
+		}
+		input := &s3.PutObjectInput{
+			Bucket:      &bckt,
+			Key:         &nsKey,
+			Body:        bytes.NewReader(v),
+			ContentType: &mtype,
+		}
+		//the key of a hashed key is kept with the object
+		if adapter.KeyPolicy().Hashed(adapter.NamespacedKey(key)) {
+			input.Metadata = map[string]string{keyMetadata: url.QueryEscape(key)}
+		}
+		//the Expires time is enforced on read. Use a bucket lifecycle rule to delete expired objects
+		if resolved := adapter.ResolveTTL(ttl); resolved != storage.NoExpiry {
+			expires := time.Now().Add(resolved)
+			input.Expires = &expires
+		}
+		_, err := adapter.Client.(S3Iface).PutObject(ctx, input)
+		if err != nil {
+			return false, errs.Wrap(err, "failed to put object")
+		}
+		if adapter.GetChained() != nil {
+			if _, err = storage.SetItemWithTTL(ctx, adapter.GetChained(), key, value, ttl); err != nil {
+				adapter.LogError(ctx, "failed to set item in chained adapter", err, key)
+			}
+		}
+		return true, nil
+	}
+
 	//set the functions
 	adapter.
-		SetGetItemFunc(func(key string) (any, error) {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
+		SetGetItemCtxFunc(func(ctx context.Context, key string) (any, error) {
+			if !adapter.GetOptions().Readable() {
 				return nil, errors.ErrNotReadable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return nil, errors.ErrKeyInvalid
 			}
-			nsKey = nsKey + adapter.GetOptions()[OptS3Suffix].(string)
-			bckt := adapter.GetOptions()[OptS3Bucket].(string)
+			nsKey = nsKey + adapter.GetOptions().String(OptS3Suffix, "")
+			bckt := adapter.GetOptions().String(OptS3Bucket, "")
 			input := &s3.GetObjectInput{
 				Bucket: &bckt,
 				Key:    &nsKey,
 			}
-			out, err := adapter.Client.(S3Iface).GetObject(context.TODO(), input)
+			out, err := adapter.Client.(S3Iface).GetObject(ctx, input)
+			if err == nil && expired(out.Expires) {
+				_ = out.Body.Close()
+				err = errs.New("object has expired")
+			}
 			if err != nil {
 				if adapter.GetChained() != nil {
-					val, err := adapter.GetChained().GetItem(key)
+					val, err := adapter.GetChainedCtx().GetItemCtx(ctx, key)
 					if err != nil {
+						if ctx.Err() != nil {
+							return nil, ctx.Err()
+						}
 						return nil, errors.ErrKeyNotFound
 					}
-					_, e := adapter.SetItem(key, val)
+					_, e := adapter.SetItemCtx(ctx, key, val)
 					return val, e
 				}
 				return nil, errs.Wrap(errors.ErrKeyNotFound, err.Error())
 			}
 
 			ret, err := io.ReadAll(out.Body)
-			return string(ret), err
+			if err != nil {
+				return nil, errs.Wrap(err, "failed to read object")
+			}
+			if codec := adapter.Codec(); codec != nil {
+				return codec.Decode(ret)
+			}
+			return string(ret), nil
 		}).
-		SetGetItemsFunc(func(keys []string) (map[string]any, error) {
+		SetGetItemsCtxFunc(func(ctx context.Context, keys []string) (map[string]any, error) {
 			ret := make(map[string]any)
 			var err error
 			for _, key := range keys {
-				val, found := adapter.GetItem(key)
+				val, found := adapter.GetItemCtx(ctx, key)
 				if found != nil {
+					if ctx.Err() != nil {
+						return ret, ctx.Err()
+					}
 					if errs.Is(found, errors.ErrKeyInvalid) || errs.Is(found, errors.ErrNotReadable) {
 						err = found
 						continue
@@ -129,49 +325,15 @@ func New(bucket string, prefix string, suffix string, mimeType string, region st
 			}
 			return ret, err
 		}).
-		SetSetItemFunc(func(key string, value any) (bool, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
-				return false, errors.ErrNotWritable
-			}
-			t := storage.GetType(value)
-			if !adapter.GetOptions()[storage.OptDataTypes].(storage.DataTypes)[t] {
-				return false, errs.Wrap(errors.ErrUnsupportedDataType, fmt.Sprintf("key: %s type: %d, value: %v", key, t, value))
-			}
-			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
-				return false, errors.ErrKeyInvalid
-			}
-			nsKey = nsKey + adapter.GetOptions()[OptS3Suffix].(string)
-			bckt := adapter.GetOptions()[OptS3Bucket].(string)
-			mtype := adapter.GetOptions()[OptS3MimeType].(string)
-			//convert value []byte dependent on its actual type
-			var v []byte
-			if t == storage.TypeString {
-				v = []byte(value.(string))
-			} else {
-				v = value.([]byte)
-			}
-			input := &s3.PutObjectInput{
-				Bucket:      &bckt,
-				Key:         &nsKey,
-				Body:        bytes.NewReader(v),
-				ContentType: &mtype,
-			}
-			_, err := adapter.Client.(S3Iface).PutObject(context.TODO(), input)
-			if err != nil {
-				return false, errs.Wrap(err, "failed to put object")
-			}
-			if adapter.GetChained() != nil {
-				_, _ = adapter.GetChained().SetItem(key, value)
-			}
-			return true, nil
+		SetSetItemCtxFunc(func(ctx context.Context, key string, value any) (bool, error) {
+			return setItem(ctx, key, value, storage.DefaultTTL)
 		}).
-		SetSetItemsFunc(func(values map[string]any) ([]string, error) {
+		SetSetItemsCtxFunc(func(ctx context.Context, values map[string]any) ([]string, error) {
 			keys := make([]string, len(values))
 			i := 0
 			var err error
 			for key, value := range values {
-				_, e := adapter.SetItem(key, value)
+				_, e := adapter.SetItemCtx(ctx, key, value)
 				if e != nil {
 					err = e
 				}
@@ -180,81 +342,81 @@ func New(bucket string, prefix string, suffix string, mimeType string, region st
 			}
 			return keys, err
 		}).
-		SetHasItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
+		SetHasItemCtxFunc(func(ctx context.Context, key string) bool {
+			if !adapter.GetOptions().Readable() {
 				return false
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false
 			}
-			nsKey = nsKey + adapter.GetOptions()[OptS3Suffix].(string)
-			bckt := adapter.GetOptions()[OptS3Bucket].(string)
+			nsKey = nsKey + adapter.GetOptions().String(OptS3Suffix, "")
+			bckt := adapter.GetOptions().String(OptS3Bucket, "")
 			input := &s3.HeadObjectInput{
 				Bucket: &bckt,
 				Key:    &nsKey,
 			}
-			_, err := adapter.Client.(S3Iface).HeadObject(context.TODO(), input)
-			if err != nil {
+			out, err := adapter.Client.(S3Iface).HeadObject(ctx, input)
+			if err != nil || expired(out.Expires) {
 				if adapter.GetChained() != nil {
-					return adapter.GetChained().HasItem(key)
+					return adapter.GetChainedCtx().HasItemCtx(ctx, key)
 				}
 				return false
 			}
 
 			return true
 		}).
-		SetHasItemsFunc(func(keys []string) map[string]bool {
+		SetHasItemsCtxFunc(func(ctx context.Context, keys []string) map[string]bool {
 			ret := make(map[string]bool)
 			for _, key := range keys {
-				ret[key] = adapter.HasItem(key)
+				ret[key] = adapter.HasItemCtx(ctx, key)
 			}
 			return ret
 		}).
-		SetCheckAndSetItemFunc(func(key string, value any) (bool, error) {
+		SetCheckAndSetItemCtxFunc(func(ctx context.Context, key string, value any) (bool, error) {
 			return false, errors.ErrNotImplemented
 		}).
-		SetCheckAndSetItemsFunc(func(values map[string]any) ([]string, error) {
+		SetCheckAndSetItemsCtxFunc(func(ctx context.Context, values map[string]any) ([]string, error) {
 			return make([]string, 0), errors.ErrNotImplemented
 		}).
-		SetTouchItemFunc(func(key string) bool {
+		SetTouchItemCtxFunc(func(ctx context.Context, key string) bool {
 			//errors.ErrNotImplemented
 			return false
 		}).
-		SetTouchItemsFunc(func(keys []string) []string {
+		SetTouchItemsCtxFunc(func(ctx context.Context, keys []string) []string {
 			//errors.ErrNotImplemented
 			return make([]string, 0)
 		}).
-		SetRemoveItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetRemoveItemCtxFunc(func(ctx context.Context, key string) bool {
+			if !adapter.GetOptions().Writable() {
 				return false
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false
 			}
-			nsKey = nsKey + adapter.GetOptions()[OptS3Suffix].(string)
-			bckt := adapter.GetOptions()[OptS3Bucket].(string)
+			nsKey = nsKey + adapter.GetOptions().String(OptS3Suffix, "")
+			bckt := adapter.GetOptions().String(OptS3Bucket, "")
 			input := &s3.DeleteObjectInput{
 				Bucket: &bckt,
 				Key:    &nsKey,
 			}
-			out, err := adapter.Client.(S3Iface).DeleteObject(context.TODO(), input)
+			out, err := adapter.Client.(S3Iface).DeleteObject(ctx, input)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().RemoveItem(key)
+				return adapter.GetChainedCtx().RemoveItemCtx(ctx, key)
 			}
 			return *out.DeleteMarker && err == nil
 		}).
-		SetRemoveItemsFunc(func(keys []string) []string {
+		SetRemoveItemsCtxFunc(func(ctx context.Context, keys []string) []string {
 			for _, key := range keys {
-				adapter.RemoveItem(key)
+				adapter.RemoveItemCtx(ctx, key)
 			}
 			return keys
 		}).
-		SetIncrementFunc(func(key string, n int64) (int64, error) {
+		SetIncrementCtxFunc(func(ctx context.Context, key string, n int64) (int64, error) {
 			return 0, errors.ErrNotImplemented
 		}).
-		SetDecrementFunc(func(key string, n int64) (int64, error) {
+		SetDecrementCtxFunc(func(ctx context.Context, key string, n int64) (int64, error) {
 			return 0, errors.ErrNotImplemented
 		}).
 		SetOpenFunc(func() (storage.Storage, error) {
@@ -262,6 +424,192 @@ func New(bucket string, prefix string, suffix string, mimeType string, region st
 		}).
 		SetCloseFunc(func() error {
 			return nil
+		}).
+		SetSetItemWithTTLFunc(setItem).
+		SetSetItemsWithTTLFunc(func(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error) {
+			keys := make([]string, len(values))
+			i := 0
+			var err error
+			for key, value := range values {
+				_, e := adapter.SetItemWithTTLCtx(ctx, key, value, ttl)
+				if e != nil {
+					err = e
+				}
+				keys[i] = key
+				i++
+			}
+			return keys, err
+		}).
+		SetTouchItemWithTTLFunc(func(ctx context.Context, key string, ttl time.Duration) bool {
+			//errors.ErrNotImplemented
+			return false
+		}).
+		SetGetTTLFunc(func(ctx context.Context, key string) (time.Duration, error) {
+			if !adapter.GetOptions().Readable() {
+				return 0, errors.ErrNotReadable
+			}
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return 0, errors.ErrKeyInvalid
+			}
+			nsKey = nsKey + adapter.GetOptions().String(OptS3Suffix, "")
+			bckt := adapter.GetOptions().String(OptS3Bucket, "")
+			input := &s3.HeadObjectInput{
+				Bucket: &bckt,
+				Key:    &nsKey,
+			}
+			out, err := adapter.Client.(S3Iface).HeadObject(ctx, input)
+			if err != nil {
+				return 0, errs.Wrap(errors.ErrKeyNotFound, err.Error())
+			}
+			if out.Expires == nil {
+				return storage.NoExpiry, nil
+			}
+			if expired(out.Expires) {
+				return 0, errors.ErrKeyNotFound
+			}
+			return time.Until(*out.Expires), nil
+		}).
+		SetFlushFunc(func(ctx context.Context) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			return listObjects(ctx, "", 0, func(keys []string) error {
+				return deleteObjects(ctx, keys)
+			})
+		}).
+		SetClearExpiredFunc(func(ctx context.Context) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			bckt := adapter.GetOptions().String(OptS3Bucket, "")
+			//the Expires time is not returned by ListObjectsV2, so each object has to be checked
+			return listObjects(ctx, "", 0, func(keys []string) error {
+				expiredKeys := make([]string, 0)
+				for _, key := range keys {
+					out, err := adapter.Client.(S3Iface).HeadObject(ctx, &s3.HeadObjectInput{
+						Bucket: &bckt,
+						Key:    aws.String(key),
+					})
+					if err != nil {
+						continue
+					}
+					if expired(out.Expires) {
+						expiredKeys = append(expiredKeys, key)
+					}
+				}
+				if len(expiredKeys) == 0 {
+					return nil
+				}
+				return deleteObjects(ctx, expiredKeys)
+			})
+		}).
+		SetClearByPrefixFunc(func(ctx context.Context, prefix string) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			err := listObjects(ctx, prefix, 0, func(keys []string) error {
+				return deleteObjects(ctx, keys)
+			})
+			if c, ok := adapter.GetChained().(storage.Clearable); ok && err == nil {
+				return c.ClearByPrefixCtx(ctx, prefix)
+			}
+			return err
+		}).
+		SetClearByPatternFunc(func(ctx context.Context, pattern string) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			err := listObjects(ctx, "", 0, func(keys []string) error {
+				matched := make([]string, 0, len(keys))
+				for _, key := range keys {
+					if adapter2.MatchGlob(pattern, objectKey(ctx, key)) {
+						matched = append(matched, key)
+					}
+				}
+				if len(matched) == 0 {
+					return nil
+				}
+				return deleteObjects(ctx, matched)
+			})
+			if c, ok := adapter.GetChained().(storage.Clearable); ok && err == nil {
+				return c.ClearByPatternCtx(ctx, pattern)
+			}
+			return err
+		}).
+		SetIterateFunc(func(ctx context.Context, opts storage.IterateOptions) iter.Seq2[string, any] {
+			return func(yield func(string, any) bool) {
+				if !adapter.GetOptions().Readable() {
+					opts.Error(errors.ErrNotReadable)
+					return
+				}
+				err := listObjects(ctx, opts.Prefix, int32(opts.Batch()), func(keys []string) error {
+					for _, objKey := range keys {
+						key := objectKey(ctx, objKey)
+						v, err := adapter.GetItemCtx(ctx, key)
+						if err != nil {
+							if ctx.Err() != nil {
+								return ctx.Err()
+							}
+							//the object may have expired or been removed since the listing
+							continue
+						}
+						if !yield(key, v) {
+							return errStopIteration
+						}
+					}
+					return nil
+				})
+				if err != nil && !errs.Is(err, errStopIteration) {
+					opts.Error(err)
+				}
+			}
+		}).
+		SetIterateKeysFunc(func(ctx context.Context, opts storage.IterateOptions) iter.Seq[string] {
+			return func(yield func(string) bool) {
+				if !adapter.GetOptions().Readable() {
+					opts.Error(errors.ErrNotReadable)
+					return
+				}
+				err := listObjects(ctx, opts.Prefix, int32(opts.Batch()), func(keys []string) error {
+					for _, objKey := range keys {
+						if !yield(objectKey(ctx, objKey)) {
+							return errStopIteration
+						}
+					}
+					return nil
+				})
+				if err != nil && !errs.Is(err, errStopIteration) {
+					opts.Error(err)
+				}
+			}
+		}).
+		SetCapabilities(storage.Capabilities{
+			//the remaining operations are not supported by S3
+			Operations: []storage.Operation{
+				storage.OpGetItem,
+				storage.OpGetItems,
+				storage.OpHasItem,
+				storage.OpHasItems,
+				storage.OpSetItem,
+				storage.OpSetItems,
+				storage.OpRemoveItem,
+				storage.OpRemoveItems,
+				storage.OpGetOrSet,
+				storage.OpGetOrSetItems,
+				storage.OpSetItemWithTTL,
+				storage.OpSetItemsWithTTL,
+				storage.OpGetTTL,
+				storage.OpFlush,
+				storage.OpClearExpired,
+				storage.OpClearByPrefix,
+				storage.OpClearByPattern,
+				storage.OpIterate,
+				storage.OpIterateKeys,
+			},
+			TTL:          true,
+			TTLPrecision: time.Second,
+			Persistent:   true,
 		})
 
 	return adapter, nil
@@ -273,5 +621,6 @@ type S3Iface interface {
 	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 }
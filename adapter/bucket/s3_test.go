@@ -6,16 +6,19 @@ import (
 	"encoding/json"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/chippyash/go-cache-manager/adapter"
 	"github.com/chippyash/go-cache-manager/adapter/bucket"
 	"github.com/chippyash/go-cache-manager/adapter/memory"
 	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
 	errs "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"io"
 	"maps"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -51,6 +54,11 @@ func (m *MockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInpu
 	return args.Get(0).(*s3.HeadObjectOutput), args.Error(1)
 }
 
+func (m *MockS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.ListObjectsV2Output), args.Error(1)
+}
+
 func TestS3Adapter_GetAndSetItem(t *testing.T) {
 	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
 	assert.NoError(t, err)
@@ -90,6 +98,75 @@ func TestS3Adapter_GetAndSetItem(t *testing.T) {
 	mockS3.AssertExpectations(t)
 }
 
+func TestS3Adapter_Context(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	type ctxKey string
+	ctx := context.WithValue(context.Background(), ctxKey("request"), "abc")
+	getInput := &s3.GetObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/key.json"),
+	}
+	getOutput := &s3.GetObjectOutput{
+		Body:        io.NopCloser(bytes.NewReader([]byte(`{"value":"foo"}`))),
+		ContentType: aws.String(bucket.MimeTypeJson),
+	}
+	//the request context must be passed through to the client
+	mockS3.On("GetObject", ctx, getInput).Return(getOutput, nil)
+	val, err := sut.(storage.StorageContext).GetItemCtx(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"value":"foo"}`, val)
+
+	//a cancelled context never reaches the client
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = sut.(storage.StorageContext).GetItemCtx(cctx, "key")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_Codec(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	opts := sut.GetOptions()
+	opts[storage.OptCodec] = storage.JsonCodec{}
+	sut.SetOptions(opts)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	setInput := &s3.PutObjectInput{
+		Bucket:      aws.String("testbucket"),
+		Key:         aws.String("/folder/key.json"),
+		Body:        bytes.NewReader([]byte(`{"value":"foo"}`)),
+		ContentType: aws.String(bucket.MimeTypeJson),
+	}
+	mockS3.On("PutObject", context.TODO(), setInput).Return(&s3.PutObjectOutput{}, nil)
+	type MyStruct struct {
+		Value string `json:"value"`
+	}
+	//with a codec, types other than string and []byte can be stored
+	ok, err := sut.SetItem("key", MyStruct{Value: "foo"})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	getInput := &s3.GetObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/key.json"),
+	}
+	getOutput := &s3.GetObjectOutput{
+		Body:        io.NopCloser(bytes.NewReader([]byte(`{"value":"foo"}`))),
+		ContentType: aws.String(bucket.MimeTypeJson),
+	}
+	mockS3.On("GetObject", context.TODO(), getInput).Return(getOutput, nil)
+	val, err := sut.GetItem("key")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"value": "foo"}, val)
+
+	mockS3.AssertExpectations(t)
+}
+
 func TestS3Adapter_GetUnknownItem(t *testing.T) {
 	sut, _ := bucket.New("testbucket", "/folder/", ".txt", bucket.MimeTypeText, "eu-west-2")
 	mockS3 := new(MockS3Client)
@@ -282,6 +359,27 @@ func TestS3Adapter_TouchItem_NotSupported(t *testing.T) {
 	assert.False(t, sut.TouchItem("bar"))
 }
 
+func TestS3Adapter_TouchItemWithTTL_NotSupported(t *testing.T) {
+	sut, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+
+	assert.False(t, sut.(storage.Expirable).TouchItemWithTTL("bar", time.Hour))
+}
+
+func TestS3Adapter_Tags_NotSupported(t *testing.T) {
+	sut, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+
+	ok, err := sut.(storage.Taggable).SetTags("bar", "baz")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+	_, err = sut.(storage.Taggable).GetTags("bar")
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+	ok, err = sut.(storage.Taggable).ClearByTags([]string{"baz"}, true)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+}
+
 func TestS3Adapter_TouchMultipleItems_NotSupported(t *testing.T) {
 	sut, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
 	assert.NoError(t, err)
@@ -343,3 +441,358 @@ func TestS3Adapter_GetClient(t *testing.T) {
 	client := sut.(*adapter.AbstractAdapter).Client.(*s3.Client)
 	assert.IsType(t, s3.Client{}, *client)
 }
+
+func TestS3Adapter_ItemTTL(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	//the object is put with an Expires time
+	mockS3.On("PutObject", context.TODO(), mock.MatchedBy(func(in *s3.PutObjectInput) bool {
+		return *in.Key == "/folder/key.json" && in.Expires != nil && time.Until(*in.Expires) > time.Minute*59
+	})).Return(&s3.PutObjectOutput{}, nil)
+	ok, err := sut.(storage.Expirable).SetItemWithTTL("key", "value", time.Hour)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	expires := time.Now().Add(time.Hour)
+	mockS3.On("HeadObject", context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/key.json"),
+	}).Return(&s3.HeadObjectOutput{Expires: &expires}, nil)
+	ttl, err := sut.(storage.Expirable).GetTTL("key")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute*59)
+
+	//objects without an Expires time do not expire
+	mockS3.On("HeadObject", context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/forever.json"),
+	}).Return(&s3.HeadObjectOutput{}, nil)
+	ttl, err = sut.(storage.Expirable).GetTTL("forever")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.NoExpiry, ttl)
+
+	//expired objects are not found
+	expired := time.Now().Add(-time.Second)
+	mockS3.On("GetObject", context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/expired.json"),
+	}).Return(&s3.GetObjectOutput{
+		Body:    io.NopCloser(bytes.NewReader([]byte("value"))),
+		Expires: &expired,
+	}, nil)
+	_, err = sut.GetItem("expired")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	mockS3.On("HeadObject", context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/expired.json"),
+	}).Return(&s3.HeadObjectOutput{Expires: &expired}, nil)
+	assert.False(t, sut.HasItem("expired"))
+	_, err = sut.(storage.Expirable).GetTTL("expired")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_Flush(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	mockS3.On("ListObjectsV2", context.TODO(), &s3.ListObjectsV2Input{
+		Bucket: aws.String("testbucket"),
+		Prefix: aws.String("/folder/"),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{
+			{Key: aws.String("/folder/key1.json")},
+			{Key: aws.String("/folder/other.txt")},
+		},
+		IsTruncated:           aws.Bool(true),
+		NextContinuationToken: aws.String("next"),
+	}, nil)
+	mockS3.On("ListObjectsV2", context.TODO(), &s3.ListObjectsV2Input{
+		Bucket:            aws.String("testbucket"),
+		Prefix:            aws.String("/folder/"),
+		ContinuationToken: aws.String("next"),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents:    []types.Object{{Key: aws.String("/folder/key2.json")}},
+		IsTruncated: aws.Bool(false),
+	}, nil)
+	//only objects with the suffix are deleted
+	deleted := make([]string, 0)
+	mockS3.On("DeleteObjects", context.TODO(), mock.Anything).Run(func(args mock.Arguments) {
+		for _, obj := range args.Get(1).(*s3.DeleteObjectsInput).Delete.Objects {
+			deleted = append(deleted, *obj.Key)
+		}
+	}).Return(&s3.DeleteObjectsOutput{}, nil)
+
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.Equal(t, []string{"/folder/key1.json", "/folder/key2.json"}, deleted)
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_ClearExpired(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	mockS3.On("ListObjectsV2", context.TODO(), mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{
+			{Key: aws.String("/folder/expired.json")},
+			{Key: aws.String("/folder/current.json")},
+		},
+	}, nil)
+	expired := time.Now().Add(-time.Second)
+	mockS3.On("HeadObject", context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/expired.json"),
+	}).Return(&s3.HeadObjectOutput{Expires: &expired}, nil)
+	mockS3.On("HeadObject", context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("/folder/current.json"),
+	}).Return(&s3.HeadObjectOutput{}, nil)
+	mockS3.On("DeleteObjects", context.TODO(), mock.MatchedBy(func(in *s3.DeleteObjectsInput) bool {
+		return len(in.Delete.Objects) == 1 && *in.Delete.Objects[0].Key == "/folder/expired.json"
+	})).Return(&s3.DeleteObjectsOutput{}, nil)
+
+	assert.NoError(t, sut.(storage.ClearExpiredable).ClearExpired())
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_ClearByPrefixAndPattern(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	mockS3.On("ListObjectsV2", context.TODO(), &s3.ListObjectsV2Input{
+		Bucket: aws.String("testbucket"),
+		Prefix: aws.String("/folder/users:42:"),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("/folder/users:42:name.json")}},
+	}, nil)
+	mockS3.On("DeleteObjects", context.TODO(), mock.MatchedBy(func(in *s3.DeleteObjectsInput) bool {
+		return len(in.Delete.Objects) == 1 && *in.Delete.Objects[0].Key == "/folder/users:42:name.json"
+	})).Return(&s3.DeleteObjectsOutput{}, nil).Once()
+	assert.NoError(t, sut.(storage.Clearable).ClearByPrefix("users:42:"))
+
+	//the pattern is matched against the key without the namespace and suffix
+	mockS3.On("ListObjectsV2", context.TODO(), &s3.ListObjectsV2Input{
+		Bucket: aws.String("testbucket"),
+		Prefix: aws.String("/folder/"),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{
+			{Key: aws.String("/folder/users:43:name.json")},
+			{Key: aws.String("/folder/users:420:name.json")},
+		},
+	}, nil)
+	mockS3.On("DeleteObjects", context.TODO(), mock.MatchedBy(func(in *s3.DeleteObjectsInput) bool {
+		return len(in.Delete.Objects) == 1 && *in.Delete.Objects[0].Key == "/folder/users:43:name.json"
+	})).Return(&s3.DeleteObjectsOutput{}, nil).Once()
+	assert.NoError(t, sut.(storage.Clearable).ClearByPattern("users:4?:name"))
+
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_Iterate(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	mockS3.On("ListObjectsV2", context.TODO(), &s3.ListObjectsV2Input{
+		Bucket:  aws.String("testbucket"),
+		Prefix:  aws.String("/folder/users:"),
+		MaxKeys: aws.Int32(1),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents:              []types.Object{{Key: aws.String("/folder/users:1.json")}},
+		IsTruncated:           aws.Bool(true),
+		NextContinuationToken: aws.String("next"),
+	}, nil)
+	mockS3.On("ListObjectsV2", context.TODO(), &s3.ListObjectsV2Input{
+		Bucket:            aws.String("testbucket"),
+		Prefix:            aws.String("/folder/users:"),
+		MaxKeys:           aws.Int32(1),
+		ContinuationToken: aws.String("next"),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("/folder/users:2.json")}},
+	}, nil)
+	for _, k := range []string{"users:1", "users:2"} {
+		mockS3.On("GetObject", context.TODO(), &s3.GetObjectInput{
+			Bucket: aws.String("testbucket"),
+			Key:    aws.String("/folder/" + k + ".json"),
+		}).Return(&s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte(k)))}, nil)
+	}
+
+	opts := storage.IterateOptions{Prefix: "users:", BatchSize: 1}
+	assert.Equal(t,
+		[]string{"users:1", "users:2"},
+		slices.Collect(sut.(storage.Iterable).IterateKeys(opts)),
+	)
+	assert.Equal(t,
+		map[string]any{"users:1": "users:1", "users:2": "users:2"},
+		maps.Collect(sut.(storage.Iterable).Iterate(opts)),
+	)
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_HashedKeys(t *testing.T) {
+	sut, err := bucket.New("testbucket", "/folder/", ".json", bucket.MimeTypeJson, "eu-west-2",
+		storage.WithKeyHashing(storage.KeyHashing{Unsafe: storage.UnsafeKey}),
+	)
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	objKey := sut.(*adapter.AbstractAdapter).NamespacedKey("a key") + ".json"
+	//the key of a hashed key is kept in the object metadata
+	mockS3.On("PutObject", context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String("testbucket"),
+		Key:         aws.String(objKey),
+		Body:        bytes.NewReader([]byte("foo")),
+		ContentType: aws.String(bucket.MimeTypeJson),
+		Metadata:    map[string]string{"gcm-key": "a+key"},
+	}).Return(&s3.PutObjectOutput{}, nil)
+	ok, err := sut.SetItem("a key", "foo")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	mockS3.On("ListObjectsV2", context.TODO(), mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String(objKey)}, {Key: aws.String("/folder/users:1.json")}},
+	}, nil)
+	mockS3.On("HeadObject", context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String(objKey),
+	}).Return(&s3.HeadObjectOutput{Metadata: map[string]string{"gcm-key": "a+key"}}, nil)
+	assert.Equal(t,
+		[]string{"a key", "users:1"},
+		slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})),
+	)
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_HashedLongKeys(t *testing.T) {
+	sut, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2",
+		storage.WithMaxKeyLength(80),
+		storage.WithKeyHashing(storage.KeyHashing{}),
+	)
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	//the backend key fits, but the object key with the suffix does not, so the key is hashed
+	key := strings.Repeat("k", 70)
+	objKey := sut.(*adapter.AbstractAdapter).NamespacedKey(key) + ".json"
+	assert.True(t, sut.(*adapter.AbstractAdapter).KeyPolicy().Hashed(strings.TrimSuffix(objKey, ".json")))
+	mockS3.On("PutObject", context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String("testbucket"),
+		Key:         aws.String(objKey),
+		Body:        bytes.NewReader([]byte("foo")),
+		ContentType: aws.String(bucket.MimeTypeJson),
+		Metadata:    map[string]string{"gcm-key": key},
+	}).Return(&s3.PutObjectOutput{}, nil)
+	ok, err := sut.SetItem(key, "foo")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	mockS3.AssertExpectations(t)
+}
+
+func TestS3Adapter_Capabilities(t *testing.T) {
+	sut, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+
+	c := sut.(storage.Capable).Capabilities()
+	assert.True(t, c.Supports(storage.OpGetItem, storage.OpSetItemWithTTL, storage.OpClearByPrefix, storage.OpIterate))
+	assert.False(t, c.Supports(storage.OpIncrement))
+	assert.False(t, c.Supports(storage.OpTouchItem))
+	assert.False(t, c.Supports(storage.OpSetTags))
+	assert.True(t, c.TTL)
+	assert.Equal(t, time.Second, c.TTLPrecision)
+	assert.True(t, c.Persistent)
+	assert.True(t, c.SupportsType(storage.TypeString))
+}
+
+func TestS3Adapter_Capabilities_Chained(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+	chained, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	sut.(storage.Chainable).ChainAdapter(chained)
+
+	c := sut.(storage.Capable).Capabilities()
+	assert.True(t, c.Supports(storage.OpGetItem, storage.OpSetItem))
+	assert.False(t, c.Supports(storage.OpIncrement))
+	assert.Equal(t, time.Second, c.TTLPrecision)
+	assert.True(t, c.Persistent)
+	assert.NoError(t, c.Require(storage.OpGetItem, storage.OpSetItem))
+	err = c.Require(storage.OpIncrement, storage.OpSetTags)
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+	assert.Contains(t, err.Error(), "increment, setTags")
+}
+
+func TestS3Adapter_FromDSN(t *testing.T) {
+	dsn, err := storage.ParseDSN("s3://testbucket/folder/?suffix=.json&region=eu-west-2")
+	assert.NoError(t, err)
+	sut, err := bucket.FromDSN(dsn)
+	assert.NoError(t, err)
+	opts := sut.GetOptions()
+	assert.Equal(t, "testbucket", opts[bucket.OptS3Bucket])
+	assert.Equal(t, "folder/", opts[storage.OptNamespace])
+	assert.Equal(t, ".json", opts[bucket.OptS3Suffix])
+	assert.Equal(t, bucket.MimeTypeJson, opts[bucket.OptS3MimeType])
+	assert.Equal(t, "eu-west-2", opts[bucket.OptS3Region])
+	assert.Contains(t, storage.Registered(), "s3")
+
+	dsn, _ = storage.ParseDSN("s3:///folder/")
+	_, err = bucket.FromDSN(dsn)
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+}
+
+func TestS3Adapter_Options(t *testing.T) {
+	sut, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2", storage.WithCodec(storage.JsonCodec{}))
+	assert.NoError(t, err)
+	assert.Equal(t, storage.JsonCodec{}, sut.GetOptions().Codec())
+	//the bucket adapter has no default TTL
+	assert.Equal(t, time.Duration(0), sut.GetOptions().TTL())
+
+	_, err = bucket.New("", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.ErrorIs(t, err, errors.ErrValidation)
+	assert.Contains(t, err.Error(), "bucket: must not be empty")
+}
+
+func TestS3Adapter_Limits(t *testing.T) {
+	sut, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2",
+		storage.WithMaxKeyLength(15),
+		storage.WithMaxValueLength(5),
+	)
+	assert.NoError(t, err)
+	mockS3 := new(MockS3Client)
+	sut.(*adapter.AbstractAdapter).Client = mockS3
+	mockS3.On("PutObject", context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String("testbucket"),
+		Key:         aws.String("folder/key.json"),
+		Body:        bytes.NewReader([]byte("foo")),
+		ContentType: aws.String(bucket.MimeTypeJson),
+	}).Return(&s3.PutObjectOutput{}, nil)
+
+	//[]byte values can be stored
+	ok, err := sut.SetItem("key", []byte("foo"))
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	//the key length includes the namespace and suffix
+	_, err = sut.SetItem("toolong", "foo")
+	assert.ErrorIs(t, err, errors.ErrKeyTooLong)
+	var keyErr *errors.KeyTooLongError
+	assert.True(t, errs.As(err, &keyErr))
+	assert.Equal(t, "toolong", keyErr.Key)
+	assert.Equal(t, 19, keyErr.Length)
+
+	_, err = sut.SetItem("key", "toolarge")
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+
+	_, err = sut.SetItems(map[string]any{"key": "toolarge"})
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+
+	_, err = sut.SetItem("key", uint8(1))
+	assert.ErrorIs(t, err, errors.ErrUnsupportedDataType)
+
+	mockS3.AssertExpectations(t)
+	mockS3.AssertNumberOfCalls(t, "PutObject", 1)
+}
@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"github.com/chippyash/go-cache-manager/storage"
+	"maps"
+	"slices"
+)
+
+/** Capable Interface **/
+
+// Capabilities returns the adapter capabilities, combined with those of the chained adapter if it is Capable.
+// The data types and maximum lengths are read from the options. If the adapter has not set its operations, the
+// operations whose functions are set are returned
+func (a *AbstractAdapter) Capabilities() storage.Capabilities {
+	c := a.capabilities
+	c.Operations = slices.Clone(c.Operations)
+	if c.Operations == nil {
+		c.Operations = a.setOperations()
+	}
+	c.DataTypes = maps.Clone(a.options.DataTypes())
+	c.MaxKeyLength = a.options.MaxKeyLength()
+	c.MaxValueLength = a.options.MaxValueLength()
+	if chained, ok := a.chained.(storage.Capable); ok {
+		c = c.Combine(chained.Capabilities())
+	}
+	return c
+}
+
+// SetCapabilities sets the adapter capabilities. The data types and maximum lengths are read from the options
+func (a *AbstractAdapter) SetCapabilities(c storage.Capabilities) *AbstractAdapter {
+	a.capabilities = c
+	return a
+}
+
+// setOperations returns the operations whose functions are set
+func (a *AbstractAdapter) setOperations() []storage.Operation {
+	ops := make([]storage.Operation, 0)
+	for op, set := range map[storage.Operation]bool{
+		storage.OpGetItem:          a.getItem != nil,
+		storage.OpGetItems:         a.getItems != nil,
+		storage.OpHasItem:          a.hasItem != nil,
+		storage.OpHasItems:         a.hasItems != nil,
+		storage.OpSetItem:          a.setItem != nil,
+		storage.OpSetItems:         a.setItems != nil,
+		storage.OpCheckAndSetItem:  a.checkAndSetItem != nil,
+		storage.OpCheckAndSetItems: a.checkAndSetItems != nil,
+		storage.OpTouchItem:        a.touchItem != nil,
+		storage.OpTouchItems:       a.touchItems != nil,
+		storage.OpRemoveItem:       a.removeItem != nil,
+		storage.OpRemoveItems:      a.removeItems != nil,
+		storage.OpIncrement:        a.increment != nil,
+		storage.OpDecrement:        a.decrement != nil,
+		storage.OpGetOrSet:         a.getItem != nil && a.setItem != nil,
+		storage.OpGetOrSetItems:    a.getItems != nil && a.setItems != nil,
+		storage.OpSetItemWithTTL:   a.setItemWithTTL != nil,
+		storage.OpSetItemsWithTTL:  a.setItemsWithTTL != nil,
+		storage.OpTouchItemWithTTL: a.touchItemWithTTL != nil,
+		storage.OpGetTTL:           a.getTTL != nil,
+		storage.OpSetTags:          a.setTags != nil,
+		storage.OpGetTags:          a.getTags != nil,
+		storage.OpClearByTags:      a.clearByTags != nil,
+		storage.OpFlush:            a.flush != nil,
+		storage.OpClearExpired:     a.clearExpired != nil,
+		storage.OpClearByPrefix:    a.clearByPrefix != nil,
+		storage.OpClearByPattern:   a.clearByPattern != nil,
+		storage.OpIterate:          a.iterate != nil,
+		storage.OpIterateKeys:      a.iterateKeys != nil,
+	} {
+		if set {
+			ops = append(ops, op)
+		}
+	}
+	slices.Sort(ops)
+	return ops
+}
@@ -0,0 +1,56 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+)
+
+/** Clearable Interface **/
+
+func (a *AbstractAdapter) ClearByPrefix(prefix string) error {
+	return a.ClearByPrefixCtx(context.TODO(), prefix)
+}
+
+func (a *AbstractAdapter) ClearByPattern(pattern string) error {
+	return a.ClearByPatternCtx(context.TODO(), pattern)
+}
+
+func (a *AbstractAdapter) ClearByPrefixCtx(ctx context.Context, prefix string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if a.clearByPrefix == nil {
+		return errors.ErrNotImplemented
+	}
+	_, err := trigger(ctx, a, storage.OpClearByPrefix, event.Params{Prefix: prefix}, func(ctx context.Context, p *event.Params) (any, error) {
+		return nil, a.clearByPrefix(ctx, p.Prefix)
+	})
+	return err
+}
+
+func (a *AbstractAdapter) ClearByPatternCtx(ctx context.Context, pattern string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if a.clearByPattern == nil {
+		return errors.ErrNotImplemented
+	}
+	_, err := trigger(ctx, a, storage.OpClearByPattern, event.Params{Pattern: pattern}, func(ctx context.Context, p *event.Params) (any, error) {
+		return nil, a.clearByPattern(ctx, p.Pattern)
+	})
+	return err
+}
+
+/** Setters for the Clearable interface functions **/
+
+func (a *AbstractAdapter) SetClearByPrefixFunc(f func(ctx context.Context, prefix string) error) *AbstractAdapter {
+	a.clearByPrefix = f
+	return a
+}
+
+func (a *AbstractAdapter) SetClearByPatternFunc(f func(ctx context.Context, pattern string) error) *AbstractAdapter {
+	a.clearByPattern = f
+	return a
+}
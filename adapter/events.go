@@ -0,0 +1,85 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/chippyash/go-cache-manager/tracing"
+	"time"
+)
+
+/** event.Capable Interface **/
+
+// GetEventManager returns the adapter's event manager, creating it if there is none. It is safe to call
+// concurrently with the operations. Listeners attached to it are called for every operation whose function is set
+func (a *AbstractAdapter) GetEventManager() *event.Manager {
+	if m := a.events.Load(); m != nil {
+		return m
+	}
+	a.events.CompareAndSwap(nil, event.NewManager())
+	return a.events.Load()
+}
+
+// SetEventManager sets the adapter's event manager. A manager can be shared by several adapters, in which case
+// the event Target identifies the adapter
+func (a *AbstractAdapter) SetEventManager(m *event.Manager) *AbstractAdapter {
+	a.events.Store(m)
+	return a
+}
+
+// trigger calls fn for the operation in a span if there is a tracer, and logs it if it fails or is slow, see dispatch
+func trigger[T any](ctx context.Context, a *AbstractAdapter, op storage.Operation, params event.Params, fn func(ctx context.Context, p *event.Params) (T, error)) (T, error) {
+	if a.tracer == nil && a.logging == nil {
+		return dispatch(ctx, a, op, params, fn)
+	}
+	start := time.Now()
+	var span tracing.Span
+	if a.tracer != nil {
+		ctx, span = a.startSpan(ctx, op, params)
+	}
+	ret, err := dispatch(ctx, a, op, params, fn)
+	if span != nil {
+		if hits, misses, ok := event.Hits(op, &params, ret, err); ok {
+			span.SetHit(hits, misses)
+		}
+		span.End(err)
+	}
+	if a.logging != nil {
+		a.logOperation(ctx, op, params, time.Since(start), err)
+	}
+	return ret, err
+}
+
+// dispatch calls fn for the operation, firing the pre, post and exception events if there is an event manager.
+// A pre listener that stops propagation short-circuits fn, and an exception listener that sets the event Err to nil
+// suppresses the error. A Result that is not of type T is returned as the zero value of T
+func dispatch[T any](ctx context.Context, a *AbstractAdapter, op storage.Operation, params event.Params, fn func(ctx context.Context, p *event.Params) (T, error)) (T, error) {
+	m := a.events.Load()
+	if m == nil {
+		return fn(ctx, &params)
+	}
+	e := &event.Event{
+		Name:   event.Name(event.Pre, op),
+		Ctx:    ctx,
+		Target: a,
+		Params: &params,
+	}
+	m.Trigger(e)
+	if e.PropagationStopped() {
+		return resultOf[T](e), e.Err
+	}
+	ret, err := fn(e.Ctx, e.Params)
+	e.Result, e.Err = ret, err
+	e.Name = event.Name(event.Post, op)
+	if err != nil {
+		e.Name = event.Name(event.Exception, op)
+	}
+	m.Trigger(e)
+	return resultOf[T](e), e.Err
+}
+
+// resultOf returns the event Result as T
+func resultOf[T any](e *event.Event) T {
+	ret, _ := e.Result.(T)
+	return ret
+}
@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"time"
+)
+
+/** Expirable Interface **/
+
+func (a *AbstractAdapter) SetItemWithTTL(key string, value any, ttl time.Duration) (bool, error) {
+	return a.SetItemWithTTLCtx(context.TODO(), key, value, ttl)
+}
+
+func (a *AbstractAdapter) SetItemsWithTTL(values map[string]any, ttl time.Duration) ([]string, error) {
+	return a.SetItemsWithTTLCtx(context.TODO(), values, ttl)
+}
+
+func (a *AbstractAdapter) TouchItemWithTTL(key string, ttl time.Duration) bool {
+	return a.TouchItemWithTTLCtx(context.TODO(), key, ttl)
+}
+
+func (a *AbstractAdapter) GetTTL(key string) (time.Duration, error) {
+	return a.GetTTLCtx(context.TODO(), key)
+}
+
+func (a *AbstractAdapter) SetItemWithTTLCtx(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if a.setItemWithTTL == nil {
+		return false, errors.ErrNotImplemented
+	}
+	return trigger(ctx, a, storage.OpSetItemWithTTL, event.Params{Key: key, Value: value, TTL: ttl}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.setItemWithTTL(ctx, p.Key, p.Value, p.TTL)
+	})
+}
+
+func (a *AbstractAdapter) SetItemsWithTTLCtx(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	if a.setItemsWithTTL == nil {
+		return []string{}, errors.ErrNotImplemented
+	}
+	return trigger(ctx, a, storage.OpSetItemsWithTTL, event.Params{Values: values, TTL: ttl}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return a.setItemsWithTTL(ctx, p.Values, p.TTL)
+	})
+}
+
+func (a *AbstractAdapter) TouchItemWithTTLCtx(ctx context.Context, key string, ttl time.Duration) bool {
+	if ctx.Err() != nil || a.touchItemWithTTL == nil {
+		return false
+	}
+	ret, _ := trigger(ctx, a, storage.OpTouchItemWithTTL, event.Params{Key: key, TTL: ttl}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.touchItemWithTTL(ctx, p.Key, p.TTL), nil
+	})
+	return ret
+}
+
+func (a *AbstractAdapter) GetTTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if a.getTTL == nil {
+		return 0, errors.ErrNotImplemented
+	}
+	return trigger(ctx, a, storage.OpGetTTL, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (time.Duration, error) {
+		return a.getTTL(ctx, p.Key)
+	})
+}
+
+// ResolveTTL returns ttl, or the default options[storage.OptTTL] if ttl is storage.DefaultTTL.
+// Returns storage.NoExpiry if the resolved ttl is not positive
+func (a *AbstractAdapter) ResolveTTL(ttl time.Duration) time.Duration {
+	if ttl == storage.DefaultTTL {
+		ttl = a.options.TTL()
+	}
+	if ttl <= 0 {
+		return storage.NoExpiry
+	}
+	return ttl
+}
+
+/** Setters for the Expirable interface functions **/
+
+func (a *AbstractAdapter) SetSetItemWithTTLFunc(f func(ctx context.Context, key string, value any, ttl time.Duration) (bool, error)) *AbstractAdapter {
+	a.setItemWithTTL = f
+	return a
+}
+
+func (a *AbstractAdapter) SetSetItemsWithTTLFunc(f func(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error)) *AbstractAdapter {
+	a.setItemsWithTTL = f
+	return a
+}
+
+func (a *AbstractAdapter) SetTouchItemWithTTLFunc(f func(ctx context.Context, key string, ttl time.Duration) bool) *AbstractAdapter {
+	a.touchItemWithTTL = f
+	return a
+}
+
+func (a *AbstractAdapter) SetGetTTLFunc(f func(ctx context.Context, key string) (time.Duration, error)) *AbstractAdapter {
+	a.getTTL = f
+	return a
+}
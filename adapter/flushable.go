@@ -0,0 +1,58 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+)
+
+/** Flushable Interface **/
+
+func (a *AbstractAdapter) Flush() error {
+	return a.FlushCtx(context.TODO())
+}
+
+func (a *AbstractAdapter) FlushCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if a.flush == nil {
+		return errors.ErrNotImplemented
+	}
+	_, err := trigger(ctx, a, storage.OpFlush, event.Params{}, func(ctx context.Context, p *event.Params) (any, error) {
+		return nil, a.flush(ctx)
+	})
+	return err
+}
+
+/** ClearExpiredable Interface **/
+
+func (a *AbstractAdapter) ClearExpired() error {
+	return a.ClearExpiredCtx(context.TODO())
+}
+
+func (a *AbstractAdapter) ClearExpiredCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if a.clearExpired == nil {
+		return errors.ErrNotImplemented
+	}
+	_, err := trigger(ctx, a, storage.OpClearExpired, event.Params{}, func(ctx context.Context, p *event.Params) (any, error) {
+		return nil, a.clearExpired(ctx)
+	})
+	return err
+}
+
+/** Setters for the Flushable and ClearExpiredable interface functions **/
+
+func (a *AbstractAdapter) SetFlushFunc(f func(ctx context.Context) error) *AbstractAdapter {
+	a.flush = f
+	return a
+}
+
+func (a *AbstractAdapter) SetClearExpiredFunc(f func(ctx context.Context) error) *AbstractAdapter {
+	a.clearExpired = f
+	return a
+}
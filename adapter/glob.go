@@ -0,0 +1,101 @@
+package adapter
+
+import "strings"
+
+// globEscaper escapes the special characters of a Redis style glob pattern
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// EscapeGlob returns s with the glob special characters escaped, so that it matches itself literally in a glob pattern
+func EscapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
+
+// MatchGlob reports whether s matches the Redis style glob pattern, in which * matches any sequence of characters,
+// including none, ? matches any single character, [abc] matches one of the characters, [^abc] any character but them,
+// [a-z] a character in the range, and \x matches x literally
+func MatchGlob(pattern, s string) bool {
+	//star and its match position, for backtracking
+	star, match := -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, match = p, i
+				p++
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if end, ok := matchClass(pattern, p, s[i]); ok {
+					p = end
+					i++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == s[i] {
+					p += 2
+					i++
+					continue
+				}
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		//backtrack so that the last star matches one more character
+		match++
+		p, i = star+1, match
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against the character class starting at pattern[start], which is '['.
+// Returns the index after the class and whether c matched. An unterminated class is matched literally
+func matchClass(pattern string, start int, c byte) (int, bool) {
+	p := start + 1
+	negate := p < len(pattern) && pattern[p] == '^'
+	if negate {
+		p++
+	}
+	matched := false
+	for p < len(pattern) && pattern[p] != ']' {
+		lo := pattern[p]
+		if lo == '\\' && p+1 < len(pattern) {
+			p++
+			lo = pattern[p]
+		}
+		p++
+		hi := lo
+		if p+1 < len(pattern) && pattern[p] == '-' && pattern[p+1] != ']' {
+			hi = pattern[p+1]
+			if hi == '\\' && p+2 < len(pattern) {
+				p++
+				hi = pattern[p+1]
+			}
+			p += 2
+		}
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if c >= lo && c <= hi {
+			matched = true
+		}
+	}
+	if p >= len(pattern) {
+		//unterminated class
+		return start + 1, c == '['
+	}
+	return p + 1, matched != negate
+}
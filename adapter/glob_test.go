@@ -0,0 +1,47 @@
+package adapter_test
+
+import (
+	"github.com/chippyash/go-cache-manager/adapter"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*", "", true},
+		{"*", "users:42:name", true},
+		{"users:*", "users:42:name", true},
+		{"users:*:name", "users:42:name", true},
+		{"users:*:name", "users:42:email", false},
+		{"users:4?:*", "users:42:name", true},
+		{"users:4?:*", "users:4:name", false},
+		{"users:[0-9][0-9]:*", "users:42:name", true},
+		{"users:[0-9][0-9]:*", "users:4a:name", false},
+		{"users:[^0-9]*", "users:a", true},
+		{"users:[^0-9]*", "users:1", false},
+		{"users:[abc]", "users:b", true},
+		{"users:[abc]", "users:d", false},
+		{`users:\*`, "users:*", true},
+		{`users:\*`, "users:42", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"[", "[", true},
+		{"", "", true},
+		{"", "a", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.s, func(t *testing.T) {
+			assert.Equal(t, tc.match, adapter.MatchGlob(tc.pattern, tc.s))
+		})
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	ns := `a*b?c[d]e\f:`
+	assert.True(t, adapter.MatchGlob(adapter.EscapeGlob(ns)+"*", ns+"key"))
+	assert.False(t, adapter.MatchGlob(adapter.EscapeGlob(ns)+"*", "aXb?c[d]e\\f:key"))
+}
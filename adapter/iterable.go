@@ -0,0 +1,63 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"iter"
+)
+
+/** Iterable Interface **/
+
+func (a *AbstractAdapter) Iterate(opts storage.IterateOptions) iter.Seq2[string, any] {
+	return a.IterateCtx(context.TODO(), opts)
+}
+
+func (a *AbstractAdapter) IterateKeys(opts storage.IterateOptions) iter.Seq[string] {
+	return a.IterateKeysCtx(context.TODO(), opts)
+}
+
+func (a *AbstractAdapter) IterateCtx(ctx context.Context, opts storage.IterateOptions) iter.Seq2[string, any] {
+	if a.iterate == nil {
+		return func(yield func(string, any) bool) {
+			opts.Error(errors.ErrNotImplemented)
+		}
+	}
+	ret, _ := trigger(ctx, a, storage.OpIterate, event.Params{IterateOptions: opts}, func(ctx context.Context, p *event.Params) (iter.Seq2[string, any], error) {
+		return a.iterate(ctx, p.IterateOptions), nil
+	})
+	if ret == nil {
+		//a pre listener short-circuited without a result
+		return func(yield func(string, any) bool) {}
+	}
+	return ret
+}
+
+func (a *AbstractAdapter) IterateKeysCtx(ctx context.Context, opts storage.IterateOptions) iter.Seq[string] {
+	if a.iterateKeys == nil {
+		return func(yield func(string) bool) {
+			opts.Error(errors.ErrNotImplemented)
+		}
+	}
+	ret, _ := trigger(ctx, a, storage.OpIterateKeys, event.Params{IterateOptions: opts}, func(ctx context.Context, p *event.Params) (iter.Seq[string], error) {
+		return a.iterateKeys(ctx, p.IterateOptions), nil
+	})
+	if ret == nil {
+		//a pre listener short-circuited without a result
+		return func(yield func(string) bool) {}
+	}
+	return ret
+}
+
+/** Setters for the Iterable interface functions **/
+
+func (a *AbstractAdapter) SetIterateFunc(f func(ctx context.Context, opts storage.IterateOptions) iter.Seq2[string, any]) *AbstractAdapter {
+	a.iterate = f
+	return a
+}
+
+func (a *AbstractAdapter) SetIterateKeysFunc(f func(ctx context.Context, opts storage.IterateOptions) iter.Seq[string]) *AbstractAdapter {
+	a.iterateKeys = f
+	return a
+}
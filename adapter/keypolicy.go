@@ -0,0 +1,179 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/chippyash/go-cache-manager/storage"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	//HashedKeyPrefix prefixes the hash of a key that KeyHashing has hashed, after the namespace
+	HashedKeyPrefix = "#"
+	//KeyKeyTpl formatting string for the key holding the key of a hashed backend key. Adapters that hash keys keep it
+	//alongside the item, with the same TTL, so that any adapter over the backend can return the key
+	KeyKeyTpl = MetaKeyPrefix + "key:%s"
+)
+
+// KeyPolicy maps the keys of an adapter to the keys sent to its backend, and back.
+// A backend key is the namespace followed by the key, encoded by the key codec if there is one, or hashed if
+// key hashing is set and the key is too long or unsafe. A hash cannot be reversed, so the adapter keeps the key of a
+// hashed backend key in its backend, see KeyKeyTpl. The key pattern is compiled once.
+// The adapter creates its policy from the options each time they are set, and again if the key options are changed
+// in the map returned by GetOptions
+type KeyPolicy struct {
+	namespace string
+	pattern   string
+	re        *regexp.Regexp
+	invalid   bool
+	codec     storage.KeyCodec
+	hashing   *storage.KeyHashing
+	//the options the policy was created from, see current
+	maxKeyLength int
+	hashingOpt   storage.KeyHashing
+}
+
+// NewKeyPolicy returns the key policy for the options. If the key pattern does not compile, no key is valid
+func NewKeyPolicy(opts storage.StorageOptions) *KeyPolicy {
+	p := &KeyPolicy{
+		namespace:    opts.Namespace(),
+		pattern:      opts.KeyPattern(),
+		codec:        opts.KeyCodec(),
+		maxKeyLength: opts.MaxKeyLength(),
+	}
+	if p.pattern != "" {
+		re, err := regexp.Compile(p.pattern)
+		p.re = re
+		p.invalid = err != nil
+	}
+	if h, ok := opts.KeyHashing(); ok {
+		p.hashingOpt = h
+		if h.MaxLength == 0 {
+			h.MaxLength = opts.MaxKeyLength()
+		}
+		p.hashing = &h
+	}
+	return p
+}
+
+// BackendKey returns the key sent to the backend for the key
+func (p *KeyPolicy) BackendKey(key string) string {
+	encoded := key
+	if p.codec != nil {
+		encoded = p.codec.EncodeKey(key)
+	}
+	backendKey := p.namespace + encoded
+	if p.mustHash(key, backendKey) {
+		sum := sha256.Sum256([]byte(key))
+		backendKey = p.namespace + HashedKeyPrefix + hex.EncodeToString(sum[:])
+	}
+	return backendKey
+}
+
+// BackendPrefix returns the prefix of the backend keys of the keys with the prefix. Hashed keys only have the
+// namespace prefix, so are not found by a non-empty prefix
+func (p *KeyPolicy) BackendPrefix(prefix string) string {
+	if p.codec != nil {
+		prefix = p.codec.EncodeKey(prefix)
+	}
+	return p.namespace + prefix
+}
+
+// Key returns the key for a backend key. A hashed key is returned as its hash, without the namespace, as the key is
+// kept by the adapter, see Hashed
+func (p *KeyPolicy) Key(backendKey string) string {
+	key := strings.TrimPrefix(backendKey, p.namespace)
+	if p.codec != nil {
+		if decoded, err := p.codec.DecodeKey(key); err == nil {
+			return decoded
+		}
+	}
+	return key
+}
+
+// Hashed returns true if the backend key may be the hash of a key, in which case the adapter looks up the key in the
+// key held at KeyKeyTpl
+func (p *KeyPolicy) Hashed(backendKey string) bool {
+	if p.hashing == nil {
+		return false
+	}
+	hash, ok := strings.CutPrefix(backendKey, p.namespace+HashedKeyPrefix)
+	if !ok || len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Valid returns true if the namespaced key, before it is encoded or hashed, matches the key pattern, if there is one.
+// A key whose backend key starts with ReservedKeyPrefix is invalid
+func (p *KeyPolicy) Valid(key string) bool {
+	if p.invalid || strings.HasPrefix(p.BackendKey(key), ReservedKeyPrefix) {
+		return false
+	}
+	if p.re == nil {
+		return true
+	}
+	return p.re.MatchString(p.namespace + key)
+}
+
+// Hashing returns true if keys may be hashed
+func (p *KeyPolicy) Hashing() bool {
+	return p.hashing != nil
+}
+
+// Transparent returns true if every backend key is the namespace followed by the key, i.e. keys are neither encoded
+// nor hashed
+func (p *KeyPolicy) Transparent() bool {
+	return p.codec == nil && p.hashing == nil
+}
+
+// Namespace returns the namespace that prefixes every backend key
+func (p *KeyPolicy) Namespace() string {
+	return p.namespace
+}
+
+// current returns true if the policy was created from the key options in opts
+func (p *KeyPolicy) current(opts storage.StorageOptions) bool {
+	if p.namespace != opts.Namespace() || p.pattern != opts.KeyPattern() || p.maxKeyLength != opts.MaxKeyLength() {
+		return false
+	}
+	if !sameValue(p.codec, opts.KeyCodec()) {
+		return false
+	}
+	h, ok := opts.KeyHashing()
+	if ok != (p.hashing != nil) {
+		return false
+	}
+	return !ok || (h.MaxLength == p.hashingOpt.MaxLength && sameFunc(h.Unsafe, p.hashingOpt.Unsafe))
+}
+
+// sameValue returns true if a and b are equal. Values that cannot be compared are never equal
+func sameValue(a, b any) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// sameFunc returns true if a and b are both nil, or both the same function
+func sameFunc(a, b func(string) bool) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// mustHash returns true if the key must be hashed
+func (p *KeyPolicy) mustHash(key, backendKey string) bool {
+	if p.hashing == nil {
+		return false
+	}
+	if p.hashing.MaxLength > 0 && len(backendKey) > p.hashing.MaxLength {
+		return true
+	}
+	return p.hashing.Unsafe != nil && p.hashing.Unsafe(key)
+}
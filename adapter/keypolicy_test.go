@@ -0,0 +1,98 @@
+package adapter_test
+
+import (
+	"github.com/chippyash/go-cache-manager/adapter"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestKeyPolicy_Namespace(t *testing.T) {
+	sut := adapter.NewKeyPolicy(storage.StorageOptions{storage.OptNamespace: "ns:"})
+	//keys that contain the namespace are not corrupted
+	for _, key := range []string{"foo", "a:ns:b", "ns:", ""} {
+		backendKey := sut.BackendKey(key)
+		assert.Equal(t, "ns:"+key, backendKey)
+		assert.Equal(t, key, sut.Key(backendKey))
+	}
+	assert.Equal(t, "ns:pre", sut.BackendPrefix("pre"))
+}
+
+func TestKeyPolicy_Pattern(t *testing.T) {
+	sut := adapter.NewKeyPolicy(storage.StorageOptions{
+		storage.OptNamespace:  "ns:",
+		storage.OptKeyPattern: "^ns:[a-z]+$",
+		storage.OptKeyCodec:   storage.SafeKeyCodec{},
+	})
+	assert.True(t, sut.Valid("foo"))
+	assert.False(t, sut.Valid("foo1"))
+	//the pattern is matched before the key is encoded
+	assert.False(t, sut.Valid("foo bar"))
+
+	sut = adapter.NewKeyPolicy(storage.StorageOptions{storage.OptKeyPattern: "["})
+	assert.False(t, sut.Valid("foo"))
+}
+
+func TestKeyPolicy_Codec(t *testing.T) {
+	sut := adapter.NewKeyPolicy(storage.StorageOptions{
+		storage.OptNamespace: "ns/",
+		storage.OptKeyCodec:  storage.SafeKeyCodec{},
+	})
+	backendKey := sut.BackendKey("users/42 name?")
+	assert.Equal(t, "ns/users/42%20name%3F", backendKey)
+	assert.Equal(t, "users/42 name?", sut.Key(backendKey))
+	assert.Equal(t, "ns/users/42%20", sut.BackendPrefix("users/42 "))
+}
+
+func TestKeyPolicy_Hashing(t *testing.T) {
+	sut := adapter.NewKeyPolicy(storage.StorageOptions{
+		storage.OptNamespace:    "ns:",
+		storage.OptMaxKeyLength: 70,
+		storage.OptKeyHashing:   storage.KeyHashing{Unsafe: storage.UnsafeKey},
+		storage.OptKeyPattern:   "^ns:x+$",
+	})
+	assert.Equal(t, "ns:short", sut.BackendKey("short"))
+	assert.False(t, sut.Hashed("ns:short"))
+
+	long := strings.Repeat("x", 100)
+	backendKey := sut.BackendKey(long)
+	assert.True(t, strings.HasPrefix(backendKey, "ns:"+adapter.HashedKeyPrefix))
+	assert.Len(t, backendKey, 68)
+	assert.Equal(t, backendKey, sut.BackendKey(long))
+	assert.True(t, sut.Hashed(backendKey))
+	//the hash cannot be reversed, the adapter keeps the key
+	assert.Equal(t, strings.TrimPrefix(backendKey, "ns:"), sut.Key(backendKey))
+	//the pattern is matched before the key is hashed
+	assert.True(t, sut.Valid(long))
+
+	unsafe := sut.BackendKey("a key")
+	assert.NotEqual(t, "ns:a key", unsafe)
+	assert.True(t, sut.Hashed(unsafe))
+	assert.False(t, sut.Valid("a key"))
+
+	//a policy without key hashing hashes no keys
+	other := adapter.NewKeyPolicy(storage.StorageOptions{storage.OptNamespace: "ns:"})
+	assert.False(t, other.Hashed(backendKey))
+}
+
+func TestAbstractAdapter_KeyPolicy(t *testing.T) {
+	sut := new(adapter.AbstractAdapter)
+	sut.SetOptions(storage.StorageOptions{
+		storage.OptNamespace: "ns:",
+		storage.OptKeyCodec:  storage.SafeKeyCodec{},
+	})
+	policy := sut.KeyPolicy()
+	assert.Same(t, policy, sut.KeyPolicy())
+	assert.Equal(t, "ns:a%20key", sut.NamespacedKey("a key"))
+
+	//changes to the options returned by GetOptions are seen
+	sut.GetOptions()[storage.OptNamespace] = "other:"
+	assert.Equal(t, "other:a%20key", sut.NamespacedKey("a key"))
+	sut.GetOptions()[storage.OptKeyHashing] = storage.KeyHashing{Unsafe: storage.UnsafeKey}
+	assert.True(t, sut.KeyPolicy().Hashed(sut.NamespacedKey("a key")))
+	sut.GetOptions()[storage.OptKeyPattern] = "^other:[a-z]+$"
+	assert.False(t, sut.ValidateItemKey("key1"))
+	policy = sut.KeyPolicy()
+	assert.Same(t, policy, sut.KeyPolicy())
+}
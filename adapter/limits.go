@@ -0,0 +1,43 @@
+package adapter
+
+import (
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+)
+
+/** Limits enforced on write **/
+
+// CheckKeyLength returns an *errors.KeyTooLongError if the key sent to the backend, backendKey, is longer than
+// OptMaxKeyLength
+func (a *AbstractAdapter) CheckKeyLength(key, backendKey string) error {
+	maxLen := a.options.MaxKeyLength()
+	if maxLen > 0 && len(backendKey) > maxLen {
+		return &errors.KeyTooLongError{Key: key, Length: len(backendKey), Max: maxLen}
+	}
+	return nil
+}
+
+// CheckDataType returns an error wrapping errors.ErrUnsupportedDataType if the type of the value is not in
+// OptDataTypes. Any type can be written if there is a codec, as the codec encodes it
+func (a *AbstractAdapter) CheckDataType(key string, value any) error {
+	if a.Codec() != nil {
+		return nil
+	}
+	t := storage.GetType(value)
+	if !a.options.DataTypes()[t] {
+		return errs.Wrap(errors.ErrUnsupportedDataType, fmt.Sprintf("key: %s type: %d, value: %v", key, t, value))
+	}
+	return nil
+}
+
+// CheckValueSize returns an *errors.ValueTooLargeError if size, the size of the encoded value sent to the backend,
+// is larger than OptMaxValueLength
+func (a *AbstractAdapter) CheckValueSize(key string, size int) error {
+	maxLen := a.options.MaxValueLength()
+	if maxLen > 0 && size > maxLen {
+		return &errors.ValueTooLargeError{Key: key, Size: size, Max: maxLen}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Logging configures the logging of an adapter's failed and slow operations, and of the errors that it does not
+// return, such as a failure to set an item in a chained adapter
+type Logging struct {
+	//Logger the logger. Logging is disabled if it is nil
+	Logger *slog.Logger
+	//Level the level that failed operations and errors are logged at. Defaults to slog.LevelError
+	Level slog.Leveler
+	//SlowLevel the level that slow operations are logged at. Defaults to slog.LevelWarn
+	SlowLevel slog.Leveler
+	//SlowThreshold operations that take longer than SlowThreshold are logged as slow. 0 does not log slow operations
+	SlowThreshold time.Duration
+	//RedactKey, if set, is called with each key before it is logged, e.g. RedactKey or HashKey
+	RedactKey func(key string) string
+}
+
+// RedactKey redacts a key completely
+func RedactKey(string) string {
+	return "[redacted]"
+}
+
+// HashKey redacts a key with the first 16 hex characters of its SHA-256 hash, so that log entries for the same
+// key can be correlated
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SetLogging sets the logging configuration. Use SetLoggingChain to set it for a chain of adapters
+func (a *AbstractAdapter) SetLogging(l Logging) {
+	if l.Logger == nil {
+		a.logging = nil
+		return
+	}
+	if l.Level == nil {
+		l.Level = slog.LevelError
+	}
+	if l.SlowLevel == nil {
+		l.SlowLevel = slog.LevelWarn
+	}
+	a.logging = &l
+}
+
+// SetLoggingChain sets the logging configuration on s and each adapter chained below it that is an AbstractAdapter.
+// Decorated adapters are unwrapped. Returns s
+func SetLoggingChain(s storage.Storage, l Logging) storage.Storage {
+	for c := s; c != nil; {
+		c = decorator.Unwrap(c)
+		if a, ok := c.(*AbstractAdapter); ok {
+			a.SetLogging(l)
+		}
+		ch, ok := c.(storage.Chainable)
+		if !ok {
+			break
+		}
+		c = ch.GetChained()
+	}
+	return s
+}
+
+// LogError logs an error that the adapter does not return, with the keys it is for, if logging is set.
+// Adapters call it for errors such as a failure to set an item in a chained adapter
+func (a *AbstractAdapter) LogError(ctx context.Context, msg string, err error, keys ...string) {
+	if a.logging == nil || err == nil {
+		return
+	}
+	args := append(a.logArgs(keys), slog.Any("error", err))
+	a.logging.Logger.Log(ctx, a.logging.Level.Level(), msg, args...)
+}
+
+// logOperation logs the operation if it failed or was slow
+func (a *AbstractAdapter) logOperation(ctx context.Context, op storage.Operation, params event.Params, elapsed time.Duration, err error) {
+	failed := err != nil && !errs.Is(err, errors.ErrKeyNotFound)
+	slow := a.logging.SlowThreshold > 0 && elapsed > a.logging.SlowThreshold
+	if !failed && !slow {
+		return
+	}
+	keys := params.Keys
+	if params.Key != "" {
+		keys = []string{params.Key}
+	} else if keys == nil && params.Values != nil {
+		keys = slices.Sorted(maps.Keys(params.Values))
+	}
+	args := append(a.logArgs(keys), slog.String("operation", string(op)), slog.Duration("duration", elapsed))
+	if failed {
+		args = append(args, slog.Any("error", err))
+		a.logging.Logger.Log(ctx, a.logging.Level.Level(), "cache operation failed", args...)
+		return
+	}
+	a.logging.Logger.Log(ctx, a.logging.SlowLevel.Level(), "slow cache operation", args...)
+}
+
+// logArgs returns the log attributes for the adapter and keys
+func (a *AbstractAdapter) logArgs(keys []string) []any {
+	ns := a.options.Namespace()
+	args := []any{slog.String("adapter", a.Name), slog.String("namespace", ns)}
+	if len(keys) == 0 {
+		return args
+	}
+	if a.logging.RedactKey != nil {
+		redacted := make([]string, len(keys))
+		for i, key := range keys {
+			redacted[i] = a.logging.RedactKey(key)
+		}
+		keys = redacted
+	}
+	if len(keys) == 1 {
+		return append(args, slog.String("key", keys[0]))
+	}
+	return append(args, slog.Any("keys", keys))
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"github.com/chippyash/go-cache-manager/storage"
+)
+
+func init() {
+	storage.Register("memory", FromDSN)
+	storage.RegisterParams("memory", storage.ParamsFactory{
+		Params: map[string]storage.Kind{
+			"ttl":      storage.KindDuration,
+			"purgeTtl": storage.KindDuration,
+		},
+		New: FromParams,
+	})
+}
+
+// FromDSN creates a memory adapter from a DSN, e.g. "memory://?namespace=users:&ttl=5m&purgeTtl=10m".
+// purgeTtl defaults to twice the ttl
+func FromDSN(dsn storage.DSN) (storage.Storage, error) {
+	if err := dsn.Validate("namespace", "ttl", "purgeTtl"); err != nil {
+		return nil, err
+	}
+	ttl, err := dsn.DurationParam("ttl", 0)
+	if err != nil {
+		return nil, err
+	}
+	purgeTtl, err := dsn.DurationParam("purgeTtl", ttl*2)
+	if err != nil {
+		return nil, err
+	}
+	return New(dsn.Param("namespace", ""), ttl, purgeTtl), nil
+}
+
+// FromParams creates a memory adapter from named parameters, e.g. the options of a tier in a configuration file.
+// purgeTtl defaults to twice the ttl
+func FromParams(p storage.Params) (storage.Storage, error) {
+	ttl := p.Duration("ttl", 0)
+	return New(p.String("namespace", ""), ttl, p.Duration("purgeTtl", ttl*2)), nil
+}
@@ -1,23 +1,31 @@
 package memory
 
 import (
+	"context"
 	"fmt"
 	"github.com/patrickmn/go-cache"
 	errs "github.com/pkg/errors"
 	adapter2 "github.com/chippyash/go-cache-manager/adapter"
 	"github.com/chippyash/go-cache-manager/errors"
 	"github.com/chippyash/go-cache-manager/storage"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
 const (
 	//OptPurgeTtl expires any cache item older than a time.Duration
-	OptPurgeTtl = iota + storage.OptDataTypes + 1
+	OptPurgeTtl = iota + storage.OptRangeMemory
 )
 
-func New(namespace string, ttl, purgeTtl time.Duration) storage.Storage {
+// New returns a memory adapter. The options, e.g. storage.WithCodec(), change the defaults
+func New(namespace string, ttl, purgeTtl time.Duration, options ...storage.Option) storage.Storage {
 	//set the options
-	dTypes := storage.DefaultDataTypes
+	//values are stored as they are, so values of any type can be stored
+	dTypes := maps.Clone(storage.DefaultDataTypes)
+	dTypes[storage.TypeUnknown] = true
 	opts := storage.StorageOptions{
 		storage.OptNamespace:      namespace,
 		storage.OptKeyPattern:     "",
@@ -27,44 +35,284 @@ func New(namespace string, ttl, purgeTtl time.Duration) storage.Storage {
 		storage.OptMaxKeyLength:   0,
 		storage.OptMaxValueLength: 0,
 		storage.OptDataTypes:      dTypes,
+		storage.OptCodec:          nil,
 		OptPurgeTtl:               purgeTtl,
-	}
+	}.Apply(options...)
 
 	adapter := new(adapter2.AbstractAdapter)
 	adapter.Name = "memory"
-	adapter.Client = cache.New(ttl, purgeTtl)
+	adapter.Client = cache.New(opts.TTL(), opts.Duration(OptPurgeTtl, purgeTtl))
 	adapter.SetOptions(opts)
 
+	//encode and decode values with the codec if there is one
+	encode := func(v any) (any, error) {
+		codec := adapter.Codec()
+		if codec == nil {
+			return v, nil
+		}
+		b, err := codec.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	decode := func(v any) (any, error) {
+		codec := adapter.Codec()
+		if codec == nil {
+			return v, nil
+		}
+		b, ok := v.([]byte)
+		if !ok {
+			return v, nil
+		}
+		return codec.Decode(b)
+	}
+	//encodeItem checks the key length and data type, and returns the encoded value if it is not too large
+	encodeItem := func(key, nsKey string, value any) (any, error) {
+		if err := adapter.CheckKeyLength(key, nsKey); err != nil {
+			return nil, err
+		}
+		if err := adapter.CheckDataType(key, value); err != nil {
+			return nil, err
+		}
+		v, err := encode(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := adapter.CheckValueSize(key, storage.Size(v)); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	//syncTags keeps the expiry of the item tags in sync with the expiry of the item
+	syncTags := func(nsKey string) {
+		c := adapter.Client.(*cache.Cache)
+		tagsKey := fmt.Sprintf(adapter2.TagsKeyTpl, nsKey)
+		tags, found := c.Get(tagsKey)
+		if !found {
+			return
+		}
+		_, exp, found := c.GetWithExpiration(nsKey)
+		switch {
+		case !found:
+			c.Delete(tagsKey)
+		case exp.IsZero():
+			c.Set(tagsKey, tags, cache.NoExpiration)
+		default:
+			c.Set(tagsKey, tags, time.Until(exp))
+		}
+	}
+
+	//syncKey keeps the key of a hashed item key, with the same expiry as the item
+	syncKey := func(key, nsKey string) {
+		if !adapter.KeyPolicy().Hashed(nsKey) {
+			return
+		}
+		c := adapter.Client.(*cache.Cache)
+		keyKey := fmt.Sprintf(adapter2.KeyKeyTpl, nsKey)
+		_, exp, found := c.GetWithExpiration(nsKey)
+		switch {
+		case !found:
+			c.Delete(keyKey)
+		case exp.IsZero():
+			c.Set(keyKey, key, cache.NoExpiration)
+		default:
+			c.Set(keyKey, key, time.Until(exp))
+		}
+	}
+	//keyOf returns the key for the namespaced key, looking up the key of a hashed key
+	keyOf := func(nsKey string) string {
+		if adapter.KeyPolicy().Hashed(nsKey) {
+			if key, found := adapter.Client.(*cache.Cache).Get(fmt.Sprintf(adapter2.KeyKeyTpl, nsKey)); found {
+				return key.(string)
+			}
+		}
+		return adapter.StripNamespace(nsKey)
+	}
+
+	//cacheMiss remembers that the item is not found, for OptNegativeTTL
+	cacheMiss := func(nsKey string) {
+		if ttl := adapter.GetOptions().NegativeTTL(); ttl > 0 {
+			adapter.Client.(*cache.Cache).Set(fmt.Sprintf(adapter2.NegativeKeyTpl, nsKey), true, ttl)
+		}
+	}
+	//cachedMiss returns true if the item is remembered as not found
+	cachedMiss := func(nsKey string) bool {
+		if adapter.GetOptions().NegativeTTL() <= 0 {
+			return false
+		}
+		_, found := adapter.Client.(*cache.Cache).Get(fmt.Sprintf(adapter2.NegativeKeyTpl, nsKey))
+		return found
+	}
+	//clearMiss forgets that the item is not found
+	clearMiss := func(nsKey string) {
+		adapter.Client.(*cache.Cache).Delete(fmt.Sprintf(adapter2.NegativeKeyTpl, nsKey))
+	}
+
+	//markFresh marks the item as fresh until OptSoftTTL
+	markFresh := func(nsKey string) {
+		if ttl := adapter.GetOptions().SoftTTL(); ttl > 0 {
+			adapter.Client.(*cache.Cache).Set(fmt.Sprintf(adapter2.SoftKeyTpl, nsKey), true, ttl)
+		}
+	}
+	//stale returns true if the item is no longer fresh
+	stale := func(nsKey string) bool {
+		if adapter.GetOptions().SoftTTL() <= 0 {
+			return false
+		}
+		_, found := adapter.Client.(*cache.Cache).Get(fmt.Sprintf(adapter2.SoftKeyTpl, nsKey))
+		return !found
+	}
+
+	//deleteItem deletes the item and its metadata: tags, key, negative, soft TTL and delta keys
+	deleteItem := func(nsKey string) {
+		c := adapter.Client.(*cache.Cache)
+		c.Delete(nsKey)
+		for _, tpl := range []string{
+			adapter2.TagsKeyTpl, adapter2.KeyKeyTpl, adapter2.NegativeKeyTpl, adapter2.SoftKeyTpl, adapter2.DeltaKeyTpl,
+		} {
+			c.Delete(fmt.Sprintf(tpl, nsKey))
+		}
+	}
+
+	//clearMatching removes the items in the namespace whose keys, without the namespace, match
+	clearMatching := func(ctx context.Context, match func(key string) bool) error {
+		if !adapter.GetOptions().Writable() {
+			return errors.ErrNotWritable
+		}
+		c := adapter.Client.(*cache.Cache)
+		ns := adapter.NamespacedPrefix("")
+		for k := range c.Items() {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !strings.HasPrefix(k, ns) || strings.HasPrefix(k, adapter2.ReservedKeyPrefix) {
+				continue
+			}
+			if match(keyOf(k)) {
+				deleteItem(k)
+			}
+		}
+		return nil
+	}
+
+	//snapshot returns the items in the namespace with the prefix and their sorted namespaced keys.
+	//The keys are matched after they are decoded, so hashed keys are found too
+	snapshot := func(prefix string) ([]string, map[string]cache.Item) {
+		items := adapter.Client.(*cache.Cache).Items()
+		ns := adapter.NamespacedPrefix("")
+		keys := make([]string, 0, len(items))
+		for k := range items {
+			if strings.HasPrefix(k, ns) && !strings.HasPrefix(k, adapter2.ReservedKeyPrefix) &&
+				strings.HasPrefix(keyOf(k), prefix) {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		return keys, items
+	}
+
+	//setItem sets the item with the ttl, passing the ttl on to the chained adapter
+	setItem := func(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+		if !adapter.GetOptions().Writable() {
+			return false, errors.ErrNotWritable
+		}
+		nsKey := adapter.NamespacedKey(key)
+		if !adapter.ValidateItemKey(key) {
+			return false, errors.ErrKeyInvalid
+		}
+		v, err := encodeItem(key, nsKey, value)
+		if err != nil {
+			return false, err
+		}
+		//storage.NoExpiry has the same value as cache.NoExpiration
+		adapter.Client.(*cache.Cache).Set(nsKey, v, adapter.ResolveTTL(ttl))
+		syncTags(nsKey)
+		syncKey(key, nsKey)
+		clearMiss(nsKey)
+		markFresh(nsKey)
+		if adapter.GetChained() != nil {
+			if _, err := storage.SetItemWithTTL(ctx, adapter.GetChained(), key, value, ttl); err != nil {
+				adapter.LogError(ctx, "failed to set item in chained adapter", err, key)
+			}
+		}
+		return true, nil
+	}
+	//touchItem resets the TTL of the item to ttl, passing the ttl on to the chained adapter
+	touchItem := func(ctx context.Context, key string, ttl time.Duration) bool {
+		if !adapter.GetOptions().Readable() {
+			return false
+		}
+		if !adapter.GetOptions().Writable() {
+			return false
+		}
+		nsKey := adapter.NamespacedKey(key)
+		if !adapter.ValidateItemKey(key) {
+			return false
+		}
+		val, found := adapter.Client.(*cache.Cache).Get(nsKey)
+		if found {
+			adapter.Client.(*cache.Cache).Set(nsKey, val, adapter.ResolveTTL(ttl))
+			syncTags(nsKey)
+			syncKey(key, nsKey)
+		}
+		if adapter.GetChained() != nil {
+			return storage.TouchItemWithTTL(ctx, adapter.GetChained(), key, ttl)
+		}
+		return found
+	}
+
 	//set the functions
 	adapter.
-		SetGetItemFunc(func(key string) (any, error) {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
+		SetGetItemCtxFunc(func(ctx context.Context, key string) (any, error) {
+			if !adapter.GetOptions().Readable() {
 				return nil, errors.ErrNotReadable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return nil, errors.ErrKeyInvalid
 			}
 			val, found := adapter.Client.(*cache.Cache).Get(nsKey)
 			if !found {
+				if cachedMiss(nsKey) {
+					return nil, errors.ErrKeyNotFound
+				}
 				if adapter.GetChained() != nil {
-					val, err := adapter.GetChained().GetItem(key)
+					val, err := adapter.GetChainedCtx().GetItemCtx(ctx, key)
 					if err != nil {
+						if ctx.Err() != nil {
+							return nil, ctx.Err()
+						}
+						if !errs.Is(err, errors.ErrKeyNotFound) {
+							adapter.LogError(ctx, "failed to get item from chained adapter", err, key)
+						} else {
+							cacheMiss(nsKey)
+						}
 						return nil, errors.ErrKeyNotFound
 					}
-					adapter.SetItem(key, val)
+					if _, err = adapter.SetItemCtx(ctx, key, val); err != nil {
+						adapter.LogError(ctx, "failed to set item found in chained adapter", err, key)
+					}
 					return val, nil
 				}
+				cacheMiss(nsKey)
 				return nil, errors.ErrKeyNotFound
 			}
-			return val, nil
+			if stale(nsKey) {
+				adapter.Refresh(ctx, key)
+			}
+			return decode(val)
 		}).
-		SetGetItemsFunc(func(keys []string) (map[string]any, error) {
+		SetGetItemsCtxFunc(func(ctx context.Context, keys []string) (map[string]any, error) {
 			ret := make(map[string]any)
 			var err error
 			for _, key := range keys {
-				val, found := adapter.GetItem(key)
+				val, found := adapter.GetItemCtx(ctx, key)
 				if found != nil {
+					if ctx.Err() != nil {
+						return ret, ctx.Err()
+					}
 					if errs.Is(found, errors.ErrKeyInvalid) || errs.Is(found, errors.ErrNotReadable) {
 						err = found
 						continue
@@ -76,26 +324,15 @@ func New(namespace string, ttl, purgeTtl time.Duration) storage.Storage {
 			}
 			return ret, err
 		}).
-		SetSetItemFunc(func(key string, value any) (bool, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
-				return false, errors.ErrNotWritable
-			}
-			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
-				return false, errors.ErrKeyInvalid
-			}
-			adapter.Client.(*cache.Cache).Set(nsKey, value, adapter.GetOptions()[storage.OptTTL].(time.Duration))
-			if adapter.GetChained() != nil {
-				_, _ = adapter.GetChained().SetItem(key, value)
-			}
-			return true, nil
+		SetSetItemCtxFunc(func(ctx context.Context, key string, value any) (bool, error) {
+			return setItem(ctx, key, value, storage.DefaultTTL)
 		}).
-		SetSetItemsFunc(func(values map[string]any) ([]string, error) {
+		SetSetItemsCtxFunc(func(ctx context.Context, values map[string]any) ([]string, error) {
 			keys := make([]string, len(values))
 			i := 0
 			var err error
 			for key, value := range values {
-				_, e := adapter.SetItem(key, value)
+				_, e := adapter.SetItemCtx(ctx, key, value)
 				if e != nil {
 					err = e
 				}
@@ -104,49 +341,65 @@ func New(namespace string, ttl, purgeTtl time.Duration) storage.Storage {
 			}
 			return keys, err
 		}).
-		SetHasItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
+		SetHasItemCtxFunc(func(ctx context.Context, key string) bool {
+			if !adapter.GetOptions().Readable() {
 				return false
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false
 			}
 			_, found := adapter.Client.(*cache.Cache).Get(nsKey)
-			if !found && adapter.GetChained() != nil {
-				return adapter.GetChained().HasItem(key)
+			if !found && adapter.GetChained() != nil && !cachedMiss(nsKey) {
+				return adapter.GetChainedCtx().HasItemCtx(ctx, key)
 			}
 			return found
 		}).
-		SetHasItemsFunc(func(keys []string) map[string]bool {
+		SetHasItemsCtxFunc(func(ctx context.Context, keys []string) map[string]bool {
 			ret := make(map[string]bool)
 			for _, key := range keys {
-				ret[key] = adapter.HasItem(key)
+				ret[key] = adapter.HasItemCtx(ctx, key)
 			}
 			return ret
 		}).
-		SetCheckAndSetItemFunc(func(key string, value any) (bool, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetCheckAndSetItemCtxFunc(func(ctx context.Context, key string, value any) (bool, error) {
+			if !adapter.GetOptions().Writable() {
 				return false, errors.ErrNotWritable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false, errors.ErrKeyInvalid
 			}
-			err := adapter.Client.(*cache.Cache).Replace(nsKey, value, adapter.GetOptions()[storage.OptTTL].(time.Duration))
+			v, err := encodeItem(key, nsKey, value)
 			if err != nil {
+				return false, err
+			}
+			err = adapter.Client.(*cache.Cache).Replace(nsKey, v, adapter.GetOptions().TTL())
+			hit := err == nil
+			if hit {
+				syncTags(nsKey)
+				syncKey(key, nsKey)
+				markFresh(nsKey)
+			} else {
 				err = errors.ErrKeyNotFound
 			}
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().CheckAndSetItem(key, value)
+				chainedHit, chainedErr := adapter.GetChainedCtx().CheckAndSetItemCtx(ctx, key, value)
+				if chainedErr != nil && !errs.Is(chainedErr, errors.ErrKeyNotFound) {
+					adapter.LogError(ctx, "failed to check and set item in chained adapter", chainedErr, key)
+				}
+				if chainedHit && !hit {
+					clearMiss(nsKey)
+					return true, nil
+				}
 			}
-			return err == nil, err
+			return hit, err
 		}).
-		SetCheckAndSetItemsFunc(func(values map[string]any) ([]string, error) {
+		SetCheckAndSetItemsCtxFunc(func(ctx context.Context, values map[string]any) ([]string, error) {
 			keys := make([]string, 0)
 			var err error
 			for key, value := range values {
-				ok, _ := adapter.CheckAndSetItem(key, value)
+				ok, _ := adapter.CheckAndSetItemCtx(ctx, key, value)
 				if !ok {
 					err = errors.ErrKeyNotFound
 					continue
@@ -155,71 +408,58 @@ func New(namespace string, ttl, purgeTtl time.Duration) storage.Storage {
 			}
 			return keys, err
 		}).
-		SetTouchItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
-				return false
-			}
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
-				return false
-			}
-			val, err := adapter.GetItem(key)
-			if err != nil {
-				return false
-			}
-			ok, err := adapter.SetItem(key, val)
-			if err != nil {
-				return false
-			}
-			if adapter.GetChained() != nil {
-				return adapter.GetChained().TouchItem(key)
-			}
-			return ok
+		SetTouchItemCtxFunc(func(ctx context.Context, key string) bool {
+			return touchItem(ctx, key, storage.DefaultTTL)
 		}).
-		SetTouchItemsFunc(func(keys []string) []string {
+		SetTouchItemsCtxFunc(func(ctx context.Context, keys []string) []string {
 			ret := make([]string, 0)
 			for _, key := range keys {
-				if adapter.TouchItem(key) {
+				if adapter.TouchItemCtx(ctx, key) {
 					ret = append(ret, key)
 				}
 			}
 			return ret
 		}).
-		SetRemoveItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetRemoveItemCtxFunc(func(ctx context.Context, key string) bool {
+			if !adapter.GetOptions().Writable() {
 				return false
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false
 			}
-			adapter.Client.(*cache.Cache).Delete(nsKey)
+			deleteItem(nsKey)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().RemoveItem(key)
+				return adapter.GetChainedCtx().RemoveItemCtx(ctx, key)
 			}
 			return true
 		}).
-		SetRemoveItemsFunc(func(keys []string) []string {
+		SetRemoveItemsCtxFunc(func(ctx context.Context, keys []string) []string {
 			for _, key := range keys {
-				adapter.RemoveItem(key)
+				adapter.RemoveItemCtx(ctx, key)
 			}
 			return keys
 		}).
-		SetIncrementFunc(func(key string, n int64) (int64, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetIncrementCtxFunc(func(ctx context.Context, key string, n int64) (int64, error) {
+			if !adapter.GetOptions().Writable() {
 				return 0, errors.ErrNotWritable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return 0, errors.ErrKeyInvalid
 			}
+			if err := adapter.CheckKeyLength(key, nsKey); err != nil {
+				return 0, err
+			}
 			err := adapter.Client.(*cache.Cache).Increment(nsKey, n)
 			if err != nil {
 				return 0, err
 			}
+			clearMiss(nsKey)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().Increment(key, n)
+				return adapter.GetChainedCtx().IncrementCtx(ctx, key, n)
 			}
-			val, err := adapter.GetItem(key)
+			val, err := adapter.GetItemCtx(ctx, key)
 			if err != nil {
 				return 0, err
 			}
@@ -254,22 +494,26 @@ func New(namespace string, ttl, purgeTtl time.Duration) storage.Storage {
 				return 0, fmt.Errorf("value for %s is not an integer or integer like", key)
 			}
 		}).
-		SetDecrementFunc(func(key string, n int64) (int64, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetDecrementCtxFunc(func(ctx context.Context, key string, n int64) (int64, error) {
+			if !adapter.GetOptions().Writable() {
 				return 0, errors.ErrNotWritable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return 0, errors.ErrKeyInvalid
 			}
+			if err := adapter.CheckKeyLength(key, nsKey); err != nil {
+				return 0, err
+			}
 			err := adapter.Client.(*cache.Cache).Decrement(nsKey, n)
 			if err != nil {
 				return 0, err
 			}
+			clearMiss(nsKey)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().Decrement(key, n)
+				return adapter.GetChainedCtx().DecrementCtx(ctx, key, n)
 			}
-			val, err := adapter.GetItem(key)
+			val, err := adapter.GetItemCtx(ctx, key)
 			if err != nil {
 				return 0, err
 			}
@@ -309,6 +553,240 @@ func New(namespace string, ttl, purgeTtl time.Duration) storage.Storage {
 		}).
 		SetCloseFunc(func() error {
 			return nil
+		}).
+		SetSetItemWithTTLFunc(setItem).
+		SetSetItemsWithTTLFunc(func(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error) {
+			keys := make([]string, len(values))
+			i := 0
+			var err error
+			for key, value := range values {
+				_, e := adapter.SetItemWithTTLCtx(ctx, key, value, ttl)
+				if e != nil {
+					err = e
+				}
+				keys[i] = key
+				i++
+			}
+			return keys, err
+		}).
+		SetTouchItemWithTTLFunc(touchItem).
+		SetGetTTLFunc(func(ctx context.Context, key string) (time.Duration, error) {
+			if !adapter.GetOptions().Readable() {
+				return 0, errors.ErrNotReadable
+			}
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return 0, errors.ErrKeyInvalid
+			}
+			_, exp, found := adapter.Client.(*cache.Cache).GetWithExpiration(nsKey)
+			if !found {
+				return 0, errors.ErrKeyNotFound
+			}
+			if exp.IsZero() {
+				return storage.NoExpiry, nil
+			}
+			return time.Until(exp), nil
+		}).
+		SetSetTagsFunc(func(ctx context.Context, key string, tags []string) (bool, error) {
+			if !adapter.GetOptions().Writable() {
+				return false, errors.ErrNotWritable
+			}
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return false, errors.ErrKeyInvalid
+			}
+			c := adapter.Client.(*cache.Cache)
+			if _, found := c.Get(nsKey); !found {
+				return false, errors.ErrKeyNotFound
+			}
+			tagsKey := fmt.Sprintf(adapter2.TagsKeyTpl, nsKey)
+			if len(tags) == 0 {
+				c.Delete(tagsKey)
+			} else {
+				c.Set(tagsKey, slices.Compact(slices.Sorted(slices.Values(tags))), cache.NoExpiration)
+				syncTags(nsKey)
+			}
+			if t, ok := adapter.GetChained().(storage.Taggable); ok {
+				if _, err := t.SetTagsCtx(ctx, key, tags...); err != nil {
+					adapter.LogError(ctx, "failed to set tags in chained adapter", err, key)
+				}
+			}
+			return true, nil
+		}).
+		SetGetTagsFunc(func(ctx context.Context, key string) ([]string, error) {
+			if !adapter.GetOptions().Readable() {
+				return []string{}, errors.ErrNotReadable
+			}
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return []string{}, errors.ErrKeyInvalid
+			}
+			c := adapter.Client.(*cache.Cache)
+			if _, found := c.Get(nsKey); !found {
+				return []string{}, errors.ErrKeyNotFound
+			}
+			tags, found := c.Get(fmt.Sprintf(adapter2.TagsKeyTpl, nsKey))
+			if !found {
+				return []string{}, nil
+			}
+			return slices.Clone(tags.([]string)), nil
+		}).
+		SetClearByTagsFunc(func(ctx context.Context, tags []string, disjunction bool) (bool, error) {
+			if !adapter.GetOptions().Writable() {
+				return false, errors.ErrNotWritable
+			}
+			prefix := fmt.Sprintf(adapter2.TagsKeyTpl, adapter.NamespacedPrefix(""))
+			for k, item := range adapter.Client.(*cache.Cache).Items() {
+				if ctx.Err() != nil {
+					return false, ctx.Err()
+				}
+				if !strings.HasPrefix(k, prefix) || len(tags) == 0 {
+					continue
+				}
+				itemTags := item.Object.([]string)
+				match := !disjunction
+				for _, tag := range tags {
+					if slices.Contains(itemTags, tag) == disjunction {
+						match = disjunction
+						break
+					}
+				}
+				if match {
+					adapter.RemoveItemCtx(ctx, keyOf(strings.TrimPrefix(k, fmt.Sprintf(adapter2.TagsKeyTpl, ""))))
+				}
+			}
+			if t, ok := adapter.GetChained().(storage.Taggable); ok {
+				return t.ClearByTagsCtx(ctx, tags, disjunction)
+			}
+			return true, nil
+		}).
+		SetFlushFunc(func(ctx context.Context) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			c := adapter.Client.(*cache.Cache)
+			ns := adapter.NamespacedPrefix("")
+			if ns == "" {
+				c.Flush()
+				return nil
+			}
+			//the client may be shared with other namespaces so only the namespace items and their metadata are removed
+			tagsPrefix := fmt.Sprintf(adapter2.TagsKeyTpl, ns)
+			negativePrefix := fmt.Sprintf(adapter2.NegativeKeyTpl, ns)
+			softPrefix := fmt.Sprintf(adapter2.SoftKeyTpl, ns)
+			deltaPrefix := fmt.Sprintf(adapter2.DeltaKeyTpl, ns)
+			keyPrefix := fmt.Sprintf(adapter2.KeyKeyTpl, ns)
+			for k := range c.Items() {
+				if strings.HasPrefix(k, ns) || strings.HasPrefix(k, tagsPrefix) || strings.HasPrefix(k, negativePrefix) ||
+					strings.HasPrefix(k, softPrefix) || strings.HasPrefix(k, deltaPrefix) || strings.HasPrefix(k, keyPrefix) {
+					c.Delete(k)
+				}
+			}
+			return nil
+		}).
+		SetClearExpiredFunc(func(ctx context.Context) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			adapter.Client.(*cache.Cache).DeleteExpired()
+			return nil
+		}).
+		SetClearByPrefixFunc(func(ctx context.Context, prefix string) error {
+			err := clearMatching(ctx, func(key string) bool {
+				return strings.HasPrefix(key, prefix)
+			})
+			if c, ok := adapter.GetChained().(storage.Clearable); ok && err == nil {
+				return c.ClearByPrefixCtx(ctx, prefix)
+			}
+			return err
+		}).
+		SetClearByPatternFunc(func(ctx context.Context, pattern string) error {
+			err := clearMatching(ctx, func(key string) bool {
+				return adapter2.MatchGlob(pattern, key)
+			})
+			if c, ok := adapter.GetChained().(storage.Clearable); ok && err == nil {
+				return c.ClearByPatternCtx(ctx, pattern)
+			}
+			return err
+		}).
+		SetIterateFunc(func(ctx context.Context, opts storage.IterateOptions) iter.Seq2[string, any] {
+			return func(yield func(string, any) bool) {
+				if !adapter.GetOptions().Readable() {
+					opts.Error(errors.ErrNotReadable)
+					return
+				}
+				keys, items := snapshot(opts.Prefix)
+				for _, k := range keys {
+					if ctx.Err() != nil {
+						opts.Error(ctx.Err())
+						return
+					}
+					v, err := decode(items[k].Object)
+					if err != nil {
+						opts.Error(err)
+						return
+					}
+					if !yield(keyOf(k), v) {
+						return
+					}
+				}
+			}
+		}).
+		SetIterateKeysFunc(func(ctx context.Context, opts storage.IterateOptions) iter.Seq[string] {
+			return func(yield func(string) bool) {
+				if !adapter.GetOptions().Readable() {
+					opts.Error(errors.ErrNotReadable)
+					return
+				}
+				keys, _ := snapshot(opts.Prefix)
+				for _, k := range keys {
+					if ctx.Err() != nil {
+						opts.Error(ctx.Err())
+						return
+					}
+					if !yield(keyOf(k)) {
+						return
+					}
+				}
+			}
+		}).
+		SetGetDeltasFunc(func(ctx context.Context, keys []string) map[string]adapter2.Delta {
+			c := adapter.Client.(*cache.Cache)
+			ret := make(map[string]adapter2.Delta, len(keys))
+			for _, key := range keys {
+				nsKey := adapter.NamespacedKey(key)
+				delta, found := c.Get(fmt.Sprintf(adapter2.DeltaKeyTpl, nsKey))
+				if !found {
+					continue
+				}
+				_, exp, found := c.GetWithExpiration(nsKey)
+				if !found {
+					continue
+				}
+				ttl := storage.NoExpiry
+				if !exp.IsZero() {
+					ttl = time.Until(exp)
+				}
+				ret[key] = adapter2.Delta{Compute: delta.(time.Duration), TTL: ttl}
+			}
+			return ret
+		}).
+		SetSetDeltasFunc(func(ctx context.Context, deltas map[string]time.Duration) {
+			c := adapter.Client.(*cache.Cache)
+			for key, delta := range deltas {
+				nsKey := adapter.NamespacedKey(key)
+				//the delta expires with the item
+				_, exp, found := c.GetWithExpiration(nsKey)
+				if !found || exp.IsZero() {
+					continue
+				}
+				c.Set(fmt.Sprintf(adapter2.DeltaKeyTpl, nsKey), delta, time.Until(exp))
+			}
+		}).
+		SetCapabilities(storage.Capabilities{
+			TTL:          true,
+			TTLPrecision: time.Nanosecond,
+			Persistent:   false,
 		})
 
 	return adapter
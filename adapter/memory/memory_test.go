@@ -1,14 +1,24 @@
 package memory_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
 	"github.com/chippyash/go-cache-manager/adapter"
 	"github.com/chippyash/go-cache-manager/adapter/memory"
 	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
 	"github.com/chippyash/go-cache-manager/storage"
 	"github.com/patrickmn/go-cache"
+	errs "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"log/slog"
 	"maps"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -128,6 +138,39 @@ func TestMemoryAdapter_Chaining(t *testing.T) {
 	assert.True(t, found)
 }
 
+func TestMemoryAdapter_ChainedCheckAndSetItem(t *testing.T) {
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	sut := memory.New("two:", time.Second*60, time.Second*120)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	_, err := sut.(storage.Chainable).GetChained().SetItem("chained", "bar")
+	assert.NoError(t, err)
+	_, err = sut.SetItem("both", "bar")
+	assert.NoError(t, err)
+	adapterClient := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache)
+	adapterClient.Set("two:own", "bar", time.Minute)
+
+	//set in this adapter, not in the chained adapter
+	ok, err := sut.CheckAndSetItem("own", "baz")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.False(t, chainedAdapter.HasItem("own"))
+	//set in the chained adapter, not in this adapter
+	ok, err = sut.CheckAndSetItem("chained", "baz")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	_, found := adapterClient.Get("two:chained")
+	assert.False(t, found)
+	//set in both
+	ok, err = sut.CheckAndSetItem("both", "baz")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	val, _ := chainedAdapter.GetItem("both")
+	assert.Equal(t, "baz", val)
+	//set in neither
+	ok, err = sut.CheckAndSetItem("none", "baz")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+}
 func TestMemoryAdapter_CheckAndSetItem(t *testing.T) {
 	sut := memory.New("", time.Second*60, time.Second*120)
 	ok, err := sut.SetItem("foo", "bar")
@@ -219,6 +262,37 @@ func TestMemoryAdapter_RemoveMultipleItems(t *testing.T) {
 	assert.True(t, slices.Contains(keys, "bar"))
 }
 
+func TestMemoryAdapter_RemoveItemMetadata(t *testing.T) {
+	sut := memory.New("ns:", time.Second*60, time.Second*120,
+		storage.WithSoftTTL(time.Second*30), storage.WithXFetchBeta(1e-9), storage.WithNegativeTTL(time.Second*10))
+	c := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache)
+	metaKeys := func(nsKey string) []string {
+		keys := make([]string, 0)
+		for _, tpl := range []string{adapter.NegativeKeyTpl, adapter.SoftKeyTpl, adapter.DeltaKeyTpl, adapter.TagsKeyTpl} {
+			if _, found := c.Get(fmt.Sprintf(tpl, nsKey)); found {
+				keys = append(keys, tpl)
+			}
+		}
+		return keys
+	}
+	for _, key := range []string{"foo", "bar"} {
+		_, err := sut.(storage.ReadThrough).GetOrSet(key, func() (any, error) {
+			return "baz", nil
+		})
+		assert.NoError(t, err)
+		_, err = sut.(storage.Taggable).SetTags(key, "tag")
+		assert.NoError(t, err)
+		//a miss remembered by another process
+		c.Set(fmt.Sprintf(adapter.NegativeKeyTpl, "ns:"+key), true, time.Second*10)
+		assert.Len(t, metaKeys("ns:"+key), 4)
+	}
+
+	assert.True(t, sut.RemoveItem("foo"))
+	assert.Empty(t, metaKeys("ns:foo"))
+	sut.RemoveItems([]string{"bar"})
+	assert.Empty(t, metaKeys("ns:bar"))
+}
+
 func TestMemoryAdapter_IncrementValidNumber(t *testing.T) {
 	sut := memory.New("", time.Second*60, time.Second*120)
 	keys := map[string]any{
@@ -296,3 +370,910 @@ func TestMemoryAdapter_GetClient(t *testing.T) {
 	client := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache)
 	assert.IsType(t, cache.Cache{}, client)
 }
+
+func TestMemoryAdapter_Context(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+	ok, err := sut.(storage.StorageContext).SetItemCtx(context.Background(), "foo", "bar")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	val, err := sut.(storage.StorageContext).GetItemCtx(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	val, err = sut.(storage.StorageContext).GetItemCtx(ctx, "foo")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, val)
+	assert.False(t, sut.(storage.StorageContext).HasItemCtx(ctx, "foo"))
+}
+
+func TestMemoryAdapter_ContextCancelsChain(t *testing.T) {
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	_, err := chainedAdapter.SetItem("key1", "value1")
+	assert.NoError(t, err)
+
+	sut := memory.New("two:", time.Second*60, time.Second*120)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = sut.(storage.StorageContext).GetItemsCtx(ctx, []string{"key1"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	//the chained value must not have been promoted
+	_, found := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get("two:key1")
+	assert.False(t, found)
+}
+
+type codecTestStruct struct {
+	Name  string
+	Count int
+}
+
+func TestMemoryAdapter_Codec(t *testing.T) {
+	gob.Register(codecTestStruct{})
+	sut := memory.New("", time.Second*60, time.Second*120)
+	opts := sut.GetOptions()
+	opts[storage.OptCodec] = storage.GobCodec{}
+	sut.SetOptions(opts)
+
+	val := codecTestStruct{Name: "foo", Count: 2}
+	ok, err := sut.SetItem("foo", val)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	//the value is stored encoded
+	raw, found := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get("foo")
+	assert.True(t, found)
+	assert.IsType(t, []byte{}, raw)
+
+	ret, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, val, ret)
+
+	rets, err := sut.GetItems([]string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": val}, rets)
+}
+
+func TestMemoryAdapter_GetOrSet(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	calls := 0
+	loader := func() (any, error) {
+		calls++
+		return "loaded", nil
+	}
+
+	//hit does not call the loader
+	val, err := sut.(storage.ReadThrough).GetOrSet("foo", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	assert.Equal(t, 0, calls)
+
+	//miss calls the loader and sets the value
+	val, err = sut.(storage.ReadThrough).GetOrSet("bop", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+	assert.Equal(t, 1, calls)
+	val, err = sut.GetItem("bop")
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+
+	//loader errors are returned and nothing is set
+	_, err = sut.(storage.ReadThrough).GetOrSet("baz", func() (any, error) {
+		return nil, errs.New("db error")
+	})
+	assert.EqualError(t, err, "db error")
+	assert.False(t, sut.HasItem("baz"))
+}
+
+func TestMemoryAdapter_GetOrSetDeduplicatesLoads(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func() (any, error) {
+		calls.Add(1)
+		<-release
+		return "loaded", nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := sut.(storage.ReadThrough).GetOrSet("foo", loader)
+			assert.NoError(t, err)
+			assert.Equal(t, "loaded", val)
+		}()
+	}
+	//give the goroutines time to join the in-flight load
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestMemoryAdapter_GetOrSetLoaderPanics(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan any)
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		_, _ = sut.(storage.ReadThrough).GetOrSet("foo", func() (any, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	//a caller that joins the in-flight load returns an error rather than blocking
+	done := make(chan error)
+	go func() {
+		_, err := sut.(storage.ReadThrough).GetOrSet("foo", func() (any, error) {
+			return "loaded", nil
+		})
+		done <- err
+	}()
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	assert.Equal(t, "boom", <-panicked)
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "loader panicked: boom")
+	case <-time.After(time.Second):
+		t.Fatal("caller blocked by a panicked load")
+	}
+
+	//later callers load the key again
+	val, err := sut.(storage.ReadThrough).GetOrSet("foo", func() (any, error) {
+		return "loaded", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+
+	assert.Panics(t, func() {
+		_, _ = sut.(storage.ReadThrough).GetOrSetItems([]string{"bar"}, func(keys []string) (map[string]any, error) {
+			panic("boom")
+		})
+	})
+	vals, err := sut.(storage.ReadThrough).GetOrSetItems([]string{"bar"}, func(keys []string) (map[string]any, error) {
+		return map[string]any{"bar": 1}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"bar": 1}, vals)
+}
+
+func TestMemoryAdapter_GetOrSetItems(t *testing.T) {
+	sut := memory.New("ns:", time.Second*60, time.Second*120)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	var requested []string
+	loader := func(keys []string) (map[string]any, error) {
+		requested = keys
+		//keys that were not requested are ignored
+		return map[string]any{"bop": 1, "baz": 2, "extra": 3}, nil
+	}
+
+	vals, err := sut.(storage.ReadThrough).GetOrSetItems([]string{"foo", "bop", "baz", "none"}, loader)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bop", "baz", "none"}, requested)
+	assert.Equal(t, map[string]any{"foo": "bar", "bop": 1, "baz": 2}, vals)
+	assert.True(t, sut.HasItem("bop"))
+	assert.True(t, sut.HasItem("baz"))
+	assert.False(t, sut.HasItem("none"))
+	assert.False(t, sut.HasItem("extra"))
+
+	_, err = sut.(storage.ReadThrough).GetOrSetItems([]string{"none"}, func(keys []string) (map[string]any, error) {
+		return nil, errs.New("db error")
+	})
+	assert.EqualError(t, err, "db error")
+}
+
+func TestMemoryAdapter_GetOrSetXFetch(t *testing.T) {
+	calls := atomic.Int32{}
+	loader := func() (any, error) {
+		time.Sleep(time.Millisecond)
+		return calls.Add(1), nil
+	}
+	//with a tiny beta items are not recomputed early
+	sut := memory.New("ns:", time.Second*60, time.Second*120, storage.WithXFetchBeta(1e-9))
+	for range 3 {
+		val, err := sut.(storage.ReadThrough).GetOrSet("foo", loader)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), val)
+	}
+	delta, found := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get(fmt.Sprintf(adapter.DeltaKeyTpl, "ns:foo"))
+	assert.True(t, found)
+	assert.GreaterOrEqual(t, delta, time.Millisecond)
+
+	//with a huge beta they always are
+	calls.Store(0)
+	sut = memory.New("ns:", time.Second*60, time.Second*120, storage.WithXFetchBeta(1e12))
+	for i := range 3 {
+		val, err := sut.(storage.ReadThrough).GetOrSet("foo", loader)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(i+1), val)
+	}
+	//if recomputing fails, the item that was found is returned
+	val, err := sut.(storage.ReadThrough).GetOrSet("foo", func() (any, error) {
+		return nil, errs.New("db error")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), val)
+
+	var requested []string
+	vals, err := sut.(storage.ReadThrough).GetOrSetItems([]string{"foo", "bar"}, func(keys []string) (map[string]any, error) {
+		requested = keys
+		time.Sleep(time.Millisecond)
+		return map[string]any{"foo": "baz", "bar": "qux"}, nil
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, requested)
+	assert.Equal(t, map[string]any{"foo": "baz", "bar": "qux"}, vals)
+	vals, err = sut.(storage.ReadThrough).GetOrSetItems([]string{"foo", "bar"}, func(keys []string) (map[string]any, error) {
+		return nil, errs.New("db error")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": "baz", "bar": "qux"}, vals)
+
+	//items without a TTL are not recomputed early
+	calls.Store(0)
+	sut = memory.New("ns:", storage.NoExpiry, time.Second*120, storage.WithXFetchBeta(1e12))
+	for range 2 {
+		val, err := sut.(storage.ReadThrough).GetOrSet("foo", loader)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), val)
+	}
+}
+
+func TestMemoryAdapter_ItemTTL(t *testing.T) {
+	sut := memory.New("one:", time.Second*60, time.Second*120)
+	ttlSut := sut.(storage.Expirable)
+	ok, err := ttlSut.SetItemWithTTL("short", "value", time.Millisecond*50)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ok, err = ttlSut.SetItemWithTTL("forever", "value", storage.NoExpiry)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	_, err = sut.SetItem("default", "value")
+	assert.NoError(t, err)
+
+	ttl, err := ttlSut.GetTTL("short")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Millisecond*50)
+	ttl, err = ttlSut.GetTTL("forever")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.NoExpiry, ttl)
+	ttl, err = ttlSut.GetTTL("default")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Second*59)
+	_, err = ttlSut.GetTTL("unknown")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	time.Sleep(time.Millisecond * 60)
+	_, err = sut.GetItem("short")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	_, err = sut.GetItem("forever")
+	assert.NoError(t, err)
+
+	keys, err := ttlSut.SetItemsWithTTL(map[string]any{"foo": "bar", "bar": "baz"}, time.Hour)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, keys)
+	ttl, err = ttlSut.GetTTL("bar")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Second*60)
+}
+
+func TestMemoryAdapter_TouchItemWithTTL(t *testing.T) {
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	sut := memory.New("two:", time.Second*60, time.Second*120)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	assert.True(t, sut.(storage.Expirable).TouchItemWithTTL("foo", time.Hour))
+	ttl, err := sut.(storage.Expirable).GetTTL("foo")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Second*60)
+	//the ttl is passed on to the chained adapter
+	ttl, err = chainedAdapter.(storage.Expirable).GetTTL("foo")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Second*60)
+	//the value is unchanged
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+
+	assert.False(t, memory.New("", time.Second*60, time.Second*120).(storage.Expirable).TouchItemWithTTL("foo", time.Hour))
+}
+
+func TestMemoryAdapter_Tags(t *testing.T) {
+	sut := memory.New("one:", time.Second*60, time.Second*120)
+	tagSut := sut.(storage.Taggable)
+	_, err := sut.SetItems(map[string]any{"order1": 1, "order2": 2, "order3": 3, "product1": "p"})
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("order1", "customer:1", "orders")
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("order2", "customer:2", "orders")
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("order3", "customer:1", "orders")
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("product1", "customer:1")
+	assert.NoError(t, err)
+
+	ok, err := tagSut.SetTags("unknown", "orders")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	tags, err := tagSut.GetTags("order1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"customer:1", "orders"}, tags)
+	_, err = tagSut.GetTags("unknown")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	//conjunction clears the items that have all the tags
+	ok, err = tagSut.ClearByTags([]string{"customer:1", "orders"}, false)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.False(t, sut.HasItem("order1"))
+	assert.True(t, sut.HasItem("order2"))
+	assert.False(t, sut.HasItem("order3"))
+	assert.True(t, sut.HasItem("product1"))
+
+	//disjunction clears the items that have any of the tags
+	ok, err = tagSut.ClearByTags([]string{"customer:2", "customer:1"}, true)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.False(t, sut.HasItem("order2"))
+	assert.False(t, sut.HasItem("product1"))
+
+	//removing an item removes its tags
+	adapterClient := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache)
+	assert.Equal(t, 0, adapterClient.ItemCount())
+}
+
+func TestMemoryAdapter_TagsExpireWithItem(t *testing.T) {
+	sut := memory.New("one:", time.Second*60, time.Second*120)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("foo", "baz")
+	assert.NoError(t, err)
+	adapterClient := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache)
+	_, itemExp, _ := adapterClient.GetWithExpiration("one:foo")
+	_, tagsExp, found := adapterClient.GetWithExpiration(fmt.Sprintf(adapter.TagsKeyTpl, "one:foo"))
+	assert.True(t, found)
+	assert.WithinDuration(t, itemExp, tagsExp, time.Millisecond)
+
+	assert.True(t, sut.(storage.Expirable).TouchItemWithTTL("foo", time.Hour))
+	_, itemExp, _ = adapterClient.GetWithExpiration("one:foo")
+	_, tagsExp, _ = adapterClient.GetWithExpiration(fmt.Sprintf(adapter.TagsKeyTpl, "one:foo"))
+	assert.WithinDuration(t, itemExp, tagsExp, time.Millisecond)
+
+	_, err = sut.(storage.Expirable).SetItemWithTTL("foo", "bar", storage.NoExpiry)
+	assert.NoError(t, err)
+	_, tagsExp, _ = adapterClient.GetWithExpiration(fmt.Sprintf(adapter.TagsKeyTpl, "one:foo"))
+	assert.True(t, tagsExp.IsZero())
+}
+
+func TestMemoryAdapter_ReservedKeys(t *testing.T) {
+	//without a namespace, the key "gcm:gcm:tags:x" would be the tags of "x"
+	sut := memory.New("", time.Second*60, time.Second*120)
+	_, err := sut.SetItem("x", "foo")
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("x", "bar")
+	assert.NoError(t, err)
+	_, err = sut.SetItem(fmt.Sprintf(adapter.TagsKeyTpl, "x"), []string{"baz"})
+	assert.ErrorIs(t, err, errors.ErrKeyInvalid)
+	_, err = sut.SetItem("gcm:x", "foo")
+	assert.ErrorIs(t, err, errors.ErrKeyInvalid)
+	tags, err := sut.(storage.Taggable).GetTags("x")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, tags)
+	assert.Equal(t, []string{"x"}, slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})))
+}
+
+func TestMemoryAdapter_Flush(t *testing.T) {
+	sut := memory.New("one:", time.Second*60, time.Second*120)
+	adapterClient := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache)
+	//an item in another namespace sharing the client
+	adapterClient.Set("two:foo", "bar", cache.NoExpiration)
+	_, err := sut.SetItems(map[string]any{"foo": "bar", "bar": "baz"})
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("foo", "baz")
+	assert.NoError(t, err)
+
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.False(t, sut.HasItem("foo"))
+	assert.False(t, sut.HasItem("bar"))
+	assert.Equal(t, 1, adapterClient.ItemCount())
+
+	sut = memory.New("", time.Second*60, time.Second*120)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.Equal(t, 0, sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).ItemCount())
+}
+
+func TestMemoryAdapter_ClearExpired(t *testing.T) {
+	sut := memory.New("one:", time.Second*60, time.Second*120)
+	_, err := sut.(storage.Expirable).SetItemWithTTL("foo", "bar", time.Millisecond)
+	assert.NoError(t, err)
+	_, err = sut.SetItem("bar", "baz")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 5)
+
+	adapterClient := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache)
+	assert.Equal(t, 2, adapterClient.ItemCount())
+	assert.NoError(t, sut.(storage.ClearExpiredable).ClearExpired())
+	assert.Equal(t, 1, adapterClient.ItemCount())
+}
+
+func TestMemoryAdapter_FlushChain(t *testing.T) {
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	sut := memory.New("two:", time.Second*60, time.Second*120)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	//flushing the adapter does not flush the chained adapter
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.True(t, chainedAdapter.HasItem("foo"))
+	//flushing the chain does
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.NoError(t, storage.FlushChain(context.Background(), sut))
+	assert.False(t, sut.HasItem("foo"))
+	assert.False(t, chainedAdapter.HasItem("foo"))
+}
+
+func TestMemoryAdapter_ClearByPrefixAndPattern(t *testing.T) {
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	sut := memory.New("two:", time.Second*60, time.Second*120)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	_, err := sut.SetItems(map[string]any{
+		"users:42:name":  "bob",
+		"users:42:email": "bob@example.com",
+		"users:43:name":  "alice",
+		"users:420:name": "carol",
+	})
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("users:42:name", "names")
+	assert.NoError(t, err)
+
+	assert.NoError(t, sut.(storage.Clearable).ClearByPrefix("users:42:"))
+	assert.False(t, sut.HasItem("users:42:name"))
+	assert.False(t, sut.HasItem("users:42:email"))
+	assert.True(t, sut.HasItem("users:43:name"))
+	assert.True(t, sut.HasItem("users:420:name"))
+	//the chained adapter is cleared too
+	assert.False(t, chainedAdapter.HasItem("users:42:name"))
+	_, found := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get(fmt.Sprintf(adapter.TagsKeyTpl, "two:users:42:name"))
+	assert.False(t, found)
+
+	assert.NoError(t, sut.(storage.Clearable).ClearByPattern("users:4?:name"))
+	assert.False(t, sut.HasItem("users:43:name"))
+	assert.True(t, sut.HasItem("users:420:name"))
+}
+
+func TestMemoryAdapter_Iterate(t *testing.T) {
+	sut := memory.New("one:", time.Second*60, time.Second*120)
+	vals := map[string]any{
+		"users:1": "bob",
+		"users:2": "alice",
+		"other":   3,
+	}
+	_, err := sut.SetItems(vals)
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("users:1", "tag")
+	assert.NoError(t, err)
+
+	//metadata is not iterated
+	assert.Equal(t, vals, maps.Collect(sut.(storage.Iterable).Iterate(storage.IterateOptions{})))
+	assert.Equal(t,
+		map[string]any{"users:1": "bob", "users:2": "alice"},
+		maps.Collect(sut.(storage.Iterable).Iterate(storage.IterateOptions{Prefix: "users:"})),
+	)
+	assert.Equal(t,
+		[]string{"other", "users:1", "users:2"},
+		slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})),
+	)
+	//the iteration can be stopped
+	for range sut.(storage.Iterable).IterateKeys(storage.IterateOptions{}) {
+		break
+	}
+
+	var iterErr error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	keys := slices.Collect(sut.(storage.Iterable).IterateKeysCtx(ctx, storage.IterateOptions{
+		OnError: func(err error) { iterErr = err },
+	}))
+	assert.Empty(t, keys)
+	assert.ErrorIs(t, iterErr, context.Canceled)
+}
+
+func TestMemoryAdapter_Capabilities(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+
+	c := sut.(storage.Capable).Capabilities()
+	assert.True(t, c.Supports(storage.OpGetItem, storage.OpIncrement, storage.OpSetTags, storage.OpIterate))
+	assert.NoError(t, c.Require(storage.OpCheckAndSetItem, storage.OpTouchItemWithTTL))
+	assert.True(t, c.TTL)
+	assert.Equal(t, time.Nanosecond, c.TTLPrecision)
+	assert.False(t, c.Persistent)
+	assert.Equal(t, 0, c.MaxKeyLength)
+	assert.True(t, c.SupportsType(storage.TypeString))
+
+	//capabilities reflect the options
+	opts := sut.GetOptions()
+	opts[storage.OptMaxKeyLength] = 64
+	sut.SetOptions(opts)
+	assert.Equal(t, 64, sut.(storage.Capable).Capabilities().MaxKeyLength)
+}
+
+func TestMemoryAdapter_Events(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+	events := sut.(event.Capable).GetEventManager()
+
+	//pre listeners can modify the arguments
+	events.Attach("pre.setItem", func(e *event.Event) {
+		e.Params.Key = "prefixed:" + e.Params.Key
+	}, 0)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.True(t, sut.HasItem("prefixed:foo"))
+	assert.False(t, sut.HasItem("foo"))
+
+	//post listeners can modify the result
+	detach := events.Attach("post.getItem", func(e *event.Event) {
+		e.Result = "scrubbed"
+	}, 0)
+	val, err := sut.GetItem("prefixed:foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "scrubbed", val)
+	detach()
+
+	//pre listeners can short-circuit the operation
+	detach = events.Attach("pre.getItem", func(e *event.Event) {
+		e.Result = "short"
+		e.StopPropagation()
+	}, 0)
+	val, err = sut.GetItem("baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "short", val)
+	detach()
+
+	//exception listeners can suppress errors
+	var caught error
+	events.Attach("exception.*", func(e *event.Event) {
+		caught = e.Err
+		e.Err = nil
+		e.Result = "default"
+	}, 0)
+	val, err = sut.GetItem("baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", val)
+	assert.ErrorIs(t, caught, errors.ErrKeyNotFound)
+}
+
+func TestMemoryAdapter_EventsConcurrentFirstUse(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sut.(event.Capable).GetEventManager().Attach("pre.setItem", func(e *event.Event) {
+				calls.Add(1)
+			}, 0)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = sut.SetItem("foo", "bar")
+		}()
+	}
+	wg.Wait()
+
+	//every listener is attached to the same manager
+	calls.Store(0)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(10), calls.Load())
+}
+
+func TestMemoryAdapter_Logging(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	sut := memory.New("", time.Second*60, time.Second*120)
+	chained := memory.New("", time.Second*60, time.Second*120)
+	opts := chained.GetOptions()
+	opts[storage.OptWritable] = false
+	chained.SetOptions(opts)
+	sut.(storage.Chainable).ChainAdapter(chained)
+	adapter.SetLoggingChain(sut, adapter.Logging{
+		Logger:    logger,
+		RedactKey: adapter.RedactKey,
+	})
+
+	//the chained set error is logged but not returned
+	ok, err := sut.SetItem("foo", "bar")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"level":"ERROR","msg":"failed to set item in chained adapter","adapter":"memory","namespace":"","key":"[redacted]","error":"not writable"`)
+	//the chained adapter logs the failed operation
+	assert.Contains(t, buf.String(), `"msg":"cache operation failed","adapter":"memory","namespace":"","key":"[redacted]","operation":"setItemWithTTL"`)
+	assert.NotContains(t, buf.String(), "foo")
+
+	//a miss is not a failure
+	buf.Reset()
+	_, err = sut.GetItem("baz")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.Empty(t, buf.String())
+
+	//slow operations are logged at the slow level
+	sut.(*adapter.AbstractAdapter).SetLogging(adapter.Logging{
+		Logger:        logger,
+		SlowThreshold: time.Nanosecond,
+		SlowLevel:     slog.LevelInfo,
+		RedactKey:     adapter.HashKey,
+	})
+	_, _ = sut.GetItems([]string{"foo", "baz"})
+	assert.Contains(t, buf.String(), fmt.Sprintf(`"level":"INFO","msg":"slow cache operation","adapter":"memory","namespace":"","keys":["%s","%s"],"operation":"getItems"`, adapter.HashKey("foo"), adapter.HashKey("baz")))
+
+	//logging is disabled without a logger
+	buf.Reset()
+	adapter.SetLoggingChain(sut, adapter.Logging{})
+	_, _ = sut.SetItem("foo", "bar")
+	assert.Empty(t, buf.String())
+}
+
+func TestMemoryAdapter_OpenDSN(t *testing.T) {
+	sut, err := storage.OpenDSN("memory://?namespace=users:&ttl=5m")
+	assert.NoError(t, err)
+	assert.Equal(t, "users:", sut.GetOptions()[storage.OptNamespace])
+	assert.Equal(t, time.Minute*5, sut.GetOptions()[storage.OptTTL])
+	assert.Equal(t, time.Minute*10, sut.GetOptions()[memory.OptPurgeTtl])
+
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+
+	_, err = storage.OpenDSN("memory://?ttl=forever")
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+	_, err = storage.OpenDSN("memory://?tll=5m")
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+}
+
+func TestMemoryAdapter_Options(t *testing.T) {
+	sut := memory.New("", time.Minute, time.Minute*2, storage.WithCodec(storage.JsonCodec{}), memory.WithPurgeTtl(time.Hour))
+	assert.Equal(t, storage.JsonCodec{}, sut.GetOptions().Codec())
+	assert.Equal(t, time.Hour, sut.GetOptions().Duration(memory.OptPurgeTtl, 0))
+	_, err := sut.Open()
+	assert.NoError(t, err)
+
+	//invalid options do not panic, and are returned by Open
+	opts := sut.GetOptions()
+	opts[storage.OptWritable] = "no"
+	opts[storage.OptTTL] = -time.Second
+	sut.SetOptions(opts)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = sut.Open()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+	assert.Contains(t, err.Error(), "writable: expected bool, got string")
+	assert.Contains(t, err.Error(), "ttl: must not be negative")
+
+	_, err = memory.New("", -time.Minute, 0).Open()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+}
+
+func TestMemoryAdapter_Limits(t *testing.T) {
+	sut := memory.New("ns:", time.Minute, time.Minute*2, storage.WithMaxKeyLength(8), storage.WithMaxValueLength(5))
+
+	_, err := sut.SetItem("toolong", "foo")
+	assert.ErrorIs(t, err, errors.ErrKeyTooLong)
+	var keyErr *errors.KeyTooLongError
+	assert.True(t, errs.As(err, &keyErr))
+	assert.Equal(t, "toolong", keyErr.Key)
+	assert.Equal(t, 10, keyErr.Length)
+	assert.False(t, sut.HasItem("toolong"))
+
+	_, err = sut.SetItem("foo", "toolarge")
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+	var valErr *errors.ValueTooLargeError
+	assert.True(t, errs.As(err, &valErr))
+	assert.Equal(t, "foo", valErr.Key)
+	assert.Equal(t, 8, valErr.Size)
+	assert.False(t, sut.HasItem("foo"))
+
+	_, err = sut.SetItems(map[string]any{"foo": "bar", "bar": "toolarge"})
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+	assert.True(t, sut.HasItem("foo"))
+	assert.False(t, sut.HasItem("bar"))
+
+	_, err = sut.CheckAndSetItem("foo", "toolarge")
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+
+	//the size is measured after encoding: "foo" is encoded as 5 bytes of JSON
+	opts := sut.GetOptions()
+	opts[storage.OptCodec] = storage.JsonCodec{}
+	opts[storage.OptMaxValueLength] = 4
+	sut.SetOptions(opts)
+	_, err = sut.SetItem("foo", "foo")
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+
+	//values of any type can be stored, unless the data types are restricted
+	type myStruct struct{ Name string }
+	sut = memory.New("", time.Minute, time.Minute*2)
+	_, err = sut.SetItem("foo", myStruct{Name: "foo"})
+	assert.NoError(t, err)
+	sut = memory.New("", time.Minute, time.Minute*2, storage.WithDataTypes(storage.DataTypes{storage.TypeString: true}))
+	_, err = sut.SetItem("foo", 1)
+	assert.ErrorIs(t, err, errors.ErrUnsupportedDataType)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+}
+
+func TestMemoryAdapter_KeyPolicy(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	sut := memory.New("ns:", time.Minute, time.Minute*2,
+		storage.WithMaxKeyLength(70),
+		storage.WithKeyHashing(storage.KeyHashing{}),
+	)
+	vals := map[string]any{
+		"a:ns:b":        1,
+		long:            2,
+		"users:" + long: 3,
+		"users:1":       4,
+	}
+	_, err := sut.SetItems(vals)
+	assert.NoError(t, err)
+
+	//the keys that contain the namespace, and the hashed keys, are returned as they were set
+	got, err := sut.GetItems(slices.Collect(maps.Keys(vals)))
+	assert.NoError(t, err)
+	assert.Equal(t, vals, got)
+	assert.Equal(t, vals, maps.Collect(sut.(storage.Iterable).Iterate(storage.IterateOptions{})))
+	assert.ElementsMatch(t,
+		[]string{"users:" + long, "users:1"},
+		slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{Prefix: "users:"})),
+	)
+	_, found := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get("ns:" + long)
+	assert.False(t, found)
+
+	//the key of the hashed key is kept in the client, so another adapter over the same client returns the keys as
+	//they were set
+	other := memory.New("ns:", time.Minute, time.Minute*2,
+		storage.WithMaxKeyLength(70),
+		storage.WithKeyHashing(storage.KeyHashing{}),
+	)
+	other.(*adapter.AbstractAdapter).Client = sut.(*adapter.AbstractAdapter).Client
+	assert.Equal(t, vals, maps.Collect(other.(storage.Iterable).Iterate(storage.IterateOptions{})))
+
+	assert.NoError(t, sut.(storage.Clearable).ClearByPrefix("users:"))
+	assert.False(t, sut.HasItem("users:"+long))
+	assert.True(t, sut.HasItem(long))
+
+	keyKey := fmt.Sprintf(adapter.KeyKeyTpl, sut.(*adapter.AbstractAdapter).NamespacedKey("users:"+long))
+	_, found = sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get(keyKey)
+	assert.False(t, found)
+	assert.True(t, other.RemoveItem(long))
+	assert.Equal(t, []string{"ns:a:ns:b"}, slices.Collect(maps.Keys(sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Items())))
+}
+
+func TestMemoryAdapter_NegativeCaching(t *testing.T) {
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	sut := memory.New("two:", time.Second*60, time.Second*120, storage.WithNegativeTTL(time.Millisecond*100))
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+
+	_, err := sut.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	//the miss is served by this adapter, without walking the chain
+	_, err = chainedAdapter.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = sut.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.False(t, sut.HasItem("foo"))
+	assert.Empty(t, slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})))
+	//until the negative TTL expires
+	assert.Eventually(t, func() bool {
+		val, err := sut.GetItem("foo")
+		return err == nil && val == "bar"
+	}, time.Second, time.Millisecond*10)
+
+	//setting the item forgets the miss
+	_, err = sut.GetItem("baz")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	_, err = sut.SetItems(map[string]any{"baz": "qux"})
+	assert.NoError(t, err)
+	assert.True(t, sut.HasItem("baz"))
+	val, err := sut.GetItem("baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "qux", val)
+
+	//the miss is remembered when there is no chained adapter
+	sut = memory.New("", time.Second*60, time.Second*120, storage.WithNegativeTTL(time.Minute))
+	_, err = sut.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	_, found := sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get(fmt.Sprintf(adapter.NegativeKeyTpl, "foo"))
+	assert.True(t, found)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, found = sut.(*adapter.AbstractAdapter).Client.(*cache.Cache).Get(fmt.Sprintf(adapter.NegativeKeyTpl, "foo"))
+	assert.False(t, found)
+}
+
+func TestMemoryAdapter_StaleWhileRevalidate(t *testing.T) {
+	calls := atomic.Int32{}
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (any, error) {
+		calls.Add(1)
+		<-release
+		if key == "gone" {
+			return nil, errors.ErrKeyNotFound
+		}
+		return "fresh", nil
+	}
+	sut := memory.New("", time.Second*60, time.Second*120,
+		storage.WithSoftTTL(time.Millisecond*50), storage.WithRefreshLoader(loader))
+	_, err := sut.SetItems(map[string]any{"foo": "bar", "gone": "bar"})
+	assert.NoError(t, err)
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	assert.Equal(t, int32(0), calls.Load())
+
+	//after the soft TTL the stale value is returned and refreshed once
+	time.Sleep(time.Millisecond * 60)
+	for range 3 {
+		val, err = sut.GetItem("foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "bar", val)
+	}
+	close(release)
+	assert.Eventually(t, func() bool {
+		val, err := sut.GetItem("foo")
+		return err == nil && val == "fresh"
+	}, time.Second, time.Millisecond*10)
+	assert.Equal(t, int32(1), calls.Load())
+	//an item that the loader does not find is removed
+	val, err = sut.GetItem("gone")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	assert.Eventually(t, func() bool { return !sut.HasItem("gone") }, time.Second, time.Millisecond*10)
+
+	//after the hard TTL the item is a miss
+	_, err = sut.(storage.Expirable).SetItemWithTTL("short", "lived", time.Millisecond*60)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 70)
+	_, err = sut.GetItem("short")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	//without a loader the item is refreshed from the chained adapter
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	sut = memory.New("two:", time.Second*60, time.Second*120, storage.WithSoftTTL(time.Millisecond*50))
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = chainedAdapter.SetItem("foo", "baz")
+	assert.NoError(t, err)
+	val, err = sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	time.Sleep(time.Millisecond * 60)
+	val, err = sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	assert.Eventually(t, func() bool {
+		val, err := sut.GetItem("foo")
+		return err == nil && val == "baz"
+	}, time.Second, time.Millisecond*10)
+	assert.Equal(t, []string{"foo"}, slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})))
+}
@@ -0,0 +1,15 @@
+package memory
+
+import (
+	"github.com/chippyash/go-cache-manager/storage"
+	"time"
+)
+
+func init() {
+	storage.DefineOption(OptPurgeTtl, "purgeTtl", storage.NotNegative[time.Duration])
+}
+
+// WithPurgeTtl sets OptPurgeTtl
+func WithPurgeTtl(purgeTtl time.Duration) storage.Option {
+	return storage.WithOption(OptPurgeTtl, purgeTtl)
+}
@@ -0,0 +1,6 @@
+package adapter
+
+// NegativeKeyTpl formatting string for the key that marks a namespaced item key as not found.
+// Adapters set it for storage.OptNegativeTTL when a key is not found in the adapter or the adapters chained to it,
+// and remove it when the item is set
+const NegativeKeyTpl = MetaKeyPrefix + "neg:%s"
@@ -0,0 +1,241 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	errs "github.com/pkg/errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+/** ReadThrough Interface **/
+
+// GetOrSet returns the value for key, calling the loader and setting its value in the storage if the key is not found.
+// If the loader value is returned but cannot be set, the set error is returned with it.
+// With storage.OptXFetchBeta, an item that is found may be recomputed before it expires, see expiresEarly. If that
+// fails, the error is logged and the item that was found is returned
+func (a *AbstractAdapter) GetOrSet(key string, loader func() (any, error)) (any, error) {
+	ctx := context.Background()
+	val, err := a.GetItem(key)
+	early := false
+	if err == nil {
+		if len(a.expiresEarly(ctx, []string{key})) == 0 {
+			return val, nil
+		}
+		early = true
+	} else if !errs.Is(err, errors.ErrKeyNotFound) {
+		return nil, err
+	}
+	v, err := a.flights.do(a.NamespacedKey(key), func() (any, error) {
+		start := time.Now()
+		v, err := loader()
+		if err != nil {
+			return nil, err
+		}
+		delta := time.Since(start)
+		if _, err = a.SetItemCtx(ctx, key, v); err != nil {
+			return v, errs.Wrap(err, "failed to set loaded item")
+		}
+		a.storeDeltas(ctx, []string{key}, delta)
+		return v, nil
+	})
+	if early && err != nil && v == nil {
+		a.LogError(ctx, "failed to recompute item early", err, key)
+		return val, nil
+	}
+	return v, err
+}
+
+// GetOrSetItems returns the values for keys, calling the loader for the keys that are not found and setting the
+// values it returns in the storage. Values for keys that were not requested from the loader are ignored. With storage.OptXFetchBeta, the loader is also called for items that are found
+// and should be recomputed before they expire. If only those are loaded and that fails, the error is logged and the
+// items that were found are returned
+func (a *AbstractAdapter) GetOrSetItems(keys []string, loader func(keys []string) (map[string]any, error)) (map[string]any, error) {
+	ctx := context.Background()
+	ret, err := a.GetItems(keys)
+	if err != nil && (errs.Is(err, errors.ErrKeyInvalid) || errs.Is(err, errors.ErrNotReadable)) {
+		return ret, err
+	}
+	if ret == nil {
+		ret = make(map[string]any, len(keys))
+	}
+	missing := make([]string, 0)
+	for _, key := range keys {
+		if _, ok := ret[key]; !ok && !slices.Contains(missing, key) {
+			missing = append(missing, key)
+		}
+	}
+	early := a.expiresEarly(ctx, slices.Collect(maps.Keys(ret)))
+	missing = append(missing, early...)
+	if len(missing) == 0 {
+		return ret, nil
+	}
+	//flights are keyed by namespaced key, so map them back to the requested keys
+	nsKeys := make([]string, len(missing))
+	byNsKey := make(map[string]string, len(missing))
+	for i, key := range missing {
+		nsKeys[i] = a.NamespacedKey(key)
+		byNsKey[nsKeys[i]] = key
+	}
+	loaded, err := a.flights.doMulti(nsKeys, func(nsKeys []string) (map[string]any, error) {
+		toLoad := make([]string, len(nsKeys))
+		for i, nsKey := range nsKeys {
+			toLoad[i] = byNsKey[nsKey]
+		}
+		start := time.Now()
+		loaded, err := loader(toLoad)
+		if err != nil {
+			return nil, err
+		}
+		delta := time.Since(start)
+		vals := make(map[string]any, len(toLoad))
+		for _, key := range toLoad {
+			if v, ok := loaded[key]; ok {
+				vals[key] = v
+			}
+		}
+		var setErr error
+		if len(vals) > 0 {
+			if _, e := a.SetItemsCtx(ctx, vals); e != nil {
+				setErr = errs.Wrap(e, "failed to set loaded items")
+			} else {
+				a.storeDeltas(ctx, slices.Collect(maps.Keys(vals)), delta)
+			}
+		}
+		nsVals := make(map[string]any, len(vals))
+		for k, v := range vals {
+			nsVals[a.NamespacedKey(k)] = v
+		}
+		return nsVals, setErr
+	})
+	for nsKey, v := range loaded {
+		ret[byNsKey[nsKey]] = v
+	}
+	if err != nil && len(early) == len(missing) {
+		a.LogError(ctx, "failed to recompute items early", err, early...)
+		return ret, nil
+	}
+	return ret, err
+}
+
+// flightCall is an in-flight or completed load of a single key
+type flightCall struct {
+	wg    sync.WaitGroup
+	val   any
+	found bool
+	err   error
+}
+
+// flightGroup deduplicates concurrent loads of the same key within the process
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do calls fn for key, unless a call for key is already in flight, in which case it waits for and returns its result.
+// If fn panics, the waiting calls return an error and the panic is passed on
+func (g *flightGroup) do(key string, fn func() (any, error)) (any, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	p := run(func() { c.val, c.err = fn() })
+	if p != nil {
+		c.val, c.err = nil, errs.Errorf("loader panicked: %v", p)
+	}
+	c.found = c.err == nil
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	c.wg.Done()
+
+	if p != nil {
+		panic(p)
+	}
+	return c.val, c.err
+}
+
+// doMulti calls fn once for the keys that are not already in flight and waits for the results of those that are.
+// If fn panics, the waiting calls return an error and the panic is passed on
+func (g *flightGroup) doMulti(keys []string, fn func(keys []string) (map[string]any, error)) (map[string]any, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	owned := make([]string, 0, len(keys))
+	waiting := make(map[string]*flightCall)
+	for _, key := range keys {
+		if c, ok := g.calls[key]; ok {
+			waiting[key] = c
+			continue
+		}
+		c := new(flightCall)
+		c.wg.Add(1)
+		g.calls[key] = c
+		owned = append(owned, key)
+	}
+	g.mu.Unlock()
+
+	ret := make(map[string]any, len(keys))
+	var err error
+	if len(owned) > 0 {
+		var vals map[string]any
+		var e error
+		p := run(func() { vals, e = fn(owned) })
+		if p != nil {
+			vals, e = nil, errs.Errorf("loader panicked: %v", p)
+		}
+		err = e
+		g.mu.Lock()
+		for _, key := range owned {
+			c := g.calls[key]
+			c.val, c.found = vals[key]
+			if !c.found {
+				c.err = e
+			}
+			delete(g.calls, key)
+			c.wg.Done()
+		}
+		g.mu.Unlock()
+		if p != nil {
+			panic(p)
+		}
+		for k, v := range vals {
+			ret[k] = v
+		}
+	}
+	for key, c := range waiting {
+		c.wg.Wait()
+		if c.found {
+			ret[key] = c.val
+			continue
+		}
+		if c.err != nil {
+			err = c.err
+		}
+	}
+	return ret, err
+}
+
+// run calls fn and returns the value it panics with, if any, so that the calls waiting for a load are released
+// before the panic is passed on
+func run(fn func()) (p any) {
+	defer func() {
+		p = recover()
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,47 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	errs "github.com/pkg/errors"
+)
+
+// SoftKeyTpl formatting string for the key that marks a namespaced item key as fresh.
+// Adapters set it for storage.OptSoftTTL when the item is set. An item without it is stale, and is refreshed
+// when it is read, until the item itself expires
+const SoftKeyTpl = MetaKeyPrefix + "soft:%s"
+
+// Refresh refreshes a stale item in the background. The value is loaded with the storage.OptRefreshLoader, or read
+// from the chained adapter if there is no loader, and set. If the loader does not find the key, the item is removed.
+// Only one refresh of a key runs at a time, and nothing is done if there is neither a loader nor a chained adapter
+func (a *AbstractAdapter) Refresh(ctx context.Context, key string) {
+	loader := a.options.RefreshLoader()
+	if loader == nil {
+		chained := a.GetChainedCtx()
+		if chained == nil {
+			return
+		}
+		loader = chained.GetItemCtx
+	}
+	nsKey := a.NamespacedKey(key)
+	if _, busy := a.refreshes.LoadOrStore(nsKey, struct{}{}); busy {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer a.refreshes.Delete(nsKey)
+		v, err := loader(ctx, key)
+		if errs.Is(err, errors.ErrKeyNotFound) {
+			if a.options.RefreshLoader() != nil {
+				a.RemoveItemCtx(ctx, key)
+			}
+			return
+		}
+		if err != nil {
+			a.LogError(ctx, "failed to refresh item", err, key)
+			return
+		}
+		_, err = a.SetItemCtx(ctx, key, v)
+		a.LogError(ctx, "failed to refresh item", err, key)
+	}()
+}
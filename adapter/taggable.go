@@ -0,0 +1,88 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+)
+
+const (
+	//ReservedKeyPrefix the prefix of the backend keys that adapters keep for themselves, e.g. the metadata keys and
+	//the Valkey managed data type keys. A key whose backend key starts with it is invalid
+	ReservedKeyPrefix = "gcm:"
+	//MetaKeyPrefix the prefix for the keys of the metadata that adapters keep alongside items. It is the reserved
+	//prefix twice, so that a metadata key is neither a backend key, nor the reserved prefix followed by a backend key
+	MetaKeyPrefix = ReservedKeyPrefix + ReservedKeyPrefix
+	//TagsKeyTpl formatting string for the key holding the tags of a namespaced item key
+	TagsKeyTpl = MetaKeyPrefix + "tags:%s"
+	//TagKeyTpl formatting string for the key holding the namespaced item keys for a namespaced tag, where supported
+	TagKeyTpl = MetaKeyPrefix + "tag:%s"
+)
+
+/** Taggable Interface **/
+
+func (a *AbstractAdapter) SetTags(key string, tags ...string) (bool, error) {
+	return a.SetTagsCtx(context.TODO(), key, tags...)
+}
+
+func (a *AbstractAdapter) GetTags(key string) ([]string, error) {
+	return a.GetTagsCtx(context.TODO(), key)
+}
+
+func (a *AbstractAdapter) ClearByTags(tags []string, disjunction bool) (bool, error) {
+	return a.ClearByTagsCtx(context.TODO(), tags, disjunction)
+}
+
+func (a *AbstractAdapter) SetTagsCtx(ctx context.Context, key string, tags ...string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if a.setTags == nil {
+		return false, errors.ErrNotImplemented
+	}
+	return trigger(ctx, a, storage.OpSetTags, event.Params{Key: key, Tags: tags}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.setTags(ctx, p.Key, p.Tags)
+	})
+}
+
+func (a *AbstractAdapter) GetTagsCtx(ctx context.Context, key string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	if a.getTags == nil {
+		return []string{}, errors.ErrNotImplemented
+	}
+	return trigger(ctx, a, storage.OpGetTags, event.Params{Key: key}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return a.getTags(ctx, p.Key)
+	})
+}
+
+func (a *AbstractAdapter) ClearByTagsCtx(ctx context.Context, tags []string, disjunction bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if a.clearByTags == nil {
+		return false, errors.ErrNotImplemented
+	}
+	return trigger(ctx, a, storage.OpClearByTags, event.Params{Tags: tags, Disjunction: disjunction}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return a.clearByTags(ctx, p.Tags, p.Disjunction)
+	})
+}
+
+/** Setters for the Taggable interface functions **/
+
+func (a *AbstractAdapter) SetSetTagsFunc(f func(ctx context.Context, key string, tags []string) (bool, error)) *AbstractAdapter {
+	a.setTags = f
+	return a
+}
+
+func (a *AbstractAdapter) SetGetTagsFunc(f func(ctx context.Context, key string) ([]string, error)) *AbstractAdapter {
+	a.getTags = f
+	return a
+}
+
+func (a *AbstractAdapter) SetClearByTagsFunc(f func(ctx context.Context, tags []string, disjunction bool) (bool, error)) *AbstractAdapter {
+	a.clearByTags = f
+	return a
+}
@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/chippyash/go-cache-manager/tracing"
+	"maps"
+	"slices"
+)
+
+/** tracing.Traceable Interface **/
+
+// SetTracer sets the tracer that a span is started with for each operation whose function is set.
+// Use tracing.Trace to set the tracer for a chain of adapters
+func (a *AbstractAdapter) SetTracer(t tracing.Tracer) {
+	a.tracer = t
+}
+
+// traceScopeKey is the context key for the traceScope of the operation being traced
+type traceScopeKey struct{}
+
+// traceScope is the adapter and tier of the operation being traced
+type traceScope struct {
+	adapter *AbstractAdapter
+	tier    int
+}
+
+// startSpan starts a span for the operation. The tier is one more than the tier of the operation that called it if
+// that was on another adapter, i.e. a chained lookup, else the same
+func (a *AbstractAdapter) startSpan(ctx context.Context, op storage.Operation, params event.Params) (context.Context, tracing.Span) {
+	tier := 0
+	if scope, ok := ctx.Value(traceScopeKey{}).(traceScope); ok {
+		tier = scope.tier
+		if scope.adapter != a {
+			tier++
+		}
+	}
+	keys := params.Keys
+	if keys == nil && params.Values != nil {
+		keys = slices.Sorted(maps.Keys(params.Values))
+	}
+	ns := a.options.Namespace()
+	ctx, span := a.tracer.Start(ctx, tracing.Attributes{
+		Operation: op,
+		Key:       params.Key,
+		Keys:      keys,
+		Namespace: ns,
+		Adapter:   a.Name,
+		Tier:      tier,
+	})
+	return context.WithValue(ctx, traceScopeKey{}, traceScope{adapter: a, tier: tier}), span
+}
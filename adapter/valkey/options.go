@@ -0,0 +1,46 @@
+package valkey
+
+import (
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/valkey-io/valkey-go"
+	"time"
+)
+
+func init() {
+	storage.DefineOption[string](OptHost, "host", nil)
+	storage.DefineOption(OptPort, "port", func(port int) string {
+		if port < 1 || port > 65535 {
+			return "must be between 1 and 65535"
+		}
+		return ""
+	})
+	storage.DefineOption[bool](OptClientCaching, "clientCaching", nil)
+	storage.DefineOption(OptClientCachingTtl, "clientCachingTtl", storage.NotNegative[time.Duration])
+	storage.DefineOption(OptValkeyOptions, "valkeyOptions", func(o valkey.ClientOption) string {
+		if len(o.InitAddress) == 0 {
+			return "InitAddress is required"
+		}
+		return ""
+	})
+	storage.DefineOption[bool](OptManageTypes, "manageTypes", nil)
+	storage.DefineOption(OptDatetimeFormat, "datetimeFormat", func(format string) string {
+		if format == "" {
+			return "must not be empty"
+		}
+		return ""
+	})
+}
+
+// WithClientOption changes the valkey client options, e.g. to set the credentials or database
+func WithClientOption(f func(o *valkey.ClientOption)) storage.Option {
+	return func(opts storage.StorageOptions) {
+		o := storage.OptionValue(opts, OptValkeyOptions, valkey.ClientOption{})
+		f(&o)
+		opts[OptValkeyOptions] = o
+	}
+}
+
+// WithDatetimeFormat sets OptDatetimeFormat, the format of time values when data types are managed
+func WithDatetimeFormat(format string) storage.Option {
+	return storage.WithOption(OptDatetimeFormat, format)
+}
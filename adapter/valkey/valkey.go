@@ -10,6 +10,9 @@ import (
 	"github.com/chippyash/go-cache-manager/storage"
 	errs "github.com/pkg/errors"
 	"github.com/valkey-io/valkey-go"
+	"iter"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,7 +20,7 @@ import (
 
 const (
 	//OptHost Redis/Valkey host name. type: string
-	OptHost = iota + storage.OptDataTypes + 1
+	OptHost = iota + storage.OptRangeValkey
 	//OptPort the port number for the server. Will default to 6379 if not supplied. type: int
 	OptPort
 	//OptClientCaching set true to use client side caching else false. type: bool
@@ -31,6 +34,14 @@ const (
 	//OptDatetimeFormat the datetime format to use for the cache datetime values when data types are managed. Defaults to time.RFC3339. type: string
 	OptDatetimeFormat
 )
+const (
+	//scanCount the number of keys requested from each SCAN call
+	scanCount = 100
+)
+
+// errStopIteration stops a scan when the iterator consumer stops
+var errStopIteration = errs.New("stop iteration")
+
 const (
 	//ManagedDataTypeCacheKeyPrefix the prefix for the managed data type cache key.
 	ManagedDataTypeCacheKeyPrefix = "gcm:"
@@ -38,9 +49,14 @@ const (
 	ManagedDataTypeCacheTpl = ManagedDataTypeCacheKeyPrefix + "%s"
 )
 
-func New(namespace string, host string, ttl time.Duration, clientCaching bool, clientCachingTtl time.Duration, manageTypes bool) storage.Storage {
+// New returns a valkey adapter. The client is created when the adapter is opened. The options, e.g.
+// storage.WithCodec() or WithClientOption(), change the defaults
+func New(namespace string, host string, ttl time.Duration, clientCaching bool, clientCachingTtl time.Duration, manageTypes bool, options ...storage.Option) storage.Storage {
 	//set the options
-	dTypes := storage.DefaultDataTypes
+	//values are stored as strings, so values of any type can be stored unless the data types are managed, when the
+	//type must be known to convert the string back
+	dTypes := maps.Clone(storage.DefaultDataTypes)
+	dTypes[storage.TypeUnknown] = !manageTypes
 	opts := storage.StorageOptions{
 		storage.OptNamespace:      namespace,
 		storage.OptKeyPattern:     "",
@@ -50,6 +66,7 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 		storage.OptMaxKeyLength:   0,
 		storage.OptMaxValueLength: 0,
 		storage.OptDataTypes:      dTypes,
+		storage.OptCodec:          nil,
 		OptHost:                   host,
 		OptPort:                   6379,
 		OptClientCaching:          clientCaching,
@@ -60,7 +77,7 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 		},
 		OptManageTypes:    manageTypes,
 		OptDatetimeFormat: time.RFC3339,
-	}
+	}.Apply(options...)
 
 	adapter := new(adapter2.AbstractAdapter)
 	adapter.Name = "valkey"
@@ -69,7 +86,7 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 	anyToString := func(v any) string {
 		switch v.(type) {
 		case time.Time:
-			return v.(time.Time).Format(adapter.GetOptions()[OptDatetimeFormat].(string))
+			return v.(time.Time).Format(adapter.GetOptions().String(OptDatetimeFormat, time.RFC3339))
 		case []byte:
 			var w bytes.Buffer
 			enc := gob.NewEncoder(&w)
@@ -80,61 +97,317 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 		}
 	}
 
-	setType := func(k string, v any) error {
-		if !adapter.GetOptions()[OptManageTypes].(bool) {
+	//encode and decode values with the codec if there is one
+	encode := func(v any) (string, error) {
+		codec := adapter.Codec()
+		if codec == nil {
+			return anyToString(v), nil
+		}
+		b, err := codec.Encode(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	decode := func(v any) (any, error) {
+		codec := adapter.Codec()
+		if codec == nil {
+			return v, nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return v, nil
+		}
+		return codec.Decode([]byte(s))
+	}
+	//firstError returns the first error in the responses, if any
+	firstError := func(resps []valkey.ValkeyResult) error {
+		for _, resp := range resps {
+			if err := resp.Error(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	//stripNamespaces returns the namespaced keys without the namespace
+	stripNamespaces := func(nsKeys []string) []string {
+		keys := make([]string, len(nsKeys))
+		for i, nsKey := range nsKeys {
+			keys[i] = adapter.StripNamespace(nsKey)
+		}
+		return keys
+	}
+	//types are not managed if there is a codec, as the codec is responsible for the value types
+	typesManaged := func() bool {
+		return adapter.GetOptions().Bool(OptManageTypes, false) && adapter.Codec() == nil
+	}
+
+	//setCmd builds the SET command for the value with the resolved ttl. If xx is true the value is only set if the key exists
+	setCmd := func(cl valkey.Client, nsKey, v string, ttl time.Duration, xx bool) valkey.Completed {
+		switch {
+		case ttl == storage.NoExpiry && xx:
+			return cl.B().Set().Key(nsKey).Value(v).Xx().Build()
+		case ttl == storage.NoExpiry:
+			return cl.B().Set().Key(nsKey).Value(v).Build()
+		case xx:
+			return cl.B().Set().Key(nsKey).Value(v).Xx().Px(ttl).Build()
+		default:
+			return cl.B().Set().Key(nsKey).Value(v).Px(ttl).Build()
+		}
+	}
+	//expireCmd builds the command to reset the TTL of the key to the resolved ttl
+	expireCmd := func(cl valkey.Client, nsKey string, ttl time.Duration) valkey.Completed {
+		if ttl == storage.NoExpiry {
+			return cl.B().Persist().Key(nsKey).Build()
+		}
+		return cl.B().Pexpire().Key(nsKey).Milliseconds(ttl.Milliseconds()).Build()
+	}
+
+	//tagIndexExpireCmds builds the commands that extend the TTL of the tag index sets so that they outlive the item
+	tagIndexExpireCmds := func(cl valkey.Client, tags []string, ttl time.Duration) valkey.Commands {
+		cmds := make(valkey.Commands, 0, len(tags)*2)
+		for _, tag := range tags {
+			indexKey := fmt.Sprintf(adapter2.TagKeyTpl, adapter.NamespacedKey(tag))
+			if ttl == storage.NoExpiry {
+				cmds = append(cmds, cl.B().Persist().Key(indexKey).Build())
+				continue
+			}
+			cmds = append(
+				cmds,
+				cl.B().Pexpire().Key(indexKey).Milliseconds(ttl.Milliseconds()).Nx().Build(),
+				cl.B().Pexpire().Key(indexKey).Milliseconds(ttl.Milliseconds()).Gt().Build(),
+			)
+		}
+		return cmds
+	}
+	//syncTags keeps the TTL of the item tags in sync with the resolved ttl of the item
+	syncTags := func(ctx context.Context, nsKey string, ttl time.Duration) {
+		cl := adapter.Client.(valkey.Client)
+		tagsKey := fmt.Sprintf(adapter2.TagsKeyTpl, nsKey)
+		tags, err := cl.Do(ctx, cl.B().Smembers().Key(tagsKey).Build()).AsStrSlice()
+		if err != nil || len(tags) == 0 {
+			return
+		}
+		cmds := append(valkey.Commands{expireCmd(cl, tagsKey, ttl)}, tagIndexExpireCmds(cl, tags, ttl)...)
+		if err = firstError(cl.DoMulti(ctx, cmds...)); err != nil {
+			adapter.LogError(ctx, "failed to set tags ttl", err, adapter.StripNamespace(nsKey))
+		}
+	}
+	//removeTags removes the items tags and removes the items from the tag index sets
+	removeTags := func(ctx context.Context, nsKeys ...string) {
+		cl := adapter.Client.(valkey.Client)
+		cmds := make(valkey.Commands, len(nsKeys))
+		for i, nsKey := range nsKeys {
+			cmds[i] = cl.B().Smembers().Key(fmt.Sprintf(adapter2.TagsKeyTpl, nsKey)).Build()
+		}
+		remCmds := make(valkey.Commands, 0)
+		for i, resp := range cl.DoMulti(ctx, cmds...) {
+			tags, err := resp.AsStrSlice()
+			if err != nil || len(tags) == 0 {
+				continue
+			}
+			for _, tag := range tags {
+				remCmds = append(remCmds, cl.B().Srem().Key(fmt.Sprintf(adapter2.TagKeyTpl, adapter.NamespacedKey(tag))).Member(nsKeys[i]).Build())
+			}
+			remCmds = append(remCmds, cl.B().Del().Key(fmt.Sprintf(adapter2.TagsKeyTpl, nsKeys[i])).Build())
+		}
+		if len(remCmds) > 0 {
+			if err := firstError(cl.DoMulti(ctx, remCmds...)); err != nil {
+				adapter.LogError(ctx, "failed to remove tags", err, stripNamespaces(nsKeys)...)
+			}
+		}
+	}
+
+	//saveKeys keeps the keys of the hashed item keys with the resolved ttl of the items
+	saveKeys := func(ctx context.Context, ttl time.Duration, keys ...string) {
+		cl := adapter.Client.(valkey.Client)
+		cmds := make(valkey.Commands, 0)
+		for _, key := range keys {
+			nsKey := adapter.NamespacedKey(key)
+			if adapter.KeyPolicy().Hashed(nsKey) {
+				cmds = append(cmds, setCmd(cl, fmt.Sprintf(adapter2.KeyKeyTpl, nsKey), key, ttl, false))
+			}
+		}
+		if len(cmds) == 0 {
+			return
+		}
+		if err := firstError(cl.DoMulti(ctx, cmds...)); err != nil {
+			adapter.LogError(ctx, "failed to set hashed keys", err, keys...)
+		}
+	}
+	//syncKey keeps the key of a hashed item key with the TTL of the item, after a command that keeps the TTL
+	syncKey := func(ctx context.Context, key string) {
+		nsKey := adapter.NamespacedKey(key)
+		if !adapter.KeyPolicy().Hashed(nsKey) {
+			return
+		}
+		cl := adapter.Client.(valkey.Client)
+		//PTTL returns -2 for a key that does not exist and -1 for a key without a TTL
+		ms, err := cl.Do(ctx, cl.B().Pttl().Key(nsKey).Build()).AsInt64()
+		if err != nil || ms == -2 {
+			return
+		}
+		ttl := storage.NoExpiry
+		if ms >= 0 {
+			ttl = time.Duration(ms) * time.Millisecond
+		}
+		saveKeys(ctx, ttl, key)
+	}
+	//keysOf returns the keys for the namespaced keys, looking up the keys of hashed keys
+	keysOf := func(ctx context.Context, nsKeys []string) []string {
+		cl := adapter.Client.(valkey.Client)
+		keys := make([]string, len(nsKeys))
+		hashed := make([]int, 0)
+		cmds := make(valkey.Commands, 0)
+		for i, nsKey := range nsKeys {
+			keys[i] = adapter.StripNamespace(nsKey)
+			if adapter.KeyPolicy().Hashed(nsKey) {
+				hashed = append(hashed, i)
+				cmds = append(cmds, cl.B().Get().Key(fmt.Sprintf(adapter2.KeyKeyTpl, nsKey)).Build())
+			}
+		}
+		if len(cmds) == 0 {
+			return keys
+		}
+		for i, resp := range cl.DoMulti(ctx, cmds...) {
+			if key, err := resp.ToString(); err == nil {
+				keys[hashed[i]] = key
+			}
+		}
+		return keys
+	}
+
+	//scanKeys calls fn with each batch of keys matching the glob pattern, scanning every node with a cursor.
+	//count is the number of keys requested from each SCAN call
+	scanKeys := func(ctx context.Context, match string, count int64, fn func(keys []string) error) error {
+		for _, node := range adapter.Client.(valkey.Client).Nodes() {
+			var cursor uint64
+			for {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				entry, err := node.Do(
+					ctx,
+					node.B().Scan().Cursor(cursor).Match(match).Count(count).Build(),
+				).AsScanEntry()
+				if err != nil {
+					return errs.Wrap(err, "failed to scan keys")
+				}
+				if len(entry.Elements) > 0 {
+					if err = fn(entry.Elements); err != nil {
+						return err
+					}
+				}
+				cursor = entry.Cursor
+				if cursor == 0 {
+					break
+				}
+			}
+		}
+		return nil
+	}
+	//unlinkKeys unlinks the keys in a single round trip. Each key is unlinked separately as they may be in different cluster slots
+	unlinkKeys := func(ctx context.Context, keys []string) error {
+		cl := adapter.Client.(valkey.Client)
+		cmds := make(valkey.Commands, len(keys))
+		for i, k := range keys {
+			cmds[i] = cl.B().Unlink().Key(k).Build()
+		}
+		for _, resp := range cl.DoMulti(ctx, cmds...) {
+			if resp.Error() != nil {
+				return errs.Wrap(resp.Error(), "failed to unlink keys")
+			}
+		}
+		return nil
+	}
+
+	//itemKeys returns the keys that are not reserved keys, i.e. metadata and managed data type keys
+	itemKeys := func(keys []string) []string {
+		ret := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if !strings.HasPrefix(k, adapter2.ReservedKeyPrefix) {
+				ret = append(ret, k)
+			}
+		}
+		return ret
+	}
+	//clearMatching removes the items, and their metadata, whose backend keys match the glob pattern and whose keys,
+	//decoded and without the namespace, match
+	clearMatching := func(ctx context.Context, glob string, match func(key string) bool) error {
+		if !adapter.GetOptions().Writable() {
+			return errors.ErrNotWritable
+		}
+		return scanKeys(ctx, glob, scanCount, func(keys []string) error {
+			nsKeys := make([]string, 0, len(keys))
+			scanned := itemKeys(keys)
+			for i, key := range keysOf(ctx, scanned) {
+				if match(key) {
+					nsKeys = append(nsKeys, scanned[i])
+				}
+			}
+			if len(nsKeys) == 0 {
+				return nil
+			}
+			removeTags(ctx, nsKeys...)
+			unlink := slices.Clone(nsKeys)
+			for _, nsKey := range nsKeys {
+				unlink = append(unlink, fmt.Sprintf(ManagedDataTypeCacheTpl, nsKey), fmt.Sprintf(adapter2.KeyKeyTpl, nsKey))
+			}
+			return unlinkKeys(ctx, unlink)
+		})
+	}
+
+	setType := func(ctx context.Context, k string, v any, ttl time.Duration) error {
+		if !typesManaged() {
 			return nil
 		}
 		t := storage.GetType(v)
-		if !adapter.GetOptions()[storage.OptDataTypes].(storage.DataTypes)[t] {
-			return errs.Wrap(errors.ErrUnsupportedDataType, fmt.Sprintf("key: %s type: %d, value: %v", k, t, v))
-		}
 		cl := adapter.Client.(valkey.Client)
 		key := fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))
-		_ = cl.Do(
-			context.TODO(),
-			cl.B().Set().Key(key).Value(anyToString(t)).Nx().Ex(adapter.GetOptions()[storage.OptTTL].(time.Duration)).Build(),
-		)
+		err := cl.Do(
+			ctx,
+			setCmd(cl, key, anyToString(t), ttl, false),
+		).Error()
+		if err != nil {
+			return errs.Wrap(err, "failed to set managed data type")
+		}
 		return nil
 	}
-	touchType := func(k string) error {
-		if !adapter.GetOptions()[OptManageTypes].(bool) {
+	touchType := func(ctx context.Context, k string, ttl time.Duration) error {
+		if !typesManaged() {
 			return nil
 		}
 		cl := adapter.Client.(valkey.Client)
 		key := fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))
 		return cl.Do(
-			context.TODO(),
-			cl.B().Expire().Key(key).Seconds(int64(adapter.GetOptions()[storage.OptTTL].(time.Duration).Seconds())).Build(),
+			ctx,
+			expireCmd(cl, key, ttl),
 		).Error()
 	}
-	setTypeMulti := func(vals map[string]any) error {
-		if !adapter.GetOptions()[OptManageTypes].(bool) {
+	setTypeMulti := func(ctx context.Context, vals map[string]any, ttl time.Duration) error {
+		if !typesManaged() {
 			return nil
 		}
 		cl := adapter.Client.(valkey.Client)
 		cmds := make(valkey.Commands, 0, len(vals))
 		for k, v := range vals {
 			t := storage.GetType(v)
-			if !adapter.GetOptions()[storage.OptDataTypes].(storage.DataTypes)[t] {
-				return errs.Wrap(errors.ErrUnsupportedDataType, fmt.Sprintf("key: %s type: %d, value: %v", k, t, v))
-			}
 			key := fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))
-			cmds = append(cmds, cl.B().Set().Key(key).Value(anyToString(t)).Nx().Ex(adapter.GetOptions()[storage.OptTTL].(time.Duration)).Build())
+			cmds = append(cmds, setCmd(cl, key, anyToString(t), ttl, false))
+		}
+		if err := firstError(cl.DoMulti(ctx, cmds...)); err != nil {
+			return errs.Wrap(err, "failed to set managed data types")
 		}
-		_ = cl.DoMulti(
-			context.TODO(),
-			cmds...,
-		)
 		return nil
 	}
-	getTyped := func(k, v string) (any, error) {
-		if !adapter.GetOptions()[OptManageTypes].(bool) {
-			return v, nil
+	getTyped := func(ctx context.Context, k, v string) (any, error) {
+		if !typesManaged() {
+			return decode(v)
 		}
 		cl := adapter.Client.(valkey.Client)
 		key := fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))
 		resp := cl.Do(
-			context.TODO(),
+			ctx,
 			cl.B().Get().Key(key).Build(),
 		)
 		if resp.Error() != nil {
@@ -148,24 +421,25 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 		if err != nil {
 			return nil, errs.Wrap(err, "failed to get type")
 		}
-		return storage.GetTypedValue(t, v, adapter.GetOptions()[OptDatetimeFormat].(string))
+		return storage.GetTypedValue(t, v, adapter.GetOptions().String(OptDatetimeFormat, time.RFC3339))
 	}
-	getTypedMulti := func(vals map[string]any) (map[string]any, error) {
-		if !adapter.GetOptions()[OptManageTypes].(bool) {
+	getTypedMulti := func(ctx context.Context, vals map[string]any) (map[string]any, error) {
+		if !typesManaged() {
 			return vals, nil
 		}
 		ret := make(map[string]any, len(vals))
 		cl := adapter.Client.(valkey.Client)
-		cmds := make(valkey.Commands, 0, len(vals))
-		for k := range vals {
+		keys := slices.Collect(maps.Keys(vals))
+		cmds := make(valkey.Commands, 0, len(keys))
+		for _, k := range keys {
 			cmds = append(cmds, cl.B().Get().Key(fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))).Build().Pin())
 		}
 		resp := cl.DoMulti(
-			context.TODO(),
+			ctx,
 			cmds...,
 		)
 		for i, r := range resp {
-			cmdKey := adapter.StripNamespace(strings.Replace(cmds[i].Commands()[1], ManagedDataTypeCacheKeyPrefix, "", 1))
+			cmdKey := keys[i]
 			tt, err := r.ToString()
 			if err != nil {
 				return nil, errs.Wrap(err, "failed to get value")
@@ -174,7 +448,7 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 			if err != nil {
 				return nil, errs.Wrap(err, "failed to get type")
 			}
-			vv, err := storage.GetTypedValue(t, anyToString(vals[cmdKey]), adapter.GetOptions()[OptDatetimeFormat].(string))
+			vv, err := storage.GetTypedValue(t, anyToString(vals[cmdKey]), adapter.GetOptions().String(OptDatetimeFormat, time.RFC3339))
 			if err != nil {
 				return nil, errs.Wrap(err, "failed to get typed value")
 			}
@@ -182,114 +456,373 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 		}
 		return ret, nil
 	}
-	delType := func(k string) error {
-		if !adapter.GetOptions()[OptManageTypes].(bool) {
+	delType := func(ctx context.Context, k string) error {
+		if !typesManaged() {
 			return nil
 		}
 		cl := adapter.Client.(valkey.Client)
 		key := fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))
 		return cl.Do(
-			context.TODO(),
+			ctx,
 			cl.B().Del().Key(key).Build(),
 		).Error()
 	}
 
-	delTypeMulti := func(keys []string) error {
-		if !adapter.GetOptions()[OptManageTypes].(bool) {
+	delTypeMulti := func(ctx context.Context, keys []string) error {
+		if !typesManaged() {
 			return nil
 		}
 		cl := adapter.Client.(valkey.Client)
-		nsKeys := make([]string, len(keys))
-		for i, k := range keys {
-			nsKeys[i] = fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))
+		//one command per key, as the keys may be in different cluster slots
+		cmds := make(valkey.Commands, 0, len(keys))
+		for _, k := range keys {
+			cmds = append(cmds, cl.B().Del().Key(fmt.Sprintf(ManagedDataTypeCacheTpl, adapter.NamespacedKey(k))).Build())
 		}
-		return cl.Do(
-			context.TODO(),
-			cl.B().Del().Key(nsKeys...).Build(),
+		for _, resp := range cl.DoMulti(ctx, cmds...) {
+			if err := resp.Error(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	//cacheMiss remembers that the item is not found, for OptNegativeTTL
+	cacheMiss := func(ctx context.Context, key string) {
+		ttl := adapter.GetOptions().NegativeTTL()
+		if ttl <= 0 {
+			return
+		}
+		cl := adapter.Client.(valkey.Client)
+		negKey := fmt.Sprintf(adapter2.NegativeKeyTpl, adapter.NamespacedKey(key))
+		adapter.LogError(ctx, "failed to cache miss", cl.Do(ctx, setCmd(cl, negKey, "1", ttl, false)).Error(), key)
+	}
+	//cachedMiss returns true if the item is remembered as not found
+	cachedMiss := func(ctx context.Context, key string) bool {
+		if adapter.GetOptions().NegativeTTL() <= 0 {
+			return false
+		}
+		cl := adapter.Client.(valkey.Client)
+		negKey := fmt.Sprintf(adapter2.NegativeKeyTpl, adapter.NamespacedKey(key))
+		n, err := cl.Do(ctx, cl.B().Exists().Key(negKey).Build()).AsInt64()
+		return err == nil && n == 1
+	}
+	//clearMiss forgets that the items are not found
+	clearMiss := func(ctx context.Context, keys ...string) {
+		if adapter.GetOptions().NegativeTTL() <= 0 {
+			return
+		}
+		cl := adapter.Client.(valkey.Client)
+		//one command per key, as the keys may be in different cluster slots
+		cmds := make(valkey.Commands, 0, len(keys))
+		for _, key := range keys {
+			cmds = append(cmds, cl.B().Del().Key(fmt.Sprintf(adapter2.NegativeKeyTpl, adapter.NamespacedKey(key))).Build())
+		}
+		for _, resp := range cl.DoMulti(ctx, cmds...) {
+			adapter.LogError(ctx, "failed to clear cached miss", resp.Error(), keys...)
+		}
+	}
+
+	//markFresh marks the items as fresh until OptSoftTTL
+	markFresh := func(ctx context.Context, keys ...string) {
+		ttl := adapter.GetOptions().SoftTTL()
+		if ttl <= 0 {
+			return
+		}
+		cl := adapter.Client.(valkey.Client)
+		cmds := make(valkey.Commands, 0, len(keys))
+		for _, key := range keys {
+			cmds = append(cmds, setCmd(cl, fmt.Sprintf(adapter2.SoftKeyTpl, adapter.NamespacedKey(key)), "1", ttl, false))
+		}
+		for _, resp := range cl.DoMulti(ctx, cmds...) {
+			adapter.LogError(ctx, "failed to mark item fresh", resp.Error(), keys...)
+		}
+	}
+	//refreshStale refreshes the items that are no longer fresh
+	refreshStale := func(ctx context.Context, keys ...string) {
+		if adapter.GetOptions().SoftTTL() <= 0 || len(keys) == 0 {
+			return
+		}
+		cl := adapter.Client.(valkey.Client)
+		cmds := make(valkey.Commands, 0, len(keys))
+		for _, key := range keys {
+			cmds = append(cmds, cl.B().Exists().Key(fmt.Sprintf(adapter2.SoftKeyTpl, adapter.NamespacedKey(key))).Build())
+		}
+		for i, resp := range cl.DoMulti(ctx, cmds...) {
+			if n, err := resp.AsInt64(); err == nil && n == 0 {
+				adapter.Refresh(ctx, keys[i])
+			}
+		}
+	}
+
+	//encodeItem checks the key length and data type, and returns the encoded value if it is not too large
+	encodeItem := func(key, nsKey string, value any) (string, error) {
+		if err := adapter.CheckKeyLength(key, nsKey); err != nil {
+			return "", err
+		}
+		if err := adapter.CheckDataType(key, value); err != nil {
+			return "", err
+		}
+		vv, err := encode(value)
+		if err != nil {
+			return "", err
+		}
+		if err := adapter.CheckValueSize(key, len(vv)); err != nil {
+			return "", err
+		}
+		return vv, nil
+	}
+	//setItem sets the item with the ttl, passing the ttl on to the chained adapter
+	setItem := func(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+		if !adapter.GetOptions().Writable() {
+			return false, errors.ErrNotWritable
+		}
+		nsKey := adapter.NamespacedKey(key)
+		if !adapter.ValidateItemKey(key) {
+			return false, errors.ErrKeyInvalid
+		}
+		vv, err := encodeItem(key, nsKey, value)
+		if err != nil {
+			return false, err
+		}
+		cl := adapter.Client.(valkey.Client)
+		err2 := cl.Do(
+			ctx,
+			setCmd(cl, nsKey, vv, adapter.ResolveTTL(ttl), false),
 		).Error()
+		if err2 != nil {
+			return false, errs.Wrap(err2, "failed to set item")
+		}
+		err3 := setType(ctx, key, value, adapter.ResolveTTL(ttl))
+		syncTags(ctx, nsKey, adapter.ResolveTTL(ttl))
+		saveKeys(ctx, adapter.ResolveTTL(ttl), key)
+		clearMiss(ctx, key)
+		markFresh(ctx, key)
+		if adapter.GetChained() != nil {
+			if _, err = storage.SetItemWithTTL(ctx, adapter.GetChained(), key, value, ttl); err != nil {
+				adapter.LogError(ctx, "failed to set item in chained adapter", err, key)
+			}
+		}
+		return true, err3
+	}
+	//setItems sets multiple items with the ttl, passing the ttl on to the chained adapter
+	setItems := func(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error) {
+		if !adapter.GetOptions().Writable() {
+			return []string{}, errors.ErrNotWritable
+		}
+		keys := make([]string, len(values))
+		cmds := make(valkey.Commands, 0, len(keys))
+		//the keys of the commands, in order
+		cmdKeys := make([]string, 0, len(keys))
+		cl := adapter.Client.(valkey.Client)
+		var err error
+		for key, value := range values {
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return keys, errors.ErrKeyInvalid
+			}
+			vv, err := encodeItem(key, nsKey, value)
+			if err != nil {
+				return keys, err
+			}
+			cmds = append(
+				cmds,
+				setCmd(cl, nsKey, vv, adapter.ResolveTTL(ttl), false).Pin(),
+			)
+			cmdKeys = append(cmdKeys, key)
+		}
+
+		for i, resp := range cl.DoMulti(ctx, cmds...) {
+			cmdKey := cmdKeys[i]
+			if resp.Error() != nil {
+				err = errs.Wrap(resp.Error(), "failed to set item")
+				continue
+			}
+			keys[i] = cmdKey
+		}
+		if err != nil {
+			return keys, err
+		}
+		err3 := setTypeMulti(ctx, values, adapter.ResolveTTL(ttl))
+		for key := range values {
+			syncTags(ctx, adapter.NamespacedKey(key), adapter.ResolveTTL(ttl))
+		}
+		saveKeys(ctx, adapter.ResolveTTL(ttl), cmdKeys...)
+		clearMiss(ctx, slices.Collect(maps.Keys(values))...)
+		markFresh(ctx, slices.Collect(maps.Keys(values))...)
+		if adapter.GetChained() != nil {
+			if _, err = storage.SetItemsWithTTL(ctx, adapter.GetChained(), values, ttl); err != nil {
+				adapter.LogError(ctx, "failed to set items in chained adapter", err, slices.Sorted(maps.Keys(values))...)
+			}
+		}
+		return keys, err3
+	}
+	//touchItem resets the TTL of the item to ttl, passing the ttl on to the chained adapter
+	touchItem := func(ctx context.Context, key string, ttl time.Duration) bool {
+		if !adapter.GetOptions().Readable() {
+			return false
+		}
+		if !adapter.GetOptions().Writable() {
+			return false
+		}
+		nsKey := adapter.NamespacedKey(key)
+		if !adapter.ValidateItemKey(key) {
+			return false
+		}
+		cl := adapter.Client.(valkey.Client)
+		resp, err := cl.Do(
+			ctx,
+			expireCmd(cl, nsKey, adapter.ResolveTTL(ttl)),
+		).AsInt64()
+		adapter.LogError(ctx, "failed to touch item", err, key)
+		storage.ReportFailure(ctx, adapter, err)
+		hit := resp == int64(1)
+		if !hit && adapter.ResolveTTL(ttl) == storage.NoExpiry {
+			//PERSIST returns 0 for a key that exists but has no TTL
+			exists, _ := cl.Do(ctx, cl.B().Exists().Key(nsKey).Build()).AsInt64()
+			hit = exists == int64(1)
+		}
+		if hit {
+			adapter.LogError(ctx, "failed to touch managed data type", touchType(ctx, key, adapter.ResolveTTL(ttl)), key)
+			syncTags(ctx, nsKey, adapter.ResolveTTL(ttl))
+		}
+		if adapter.GetChained() != nil {
+			_ = storage.TouchItemWithTTL(ctx, adapter.GetChained(), key, ttl)
+		}
+		return hit
 	}
 
 	//set the functions
 	adapter.
-		SetGetItemFunc(func(key string) (any, error) {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
+		SetGetItemCtxFunc(func(ctx context.Context, key string) (any, error) {
+			if !adapter.GetOptions().Readable() {
 				return nil, errors.ErrNotReadable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return nil, errors.ErrKeyInvalid
 			}
 			cl := adapter.Client.(valkey.Client)
 			var val any
 			var found bool
-			switch adapter.GetOptions()[OptClientCaching].(bool) {
+			var e error
+			switch adapter.GetOptions().Bool(OptClientCaching, false) {
 			case true:
 				resp := cl.DoCache(
-					context.TODO(),
+					ctx,
 					cl.B().Get().Key(nsKey).Cache(),
-					adapter.GetOptions()[OptClientCachingTtl].(time.Duration),
+					adapter.GetOptions().Duration(OptClientCachingTtl, 0),
 				)
-				found = resp.Error() == nil
+				e = resp.Error()
+				found = e == nil
 				val, _ = resp.ToAny()
 			case false:
 				resp := cl.Do(
-					context.TODO(),
+					ctx,
 					cl.B().Get().Key(nsKey).Build(),
 				)
-				e := resp.Error()
+				e = resp.Error()
 				found = e == nil
 				val, _ = resp.ToAny()
 			}
+			if e != nil && !valkey.IsValkeyNil(e) {
+				//treated as a miss
+				adapter.LogError(ctx, "failed to get item", e, key)
+				storage.ReportFailure(ctx, adapter, e)
+			}
+			//a failure is not remembered as a miss
+			miss := valkey.IsValkeyNil(e)
 			if !found {
+				if miss && cachedMiss(ctx, key) {
+					return nil, errors.ErrKeyNotFound
+				}
 				if adapter.GetChained() != nil {
-					v, err2 := adapter.GetChained().GetItem(key)
+					v, err2 := adapter.GetChainedCtx().GetItemCtx(ctx, key)
 					if err2 != nil {
+						if ctx.Err() != nil {
+							return nil, ctx.Err()
+						}
+						if !errs.Is(err2, errors.ErrKeyNotFound) {
+							adapter.LogError(ctx, "failed to get item from chained adapter", err2, key)
+						} else if miss {
+							cacheMiss(ctx, key)
+						}
 						return nil, errors.ErrKeyNotFound
 					}
-					_, _ = adapter.SetItem(key, v)
-					return getTyped(key, anyToString(v))
+					if _, err := adapter.SetItemCtx(ctx, key, v); err != nil {
+						adapter.LogError(ctx, "failed to set item found in chained adapter", err, key)
+					}
+					if adapter.Codec() != nil {
+						return v, nil
+					}
+					return getTyped(ctx, key, anyToString(v))
+				}
+				if miss {
+					cacheMiss(ctx, key)
 				}
 				return nil, errors.ErrKeyNotFound
 			}
-			return getTyped(key, val.(string))
+			refreshStale(ctx, key)
+			return getTyped(ctx, key, val.(string))
 		}).
-		SetGetItemsFunc(func(keys []string) (map[string]any, error) {
+		SetGetItemsCtxFunc(func(ctx context.Context, keys []string) (map[string]any, error) {
 			ret := make(map[string]any)
 			cl := adapter.Client.(valkey.Client)
 			var err2 error
-			switch adapter.GetOptions()[OptClientCaching].(bool) {
+			//the keys found in this adapter, rather than the chained adapter
+			found := make([]string, 0, len(keys))
+			switch adapter.GetOptions().Bool(OptClientCaching, false) {
 			case true:
 				cmds := make([]valkey.CacheableTTL, 0, len(keys))
 				for _, key := range keys {
 					nsKey := adapter.NamespacedKey(key)
-					if !adapter.ValidateKey(nsKey) {
+					if !adapter.ValidateItemKey(key) {
 						return ret, errs.Wrap(errors.ErrKeyInvalid, "failed to get item")
 					}
 					cmds = append(
 						cmds,
 						valkey.CT(
 							cl.B().Get().Key(nsKey).Cache().Pin(),
-							adapter.GetOptions()[OptClientCachingTtl].(time.Duration),
+							adapter.GetOptions().Duration(OptClientCachingTtl, 0),
 						),
 					)
 				}
-				for i, resp := range cl.DoMultiCache(context.TODO(), cmds...) {
-					cmdKey := adapter.StripNamespace(cmds[i].Cmd.Commands()[1])
+				for i, resp := range cl.DoMultiCache(ctx, cmds...) {
+					cmdKey := keys[i]
 					if resp.Error() != nil {
+						miss := valkey.IsValkeyNil(resp.Error())
+						if !miss {
+							//treated as a miss
+							adapter.LogError(ctx, "failed to get item", resp.Error(), cmdKey)
+							storage.ReportFailure(ctx, adapter, resp.Error())
+						}
+						if miss && cachedMiss(ctx, cmdKey) {
+							err2 = errs.Wrap(errors.ErrKeyNotFound, "failed to get item")
+							continue
+						}
 						if adapter.GetChained() != nil {
-							v, err3 := adapter.GetChained().GetItem(cmdKey)
+							v, err3 := adapter.GetChainedCtx().GetItemCtx(ctx, cmdKey)
 							if err3 != nil {
+								if miss && errs.Is(err3, errors.ErrKeyNotFound) {
+									cacheMiss(ctx, cmdKey)
+								}
 								return ret, errs.Wrap(err3, "failed to get item")
 							}
-							adapter.SetItem(cmdKey, v)
+							adapter.SetItemCtx(ctx, cmdKey, v)
 							ret[cmdKey] = v
 							continue
 						}
+						if miss {
+							cacheMiss(ctx, cmdKey)
+						}
 						err2 = errs.Wrap(resp.Error(), "failed to get item")
 						continue
 					}
 					v, err3 := resp.ToAny()
+					if err3 == nil {
+						v, err3 = decode(v)
+					}
 					ret[cmdKey] = v
+					found = append(found, cmdKey)
 					if err3 != nil {
 						err2 = errs.Wrap(err3, "failed to get item")
 					}
@@ -299,29 +832,49 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 				cmds := make(valkey.Commands, 0, len(keys))
 				for _, key := range keys {
 					nsKey := adapter.NamespacedKey(key)
-					if !adapter.ValidateKey(nsKey) {
+					if !adapter.ValidateItemKey(key) {
 						return ret, errs.Wrap(errors.ErrKeyInvalid, "failed to get item")
 					}
 					cmds = append(cmds, cl.B().Get().Key(nsKey).Build().Pin())
 				}
-				for i, resp := range cl.DoMulti(context.TODO(), cmds...) {
-					cmdKey := adapter.StripNamespace(cmds[i].Commands()[1])
+				for i, resp := range cl.DoMulti(ctx, cmds...) {
+					cmdKey := keys[i]
 					if resp.Error() != nil {
+						miss := valkey.IsValkeyNil(resp.Error())
+						if !miss {
+							//treated as a miss
+							adapter.LogError(ctx, "failed to get item", resp.Error(), cmdKey)
+							storage.ReportFailure(ctx, adapter, resp.Error())
+						}
+						if miss && cachedMiss(ctx, cmdKey) {
+							err2 = errs.Wrap(errors.ErrKeyNotFound, "failed to get item")
+							continue
+						}
 						//we only hit the chained cache one key at a time as response from this cache may have partial hits
 						if adapter.GetChained() != nil {
-							v, err3 := adapter.GetChained().GetItem(cmdKey)
+							v, err3 := adapter.GetChainedCtx().GetItemCtx(ctx, cmdKey)
 							if err3 != nil {
+								if miss && errs.Is(err3, errors.ErrKeyNotFound) {
+									cacheMiss(ctx, cmdKey)
+								}
 								return ret, errs.Wrap(err3, "failed to get item")
 							}
-							adapter.SetItem(cmdKey, v)
+							adapter.SetItemCtx(ctx, cmdKey, v)
 							ret[cmdKey] = v
 							continue
 						}
+						if miss {
+							cacheMiss(ctx, cmdKey)
+						}
 						err2 = errs.Wrap(resp.Error(), "failed to get item")
 						continue
 					}
 					v, err3 := resp.ToAny()
+					if err3 == nil {
+						v, err3 = decode(v)
+					}
 					ret[cmdKey] = v
+					found = append(found, cmdKey)
 					if err3 != nil {
 						err2 = errs.Wrap(err3, "failed to get item")
 					}
@@ -330,124 +883,94 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 			if err2 != nil {
 				return ret, err2
 			}
-			return getTypedMulti(ret)
+			refreshStale(ctx, found...)
+			return getTypedMulti(ctx, ret)
 		}).
-		SetSetItemFunc(func(key string, value any) (bool, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
-				return false, errors.ErrNotWritable
-			}
-			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
-				return false, errors.ErrKeyInvalid
-			}
-			cl := adapter.Client.(valkey.Client)
-			err2 := cl.Do(
-				context.TODO(),
-				cl.B().Set().Key(nsKey).Value(anyToString(value)).Ex(adapter.GetOptions()[storage.OptTTL].(time.Duration)).Build(),
-			).Error()
-			if err2 != nil {
-				return false, errs.Wrap(err2, "failed to set item")
-			}
-			err3 := setType(key, value)
-			if adapter.GetChained() != nil {
-				_, _ = adapter.GetChained().SetItem(key, value)
-			}
-			return true, err3
+		SetSetItemCtxFunc(func(ctx context.Context, key string, value any) (bool, error) {
+			return setItem(ctx, key, value, storage.DefaultTTL)
 		}).
-		SetSetItemsFunc(func(values map[string]any) ([]string, error) {
-			keys := make([]string, len(values))
-			cmds := make(valkey.Commands, 0, len(keys))
-			cl := adapter.Client.(valkey.Client)
-			var err error
-			for key, value := range values {
-				nsKey := adapter.NamespacedKey(key)
-				if !adapter.ValidateKey(nsKey) {
-					return keys, errors.ErrKeyInvalid
-				}
-				vv := anyToString(value)
-				cmds = append(
-					cmds,
-					cl.B().Set().Key(nsKey).Value(vv).Ex(adapter.GetOptions()[storage.OptTTL].(time.Duration)).Build().Pin(),
-				)
-			}
-
-			for i, resp := range cl.DoMulti(context.TODO(), cmds...) {
-				cmdKey := adapter.StripNamespace(cmds[i].Commands()[1])
-				if resp.Error() != nil {
-					err = errs.Wrap(resp.Error(), "failed to set item")
-					continue
-				}
-				keys[i] = cmdKey
-			}
-			if err != nil {
-				return keys, err
-			}
-			err3 := setTypeMulti(values)
-			if adapter.GetChained() != nil {
-				_, _ = adapter.GetChained().SetItems(values)
-			}
-			return keys, err3
+		SetSetItemsCtxFunc(func(ctx context.Context, values map[string]any) ([]string, error) {
+			return setItems(ctx, values, storage.DefaultTTL)
 		}).
-		SetHasItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
+		SetHasItemCtxFunc(func(ctx context.Context, key string) bool {
+			if !adapter.GetOptions().Readable() {
 				return false
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false
 			}
 			cl := adapter.Client.(valkey.Client)
-			resp := cl.Do(context.TODO(), cl.B().Exists().Key(nsKey).Build())
+			resp := cl.Do(ctx, cl.B().Exists().Key(nsKey).Build())
 			if resp.Error() != nil {
+				adapter.LogError(ctx, "failed to check item", resp.Error(), key)
+				storage.ReportFailure(ctx, adapter, resp.Error())
 				return false
 			}
 			v, err := resp.AsInt64()
 			if err != nil {
 				return false
 			}
-			if v == 0 && adapter.GetChained() != nil {
-				return adapter.GetChained().HasItem(key)
+			if v == 0 && adapter.GetChained() != nil && !cachedMiss(ctx, key) {
+				return adapter.GetChainedCtx().HasItemCtx(ctx, key)
 			}
 			return v == 1
 		}).
-		SetHasItemsFunc(func(keys []string) map[string]bool {
+		SetHasItemsCtxFunc(func(ctx context.Context, keys []string) map[string]bool {
 			ret := make(map[string]bool)
 			for _, key := range keys {
-				ret[key] = adapter.HasItem(key)
+				ret[key] = adapter.HasItemCtx(ctx, key)
 			}
 			return ret
 		}).
-		SetCheckAndSetItemFunc(func(key string, value any) (bool, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetCheckAndSetItemCtxFunc(func(ctx context.Context, key string, value any) (bool, error) {
+			if !adapter.GetOptions().Writable() {
 				return false, errors.ErrNotWritable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false, errors.ErrKeyInvalid
 			}
+			vv, err := encodeItem(key, nsKey, value)
+			if err != nil {
+				return false, err
+			}
 			cl := adapter.Client.(valkey.Client)
 			resp := cl.Do(
-				context.TODO(),
-				cl.B().Set().Key(nsKey).Value(anyToString(value)).Xx().Ex(adapter.GetOptions()[storage.OptTTL].(time.Duration)).Build(),
+				ctx,
+				setCmd(cl, nsKey, vv, adapter.ResolveTTL(storage.DefaultTTL), true),
 			)
+			hit := false
 			if resp.Error() != nil {
-				return false, errs.Wrap(resp.Error(), errors.ErrKeyNotFound.Error())
-			}
-			ret, err := resp.ToString()
-			hit := ret == "OK"
-			if adapter.GetChained() != nil {
-				return adapter.GetChained().CheckAndSetItem(key, value)
+				err = errs.Wrap(resp.Error(), errors.ErrKeyNotFound.Error())
+			} else {
+				var ret string
+				ret, err = resp.ToString()
+				hit = ret == "OK"
 			}
 			if hit {
-				return hit, touchType(key)
+				syncTags(ctx, nsKey, adapter.ResolveTTL(storage.DefaultTTL))
+				saveKeys(ctx, adapter.ResolveTTL(storage.DefaultTTL), key)
+				markFresh(ctx, key)
+				err = touchType(ctx, key, adapter.ResolveTTL(storage.DefaultTTL))
+			}
+			if adapter.GetChained() != nil {
+				chainedHit, chainedErr := adapter.GetChainedCtx().CheckAndSetItemCtx(ctx, key, value)
+				if chainedErr != nil && !errs.Is(chainedErr, errors.ErrKeyNotFound) {
+					adapter.LogError(ctx, "failed to check and set item in chained adapter", chainedErr, key)
+				}
+				if chainedHit && !hit {
+					clearMiss(ctx, key)
+					return true, nil
+				}
 			}
 			return hit, err
 		}).
-		SetCheckAndSetItemsFunc(func(values map[string]any) ([]string, error) {
+		SetCheckAndSetItemsCtxFunc(func(ctx context.Context, values map[string]any) ([]string, error) {
 			keys := make([]string, 0)
 			var err error
 			for key, value := range values {
-				ok, _ := adapter.CheckAndSetItem(key, value)
+				ok, _ := adapter.CheckAndSetItemCtx(ctx, key, value)
 				if !ok {
 					err = errors.ErrKeyNotFound
 					continue
@@ -456,168 +979,162 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 			}
 			return keys, err
 		}).
-		SetTouchItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
-				return false
-			}
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
-				return false
-			}
-			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
-				return false
-			}
-			cl := adapter.Client.(valkey.Client)
-			resp, _ := cl.Do(
-				context.TODO(),
-				cl.B().Expire().Key(nsKey).Seconds(int64(adapter.GetOptions()[storage.OptTTL].(time.Duration).Seconds())).Build(),
-			).AsInt64()
-			hit := resp == int64(1)
-			if hit {
-				_ = touchType(key)
-			}
-			if adapter.GetChained() != nil {
-				_ = adapter.GetChained().TouchItem(key)
-			}
-			return hit
+		SetTouchItemCtxFunc(func(ctx context.Context, key string) bool {
+			return touchItem(ctx, key, storage.DefaultTTL)
 		}).
-		SetTouchItemsFunc(func(keys []string) []string {
-			if !adapter.GetOptions()[storage.OptReadable].(bool) {
+		SetTouchItemsCtxFunc(func(ctx context.Context, keys []string) []string {
+			if !adapter.GetOptions().Readable() {
 				return []string{}
 			}
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+			if !adapter.GetOptions().Writable() {
 				return []string{}
 			}
 			cl := adapter.Client.(valkey.Client)
 			cmds := make(valkey.Commands, 0, len(keys))
 			for _, key := range keys {
 				nsKey := adapter.NamespacedKey(key)
-				if !adapter.ValidateKey(nsKey) {
+				if !adapter.ValidateItemKey(key) {
 					return []string{}
 				}
-				cmds = append(cmds, cl.B().Expire().Key(nsKey).Seconds(int64(adapter.GetOptions()[storage.OptTTL].(time.Duration).Seconds())).Build().Pin())
+				cmds = append(cmds, expireCmd(cl, nsKey, adapter.ResolveTTL(storage.DefaultTTL)).Pin())
 			}
 			retkeys := make([]string, 0)
 			for i, resp := range cl.DoMulti(
-				context.TODO(),
+				ctx,
 				cmds...,
 			) {
-				cmdKey := adapter.StripNamespace(cmds[i].Commands()[1])
+				cmdKey := keys[i]
 				if resp.Error() != nil {
 					continue
 				}
 				hit, err := resp.AsInt64()
 				if err == nil && hit == int64(1) {
 					retkeys = append(retkeys, cmdKey)
-					_ = touchType(cmdKey)
+					adapter.LogError(ctx, "failed to touch managed data type", touchType(ctx, cmdKey, adapter.ResolveTTL(storage.DefaultTTL)), cmdKey)
+					syncTags(ctx, adapter.NamespacedKey(cmdKey), adapter.ResolveTTL(storage.DefaultTTL))
+					saveKeys(ctx, adapter.ResolveTTL(storage.DefaultTTL), cmdKey)
 				}
 			}
 			if adapter.GetChained() != nil {
-				_ = adapter.GetChained().TouchItems(keys)
+				_ = adapter.GetChainedCtx().TouchItemsCtx(ctx, keys)
 			}
 			return retkeys
 		}).
-		SetRemoveItemFunc(func(key string) bool {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetRemoveItemCtxFunc(func(ctx context.Context, key string) bool {
+			if !adapter.GetOptions().Writable() {
 				return false
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return false
 			}
 			cl := adapter.Client.(valkey.Client)
 			err2 := cl.Do(
-				context.TODO(),
+				ctx,
 				cl.B().Del().Key(nsKey).Build(),
 			).Error()
+			adapter.LogError(ctx, "failed to remove item", err2, key)
+			if err2 == nil && adapter.KeyPolicy().Hashed(nsKey) {
+				adapter.LogError(ctx, "failed to remove hashed key", cl.Do(ctx, cl.B().Del().Key(fmt.Sprintf(adapter2.KeyKeyTpl, nsKey)).Build()).Error(), key)
+			}
+			storage.ReportFailure(ctx, adapter, err2)
+			removeTags(ctx, nsKey)
+			adapter.LogError(ctx, "failed to delete managed data type", delType(ctx, key), key)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().RemoveItem(key)
+				return adapter.GetChainedCtx().RemoveItemCtx(ctx, key)
 			}
-			_ = delType(key)
 			return err2 == nil
 		}).
-		SetRemoveItemsFunc(func(keys []string) []string {
+		SetRemoveItemsCtxFunc(func(ctx context.Context, keys []string) []string {
 			cl := adapter.Client.(valkey.Client)
 			cmds := make(valkey.Commands, 0, len(keys))
 			for _, key := range keys {
 				nsKey := adapter.NamespacedKey(key)
-				if !adapter.ValidateKey(nsKey) {
+				if !adapter.ValidateItemKey(key) {
 					return []string{}
 				}
 				cmds = append(cmds, cl.B().Del().Key(nsKey).Build().Pin())
 			}
 			ret := make([]string, 0)
 			for i, resp := range cl.DoMulti(
-				context.TODO(),
+				ctx,
 				cmds...,
 			) {
-				cmdKey := adapter.StripNamespace(cmds[i].Commands()[1])
+				cmdKey := keys[i]
 				if resp.Error() != nil {
+					adapter.LogError(ctx, "failed to remove item", resp.Error(), cmdKey)
+					storage.ReportFailure(ctx, adapter, resp.Error())
 					continue
 				}
 				if hit, err := resp.AsInt64(); err == nil && hit == int64(1) {
 					ret = append(ret, cmdKey)
 				}
+				removeTags(ctx, adapter.NamespacedKey(cmdKey))
+				if nsKey := adapter.NamespacedKey(cmdKey); adapter.KeyPolicy().Hashed(nsKey) {
+					adapter.LogError(ctx, "failed to remove hashed key", cl.Do(ctx, cl.B().Del().Key(fmt.Sprintf(adapter2.KeyKeyTpl, nsKey)).Build()).Error(), cmdKey)
+				}
 			}
+			adapter.LogError(ctx, "failed to delete managed data types", delTypeMulti(ctx, keys), keys...)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().RemoveItems(keys)
+				return adapter.GetChainedCtx().RemoveItemsCtx(ctx, keys)
 			}
-			_ = delTypeMulti(keys)
 			return ret
 		}).
-		SetIncrementFunc(func(key string, n int64) (int64, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetIncrementCtxFunc(func(ctx context.Context, key string, n int64) (int64, error) {
+			if !adapter.GetOptions().Writable() {
 				return 0, errors.ErrNotWritable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return 0, errors.ErrKeyInvalid
 			}
+			if err := adapter.CheckKeyLength(key, nsKey); err != nil {
+				return 0, err
+			}
 			cl := adapter.Client.(valkey.Client)
-			err := cl.Do(
-				context.TODO(),
+			val, err := cl.Do(
+				ctx,
 				cl.B().Incrby().Key(nsKey).Increment(n).Build(),
-			).Error()
+			).AsInt64()
 			if err != nil {
 				return 0, err
 			}
+			clearMiss(ctx, key)
+			syncKey(ctx, key)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().Increment(key, n)
-			}
-			val, err := adapter.GetItem(key)
-			if err != nil {
-				return 0, err
+				return adapter.GetChainedCtx().IncrementCtx(ctx, key, n)
 			}
-			return strconv.ParseInt(val.(string), 10, 64)
+			return val, nil
 		}).
-		SetDecrementFunc(func(key string, n int64) (int64, error) {
-			if !adapter.GetOptions()[storage.OptWritable].(bool) {
+		SetDecrementCtxFunc(func(ctx context.Context, key string, n int64) (int64, error) {
+			if !adapter.GetOptions().Writable() {
 				return 0, errors.ErrNotWritable
 			}
 			nsKey := adapter.NamespacedKey(key)
-			if !adapter.ValidateKey(nsKey) {
+			if !adapter.ValidateItemKey(key) {
 				return 0, errors.ErrKeyInvalid
 			}
+			if err := adapter.CheckKeyLength(key, nsKey); err != nil {
+				return 0, err
+			}
 			cl := adapter.Client.(valkey.Client)
-			err := cl.Do(
-				context.TODO(),
+			val, err := cl.Do(
+				ctx,
 				cl.B().Decrby().Key(nsKey).Decrement(n).Build(),
-			).Error()
+			).AsInt64()
 			if err != nil {
 				return 0, err
 			}
+			clearMiss(ctx, key)
+			syncKey(ctx, key)
 			if adapter.GetChained() != nil {
-				return adapter.GetChained().Decrement(key, n)
+				return adapter.GetChainedCtx().DecrementCtx(ctx, key, n)
 			}
-			val, err := adapter.GetItem(key)
-			if err != nil {
-				return 0, err
-			}
-			return strconv.ParseInt(val.(string), 10, 64)
+			return val, nil
 		}).
 		SetOpenFunc(func() (storage.Storage, error) {
 			c, err := valkey.NewClient(
-				adapter.GetOptions()[OptValkeyOptions].(valkey.ClientOption),
+				storage.OptionValue(adapter.GetOptions(), OptValkeyOptions, valkey.ClientOption{}),
 			)
 			if err != nil {
 				return nil, errs.Wrap(err, "failed to create Valkey client")
@@ -628,6 +1145,359 @@ func New(namespace string, host string, ttl time.Duration, clientCaching bool, c
 		}).
 		SetCloseFunc(func() error {
 			return nil
+		}).
+		SetSetItemWithTTLFunc(setItem).
+		SetSetItemsWithTTLFunc(setItems).
+		SetTouchItemWithTTLFunc(touchItem).
+		SetGetTTLFunc(func(ctx context.Context, key string) (time.Duration, error) {
+			if !adapter.GetOptions().Readable() {
+				return 0, errors.ErrNotReadable
+			}
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return 0, errors.ErrKeyInvalid
+			}
+			cl := adapter.Client.(valkey.Client)
+			ms, err := cl.Do(
+				ctx,
+				cl.B().Pttl().Key(nsKey).Build(),
+			).AsInt64()
+			if err != nil {
+				return 0, errs.Wrap(err, "failed to get ttl")
+			}
+			switch ms {
+			case -2:
+				return 0, errors.ErrKeyNotFound
+			case -1:
+				return storage.NoExpiry, nil
+			default:
+				return time.Duration(ms) * time.Millisecond, nil
+			}
+		}).
+		SetSetTagsFunc(func(ctx context.Context, key string, tags []string) (bool, error) {
+			if !adapter.GetOptions().Writable() {
+				return false, errors.ErrNotWritable
+			}
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return false, errors.ErrKeyInvalid
+			}
+			cl := adapter.Client.(valkey.Client)
+			ms, err := cl.Do(ctx, cl.B().Pttl().Key(nsKey).Build()).AsInt64()
+			if err != nil {
+				return false, errs.Wrap(err, "failed to set tags")
+			}
+			ttl := storage.NoExpiry
+			switch {
+			case ms == -2:
+				return false, errors.ErrKeyNotFound
+			case ms > 0:
+				ttl = time.Duration(ms) * time.Millisecond
+			}
+			removeTags(ctx, nsKey)
+			if len(tags) > 0 {
+				tagsKey := fmt.Sprintf(adapter2.TagsKeyTpl, nsKey)
+				cmds := valkey.Commands{cl.B().Sadd().Key(tagsKey).Member(tags...).Build()}
+				for _, tag := range tags {
+					cmds = append(cmds, cl.B().Sadd().Key(fmt.Sprintf(adapter2.TagKeyTpl, adapter.NamespacedKey(tag))).Member(nsKey).Build())
+				}
+				cmds = append(cmds, expireCmd(cl, tagsKey, ttl))
+				cmds = append(cmds, tagIndexExpireCmds(cl, tags, ttl)...)
+				for _, resp := range cl.DoMulti(ctx, cmds...) {
+					if resp.Error() != nil {
+						return false, errs.Wrap(resp.Error(), "failed to set tags")
+					}
+				}
+			}
+			if t, ok := adapter.GetChained().(storage.Taggable); ok {
+				if _, err := t.SetTagsCtx(ctx, key, tags...); err != nil {
+					adapter.LogError(ctx, "failed to set tags in chained adapter", err, key)
+				}
+			}
+			return true, nil
+		}).
+		SetGetTagsFunc(func(ctx context.Context, key string) ([]string, error) {
+			if !adapter.GetOptions().Readable() {
+				return []string{}, errors.ErrNotReadable
+			}
+			nsKey := adapter.NamespacedKey(key)
+			if !adapter.ValidateItemKey(key) {
+				return []string{}, errors.ErrKeyInvalid
+			}
+			cl := adapter.Client.(valkey.Client)
+			resp := cl.DoMulti(
+				ctx,
+				cl.B().Exists().Key(nsKey).Build(),
+				cl.B().Smembers().Key(fmt.Sprintf(adapter2.TagsKeyTpl, nsKey)).Build(),
+			)
+			exists, err := resp[0].AsInt64()
+			if err != nil {
+				return []string{}, errs.Wrap(err, "failed to get tags")
+			}
+			if exists == 0 {
+				return []string{}, errors.ErrKeyNotFound
+			}
+			tags, err := resp[1].AsStrSlice()
+			if err != nil {
+				return []string{}, errs.Wrap(err, "failed to get tags")
+			}
+			slices.Sort(tags)
+			return tags, nil
+		}).
+		SetClearByTagsFunc(func(ctx context.Context, tags []string, disjunction bool) (bool, error) {
+			if !adapter.GetOptions().Writable() {
+				return false, errors.ErrNotWritable
+			}
+			if len(tags) > 0 {
+				cl := adapter.Client.(valkey.Client)
+				tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+				//the tag index sets may be in different cluster slots so they are combined here rather than with SINTER or SUNION
+				cmds := make(valkey.Commands, len(tags))
+				for i, tag := range tags {
+					cmds[i] = cl.B().Smembers().Key(fmt.Sprintf(adapter2.TagKeyTpl, adapter.NamespacedKey(tag))).Build()
+				}
+				counts := make(map[string]int)
+				for _, resp := range cl.DoMulti(ctx, cmds...) {
+					members, err := resp.AsStrSlice()
+					if err != nil {
+						return false, errs.Wrap(err, "failed to get tagged items")
+					}
+					for _, m := range members {
+						counts[m]++
+					}
+				}
+				nsKeys := make([]string, 0, len(counts))
+				for nsKey, n := range counts {
+					if disjunction || n == len(tags) {
+						nsKeys = append(nsKeys, nsKey)
+					}
+				}
+				if len(nsKeys) > 0 {
+					adapter.RemoveItemsCtx(ctx, keysOf(ctx, nsKeys))
+				}
+			}
+			if t, ok := adapter.GetChained().(storage.Taggable); ok {
+				return t.ClearByTagsCtx(ctx, tags, disjunction)
+			}
+			return true, nil
+		}).
+		SetFlushFunc(func(ctx context.Context) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			ns := adapter.NamespacedPrefix("")
+			if ns == "" {
+				cl := adapter.Client.(valkey.Client)
+				return errs.Wrap(cl.Do(ctx, cl.B().Flushdb().Build()).Error(), "failed to flush")
+			}
+			//only the namespace items and their metadata are removed
+			escaped := adapter2.EscapeGlob(ns)
+			for _, match := range []string{
+				escaped + "*",
+				fmt.Sprintf(ManagedDataTypeCacheTpl, escaped) + "*",
+				fmt.Sprintf(adapter2.TagsKeyTpl, escaped) + "*",
+				fmt.Sprintf(adapter2.TagKeyTpl, escaped) + "*",
+				fmt.Sprintf(adapter2.NegativeKeyTpl, escaped) + "*",
+				fmt.Sprintf(adapter2.SoftKeyTpl, escaped) + "*",
+				fmt.Sprintf(adapter2.DeltaKeyTpl, escaped) + "*",
+				fmt.Sprintf(adapter2.KeyKeyTpl, escaped) + "*",
+			} {
+				if err := scanKeys(ctx, match, scanCount, func(keys []string) error {
+					return unlinkKeys(ctx, keys)
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).
+		SetClearExpiredFunc(func(ctx context.Context) error {
+			if !adapter.GetOptions().Writable() {
+				return errors.ErrNotWritable
+			}
+			//expired items are removed by the server, but the tag index sets still list them
+			cl := adapter.Client.(valkey.Client)
+			match := fmt.Sprintf(adapter2.TagKeyTpl, adapter2.EscapeGlob(adapter.NamespacedPrefix(""))) + "*"
+			return scanKeys(ctx, match, scanCount, func(indexKeys []string) error {
+				for _, indexKey := range indexKeys {
+					members, err := cl.Do(ctx, cl.B().Smembers().Key(indexKey).Build()).AsStrSlice()
+					if err != nil || len(members) == 0 {
+						continue
+					}
+					cmds := make(valkey.Commands, len(members))
+					for i, m := range members {
+						cmds[i] = cl.B().Exists().Key(m).Build()
+					}
+					expired := make([]string, 0)
+					for i, resp := range cl.DoMulti(ctx, cmds...) {
+						if n, err := resp.AsInt64(); err == nil && n == 0 {
+							expired = append(expired, members[i])
+						}
+					}
+					if len(expired) > 0 {
+						err = cl.Do(ctx, cl.B().Srem().Key(indexKey).Member(expired...).Build()).Error()
+						if err != nil {
+							return errs.Wrap(err, "failed to clear expired tags")
+						}
+					}
+				}
+				return nil
+			})
+		}).
+		SetClearByPrefixFunc(func(ctx context.Context, prefix string) error {
+			//hashed keys do not have the prefix of their key, so the whole namespace is scanned
+			glob := adapter2.EscapeGlob(adapter.NamespacedPrefix(""))
+			if !adapter.KeyPolicy().Hashing() {
+				glob = adapter2.EscapeGlob(adapter.NamespacedPrefix(prefix))
+			}
+			err := clearMatching(ctx, glob+"*", func(key string) bool {
+				return strings.HasPrefix(key, prefix)
+			})
+			if c, ok := adapter.GetChained().(storage.Clearable); ok && err == nil {
+				return c.ClearByPrefixCtx(ctx, prefix)
+			}
+			return err
+		}).
+		SetClearByPatternFunc(func(ctx context.Context, pattern string) error {
+			//the pattern is matched against the decoded keys, as it is by the memory adapter, so it is only sent
+			//to the backend if the keys are neither encoded nor hashed
+			glob := adapter2.EscapeGlob(adapter.NamespacedPrefix("")) + "*"
+			if adapter.KeyPolicy().Transparent() {
+				glob = adapter2.EscapeGlob(adapter.NamespacedPrefix("")) + pattern
+			}
+			err := clearMatching(ctx, glob, func(key string) bool {
+				return adapter2.MatchGlob(pattern, key)
+			})
+			if c, ok := adapter.GetChained().(storage.Clearable); ok && err == nil {
+				return c.ClearByPatternCtx(ctx, pattern)
+			}
+			return err
+		}).
+		SetIterateFunc(func(ctx context.Context, opts storage.IterateOptions) iter.Seq2[string, any] {
+			return func(yield func(string, any) bool) {
+				if !adapter.GetOptions().Readable() {
+					opts.Error(errors.ErrNotReadable)
+					return
+				}
+				cl := adapter.Client.(valkey.Client)
+				match := adapter2.EscapeGlob(adapter.NamespacedPrefix(opts.Prefix)) + "*"
+				err := scanKeys(ctx, match, int64(opts.Batch()), func(scanned []string) error {
+					nsKeys := itemKeys(scanned)
+					if len(nsKeys) == 0 {
+						return nil
+					}
+					//MGet splits the keys by cluster slot
+					resp, err := valkey.MGet(cl, ctx, nsKeys)
+					if err != nil {
+						return errs.Wrap(err, "failed to get items")
+					}
+					keys := keysOf(ctx, nsKeys)
+					vals := make(map[string]any, len(nsKeys))
+					for i, nsKey := range nsKeys {
+						//the item may have expired or been removed since the scan
+						msg, ok := resp[nsKey]
+						if !ok {
+							continue
+						}
+						v, err := msg.ToString()
+						if err != nil {
+							continue
+						}
+						dv, err := decode(v)
+						if err != nil {
+							return errs.Wrap(err, "failed to get items")
+						}
+						vals[keys[i]] = dv
+					}
+					typed, err := getTypedMulti(ctx, vals)
+					if err != nil {
+						return err
+					}
+					for _, key := range keys {
+						v, ok := typed[key]
+						if !ok {
+							continue
+						}
+						if !yield(key, v) {
+							return errStopIteration
+						}
+					}
+					return nil
+				})
+				if err != nil && !errs.Is(err, errStopIteration) {
+					opts.Error(err)
+				}
+			}
+		}).
+		SetIterateKeysFunc(func(ctx context.Context, opts storage.IterateOptions) iter.Seq[string] {
+			return func(yield func(string) bool) {
+				if !adapter.GetOptions().Readable() {
+					opts.Error(errors.ErrNotReadable)
+					return
+				}
+				match := adapter2.EscapeGlob(adapter.NamespacedPrefix(opts.Prefix)) + "*"
+				err := scanKeys(ctx, match, int64(opts.Batch()), func(keys []string) error {
+					for _, key := range keysOf(ctx, itemKeys(keys)) {
+						if !yield(key) {
+							return errStopIteration
+						}
+					}
+					return nil
+				})
+				if err != nil && !errs.Is(err, errStopIteration) {
+					opts.Error(err)
+				}
+			}
+		}).
+		SetGetDeltasFunc(func(ctx context.Context, keys []string) map[string]adapter2.Delta {
+			cl := adapter.Client.(valkey.Client)
+			cmds := make(valkey.Commands, 0, len(keys)*2)
+			for _, key := range keys {
+				nsKey := adapter.NamespacedKey(key)
+				cmds = append(cmds,
+					cl.B().Get().Key(fmt.Sprintf(adapter2.DeltaKeyTpl, nsKey)).Build(),
+					cl.B().Pttl().Key(nsKey).Build(),
+				)
+			}
+			resps := cl.DoMulti(ctx, cmds...)
+			ret := make(map[string]adapter2.Delta, len(keys))
+			for i, key := range keys {
+				delta, err := resps[i*2].AsInt64()
+				if err != nil {
+					continue
+				}
+				//PTTL returns -2 for a key that does not exist and -1 for a key without a TTL
+				ms, err := resps[i*2+1].AsInt64()
+				if err != nil || ms == -2 {
+					continue
+				}
+				ttl := storage.NoExpiry
+				if ms >= 0 {
+					ttl = time.Duration(ms) * time.Millisecond
+				}
+				ret[key] = adapter2.Delta{Compute: time.Duration(delta), TTL: ttl}
+			}
+			return ret
+		}).
+		SetSetDeltasFunc(func(ctx context.Context, deltas map[string]time.Duration) {
+			//the items have just been set with the default TTL, so the deltas expire with them
+			ttl := adapter.ResolveTTL(storage.DefaultTTL)
+			if ttl == storage.NoExpiry {
+				return
+			}
+			cl := adapter.Client.(valkey.Client)
+			cmds := make(valkey.Commands, 0, len(deltas))
+			for key, delta := range deltas {
+				deltaKey := fmt.Sprintf(adapter2.DeltaKeyTpl, adapter.NamespacedKey(key))
+				cmds = append(cmds, setCmd(cl, deltaKey, strconv.FormatInt(int64(delta), 10), ttl, false))
+			}
+			for _, resp := range cl.DoMulti(ctx, cmds...) {
+				adapter.LogError(ctx, "failed to set compute time", resp.Error(), slices.Sorted(maps.Keys(deltas))...)
+			}
+		}).
+		SetCapabilities(storage.Capabilities{
+			TTL:          true,
+			TTLPrecision: time.Millisecond,
+			Persistent:   true,
 		})
 
 	return adapter
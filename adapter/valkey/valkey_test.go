@@ -1,17 +1,23 @@
 package valkey_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
 	"github.com/chippyash/go-cache-manager/adapter"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
 	"github.com/chippyash/go-cache-manager/adapter/valkey"
 	"github.com/chippyash/go-cache-manager/errors"
 	"github.com/chippyash/go-cache-manager/storage"
 	"github.com/stretchr/testify/assert"
 	valkey2 "github.com/valkey-io/valkey-go"
+	"log/slog"
 	"maps"
 	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -231,6 +237,27 @@ func TestValkeyAdapter_Chaining(t *testing.T) {
 	assert.True(t, slices.Contains(parentKeys, "two:key3"))
 }
 
+func TestValkeyAdapter_ChainedRemoveItem(t *testing.T) {
+	rs := miniRedis(t)
+	rs2 := miniredis.RunT(t)
+	chainedAdapter, err := valkey.New("one:", rs2.Addr(), time.Second*60, false, time.Second*0, false).Open()
+	assert.NoError(t, err)
+	sut, err := valkey.New("two:", rs.Addr(), time.Second*60, false, time.Second*0, true).Open()
+	assert.NoError(t, err)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	_, err = sut.SetItems(map[string]any{"key1": 1, "key2": 2, "key3": 3})
+	assert.NoError(t, err)
+	assert.True(t, rs.Exists(fmt.Sprintf(valkey.ManagedDataTypeCacheTpl, "two:key1")))
+
+	assert.True(t, sut.RemoveItem("key1"))
+	assert.False(t, rs.Exists("two:key1"))
+	assert.False(t, rs.Exists(fmt.Sprintf(valkey.ManagedDataTypeCacheTpl, "two:key1")))
+	assert.False(t, chainedAdapter.HasItem("key1"))
+
+	sut.RemoveItems([]string{"key2", "key3"})
+	assert.Empty(t, rs.Keys())
+	assert.False(t, chainedAdapter.HasItem("key2"))
+}
 func TestValkeyAdapter_CheckAndSetItem(t *testing.T) {
 	rs := miniRedis(t)
 	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, false)
@@ -433,6 +460,370 @@ func TestValkeyAdapter_GetClient(t *testing.T) {
 	assert.NotNil(t, client)
 }
 
+func TestValkeyAdapter_Context(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	ok, err := sut.(storage.StorageContext).SetItemCtx(context.Background(), "foo", 10)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	val, err := sut.(storage.StorageContext).GetItemCtx(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, val)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = sut.(storage.StorageContext).GetItemCtx(ctx, "foo")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = sut.(storage.StorageContext).SetItemsCtx(ctx, map[string]any{"bar": "baz"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, rs.Exists("one:bar"))
+}
+
+func TestValkeyAdapter_Codec(t *testing.T) {
+	rs := miniRedis(t)
+	//types are not managed when there is a codec
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	opts := sut.GetOptions()
+	opts[storage.OptCodec] = storage.JsonCodec{}
+	sut.SetOptions(opts)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+
+	type myStruct struct {
+		Name  string
+		Count int
+	}
+	ok, err := sut.SetItem("foo", myStruct{Name: "foo", Count: 2})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	_, err = sut.SetItems(map[string]any{"bar": []string{"a", "b"}})
+	assert.NoError(t, err)
+
+	raw, err := rs.Get("one:foo")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Name":"foo","Count":2}`, raw)
+	assert.False(t, rs.Exists("gcm:one:foo"))
+
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"Name": "foo", "Count": float64(2)}, val)
+
+	vals, err := sut.GetItems([]string{"foo", "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"Name": "foo", "Count": float64(2)}, vals["foo"])
+	assert.Equal(t, []any{"a", "b"}, vals["bar"])
+
+	typed, err := storage.NewTypedStorage[myStruct](sut).GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, myStruct{Name: "foo", Count: 2}, typed)
+}
+
+func TestValkeyAdapter_IncrementManaged(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItem("foo", int8(100))
+	assert.NoError(t, err)
+	val, err := sut.Increment("foo", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(101), val)
+}
+
+func TestValkeyAdapter_ItemTTL(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	ttlSut := sut.(storage.Expirable)
+	ok, err := ttlSut.SetItemWithTTL("short", 10, time.Second*5)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ok, err = ttlSut.SetItemWithTTL("forever", "value", storage.NoExpiry)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	_, err = sut.SetItem("default", "value")
+	assert.NoError(t, err)
+
+	assert.Equal(t, time.Second*5, rs.TTL("one:short"))
+	//the managed type expires with the value
+	assert.Equal(t, time.Second*5, rs.TTL(fmt.Sprintf(valkey.ManagedDataTypeCacheTpl, "one:short")))
+	ttl, err := ttlSut.GetTTL("short")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*5, ttl)
+	ttl, err = ttlSut.GetTTL("forever")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.NoExpiry, ttl)
+	ttl, err = ttlSut.GetTTL("default")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*60, ttl)
+	_, err = ttlSut.GetTTL("unknown")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	rs.FastForward(time.Second * 6)
+	_, err = sut.GetItem("short")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	val, err := sut.GetItem("forever")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", val)
+
+	keys, err := ttlSut.SetItemsWithTTL(map[string]any{"foo": "bar", "bar": "baz"}, time.Hour)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, keys)
+	assert.Equal(t, time.Hour, rs.TTL("one:foo"))
+	assert.Equal(t, time.Hour, rs.TTL("one:bar"))
+}
+
+func TestValkeyAdapter_TouchItemWithTTL(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	assert.True(t, sut.(storage.Expirable).TouchItemWithTTL("foo", time.Hour))
+	assert.Equal(t, time.Hour, rs.TTL("one:foo"))
+	assert.Equal(t, time.Hour, rs.TTL(fmt.Sprintf(valkey.ManagedDataTypeCacheTpl, "one:foo")))
+	assert.True(t, sut.(storage.Expirable).TouchItemWithTTL("foo", storage.NoExpiry))
+	assert.Equal(t, time.Duration(0), rs.TTL("one:foo"))
+	//touching an item that does not expire is still a hit
+	assert.True(t, sut.(storage.Expirable).TouchItemWithTTL("foo", storage.NoExpiry))
+	assert.False(t, sut.(storage.Expirable).TouchItemWithTTL("unknown", time.Hour))
+}
+
+func TestValkeyAdapter_Tags(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, false)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	tagSut := sut.(storage.Taggable)
+	_, err = sut.SetItems(map[string]any{"order1": 1, "order2": 2, "order3": 3, "product1": "p"})
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("order1", "customer:1", "orders")
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("order2", "customer:2", "orders")
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("order3", "customer:1", "orders")
+	assert.NoError(t, err)
+	_, err = tagSut.SetTags("product1", "customer:1")
+	assert.NoError(t, err)
+
+	ok, err := tagSut.SetTags("unknown", "orders")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	tags, err := tagSut.GetTags("order1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"customer:1", "orders"}, tags)
+	_, err = tagSut.GetTags("unknown")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	//replacing the tags removes the item from the old tag index
+	_, err = tagSut.SetTags("product1", "products")
+	assert.NoError(t, err)
+	members, err := rs.SMembers(fmt.Sprintf(adapter.TagKeyTpl, "one:customer:1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one:order1", "one:order3"}, members)
+	_, err = tagSut.SetTags("product1", "customer:1")
+	assert.NoError(t, err)
+
+	//conjunction clears the items that have all the tags
+	ok, err = tagSut.ClearByTags([]string{"customer:1", "orders"}, false)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.False(t, sut.HasItem("order1"))
+	assert.True(t, sut.HasItem("order2"))
+	assert.False(t, sut.HasItem("order3"))
+	assert.True(t, sut.HasItem("product1"))
+	assert.False(t, rs.Exists(fmt.Sprintf(adapter.TagsKeyTpl, "one:order1")))
+
+	//disjunction clears the items that have any of the tags
+	ok, err = tagSut.ClearByTags([]string{"customer:2", "customer:1"}, true)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.False(t, sut.HasItem("order2"))
+	assert.False(t, sut.HasItem("product1"))
+	//removing the items removes all the tag metadata
+	assert.Empty(t, rs.Keys())
+}
+
+func TestValkeyAdapter_TagsExpireWithItem(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, false)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("foo", "baz")
+	assert.NoError(t, err)
+	tagsKey := fmt.Sprintf(adapter.TagsKeyTpl, "one:foo")
+	indexKey := fmt.Sprintf(adapter.TagKeyTpl, "one:baz")
+	assert.Equal(t, time.Second*60, rs.TTL(tagsKey))
+	assert.Equal(t, time.Second*60, rs.TTL(indexKey))
+
+	assert.True(t, sut.(storage.Expirable).TouchItemWithTTL("foo", time.Hour))
+	assert.Equal(t, time.Hour, rs.TTL(tagsKey))
+	assert.Equal(t, time.Hour, rs.TTL(indexKey))
+	//the tag index keeps the longest ttl of its items
+	_, err = sut.(storage.Expirable).SetItemWithTTL("foo", "bar", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, rs.TTL(tagsKey))
+	assert.Equal(t, time.Hour, rs.TTL(indexKey))
+	_, err = sut.(storage.Expirable).SetItemWithTTL("foo", "bar", storage.NoExpiry)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), rs.TTL(tagsKey))
+	assert.Equal(t, time.Duration(0), rs.TTL(indexKey))
+}
+
+func TestValkeyAdapter_ReservedKeys(t *testing.T) {
+	rs := miniRedis(t)
+	//without a namespace, the managed data type key of "tags:x" is "gcm:tags:x"
+	sut := valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItems(map[string]any{"x": "foo", "tags:x": 1})
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("x", "bar")
+	assert.NoError(t, err)
+	_, err = sut.SetItem("tags:x", 2)
+	assert.NoError(t, err)
+	tags, err := sut.(storage.Taggable).GetTags("x")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, tags)
+	val, err := sut.GetItem("tags:x")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+
+	//keys with the reserved prefix are invalid, so are never hidden from the iterators
+	_, err = sut.SetItem("gcm:x", "foo")
+	assert.ErrorIs(t, err, errors.ErrKeyInvalid)
+	_, err = sut.GetItem("gcm:x")
+	assert.ErrorIs(t, err, errors.ErrKeyInvalid)
+	assert.Equal(t, []string{"tags:x", "x"}, slices.Sorted(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})))
+}
+
+func TestValkeyAdapter_Flush(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	other := valkey.New("two:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	other, err = other.Open()
+	assert.NoError(t, err)
+	//miniredis SCAN cursors are offsets into the sorted keys, so unlinking keys during a scan skips keys that a
+	//Valkey server would return. Keep the keys within a single scan batch
+	for i := 0; i < 40; i++ {
+		_, err = sut.SetItem(fmt.Sprintf("key%d", i), i)
+		assert.NoError(t, err)
+	}
+	_, err = sut.(storage.Taggable).SetTags("key1", "tag")
+	assert.NoError(t, err)
+	_, err = other.SetItem("key1", 1)
+	assert.NoError(t, err)
+
+	//only the namespace is flushed
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.ElementsMatch(t, []string{"two:key1", fmt.Sprintf(valkey.ManagedDataTypeCacheTpl, "two:key1")}, rs.Keys())
+
+	//without a namespace the database is flushed
+	sut = valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, false)
+	sut, err = sut.Open()
+	assert.NoError(t, err)
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.Empty(t, rs.Keys())
+}
+
+func TestValkeyAdapter_ClearExpired(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, false)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	_, err = sut.(storage.Expirable).SetItemWithTTL("foo", "bar", time.Second)
+	assert.NoError(t, err)
+	_, err = sut.(storage.Expirable).SetItemWithTTL("bar", "baz", time.Hour)
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("foo", "tag")
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("bar", "tag")
+	assert.NoError(t, err)
+	rs.FastForward(time.Second * 2)
+
+	assert.NoError(t, sut.(storage.ClearExpiredable).ClearExpired())
+	members, err := rs.SMembers(fmt.Sprintf(adapter.TagKeyTpl, "one:tag"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one:bar"}, members)
+}
+
+func TestValkeyAdapter_ClearByPrefixAndPattern(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItems(map[string]any{
+		"users:42:name":  "bob",
+		"users:42:email": "bob@example.com",
+		"users:43:name":  "alice",
+		"users:420:name": "carol",
+	})
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("users:42:name", "names")
+	assert.NoError(t, err)
+	//an item in another namespace with a matching key
+	rs.Set("two:users:42:name", "dave")
+
+	assert.NoError(t, sut.(storage.Clearable).ClearByPrefix("users:42:"))
+	assert.False(t, sut.HasItem("users:42:name"))
+	assert.False(t, sut.HasItem("users:42:email"))
+	assert.True(t, sut.HasItem("users:43:name"))
+	assert.True(t, sut.HasItem("users:420:name"))
+	assert.True(t, rs.Exists("two:users:42:name"))
+	//the item metadata is removed too
+	assert.False(t, rs.Exists(fmt.Sprintf(valkey.ManagedDataTypeCacheTpl, "one:users:42:name")))
+	assert.False(t, rs.Exists(fmt.Sprintf(adapter.TagsKeyTpl, "one:users:42:name")))
+	assert.False(t, rs.Exists(fmt.Sprintf(adapter.TagKeyTpl, "one:names")))
+
+	assert.NoError(t, sut.(storage.Clearable).ClearByPattern("users:4?:name"))
+	assert.False(t, sut.HasItem("users:43:name"))
+	assert.True(t, sut.HasItem("users:420:name"))
+	assert.True(t, rs.Exists("two:users:42:name"))
+}
+
+func TestValkeyAdapter_Iterate(t *testing.T) {
+	rs := miniRedis(t)
+	sut := valkey.New("one:", rs.Addr(), time.Second*60, false, time.Second*0, true)
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	vals := make(map[string]any)
+	for i := 0; i < 25; i++ {
+		vals[fmt.Sprintf("users:%d", i)] = i
+	}
+	vals["other"] = "value"
+	_, err = sut.SetItems(vals)
+	assert.NoError(t, err)
+	_, err = sut.(storage.Taggable).SetTags("other", "tag")
+	assert.NoError(t, err)
+	rs.Set("two:users:1", "another namespace")
+
+	//values are typed, and metadata and other namespaces are not iterated
+	assert.Equal(t, vals, maps.Collect(sut.(storage.Iterable).Iterate(storage.IterateOptions{BatchSize: 10})))
+	delete(vals, "other")
+	assert.ElementsMatch(t,
+		slices.Collect(maps.Keys(vals)),
+		slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{Prefix: "users:", BatchSize: 10})),
+	)
+	//the iteration can be stopped
+	n := 0
+	for range sut.(storage.Iterable).Iterate(storage.IterateOptions{BatchSize: 10}) {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, n)
+}
+
 func miniRedis(t *testing.T) *miniredis.Miniredis {
 	s := miniredis.NewMiniRedis()
 	err := s.StartAddr(":6370")
@@ -440,3 +831,396 @@ func miniRedis(t *testing.T) *miniredis.Miniredis {
 	t.Cleanup(s.Close)
 	return s
 }
+
+func TestValkeyAdapter_LogsChainedErrors(t *testing.T) {
+	rs := miniRedis(t)
+	rs2 := miniredis.RunT(t)
+	chainedAdapter, err := valkey.New("one:", rs2.Addr(), time.Second*60, false, time.Second*0, true).Open()
+	assert.NoError(t, err)
+	sut, err := valkey.New("two:", rs.Addr(), time.Second*60, false, time.Second*0, true).Open()
+	assert.NoError(t, err)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	buf := new(bytes.Buffer)
+	sut.(*adapter.AbstractAdapter).SetLogging(adapter.Logging{Logger: slog.New(slog.NewTextHandler(buf, nil))})
+
+	rs2.SetError("ERR chained failure")
+	buf2 := new(bytes.Buffer)
+	chainedAdapter.(*adapter.AbstractAdapter).SetLogging(adapter.Logging{Logger: slog.New(slog.NewTextHandler(buf2, nil))})
+	ok, err := sut.SetItem("key", "value")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `level=ERROR msg="failed to set item in chained adapter" adapter=valkey namespace=two: key=key`)
+	assert.Contains(t, buf.String(), "chained failure")
+
+	buf.Reset()
+	_, err = sut.GetItem("missing")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	//the chained adapter treats the error as a miss
+	assert.Contains(t, buf2.String(), `msg="failed to get item" adapter=valkey namespace=one: key=missing`)
+}
+
+func TestValkeyAdapter_LogsBackendErrors(t *testing.T) {
+	rs := miniRedis(t)
+	chainedAdapter := memory.New("one:", time.Second*60, time.Second*120)
+	sut, err := valkey.New("two:", rs.Addr(), time.Second*60, false, time.Second*0, false).Open()
+	assert.NoError(t, err)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+	_, err = sut.SetItems(map[string]any{"foo": "bar", "baz": "qux"})
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	sut.(*adapter.AbstractAdapter).SetLogging(adapter.Logging{Logger: slog.New(slog.NewTextHandler(buf, nil))})
+
+	//the operations that return a bool, or treat the error as a miss, log it
+	rs.SetError("ERR backend failure")
+	assert.False(t, sut.HasItem("foo"))
+	assert.False(t, sut.(storage.Expirable).TouchItemWithTTL("foo", time.Hour))
+	vals, err := sut.GetItems([]string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": "bar"}, vals)
+	assert.True(t, sut.RemoveItem("foo"))
+	assert.Equal(t, []string{"baz"}, sut.RemoveItems([]string{"baz"}))
+	for _, msg := range []string{"failed to check item", "failed to touch item", "failed to get item", "failed to remove item"} {
+		assert.Contains(t, buf.String(), `level=ERROR msg="`+msg+`" adapter=valkey namespace=two: key=`)
+	}
+	assert.Contains(t, buf.String(), `key=baz error="backend failure"`)
+}
+
+func TestValkeyAdapter_OpenDSN(t *testing.T) {
+	rs := miniredis.RunT(t)
+	sut, err := storage.OpenDSN(fmt.Sprintf("valkey://%s/2?namespace=users:&ttl=5m&manageTypes=true", rs.Addr()))
+	assert.NoError(t, err)
+	defer sut.Close()
+	opts := sut.GetOptions()
+	assert.Equal(t, "users:", opts[storage.OptNamespace])
+	assert.Equal(t, time.Minute*5, opts[storage.OptTTL])
+	assert.Equal(t, true, opts[valkey.OptManageTypes])
+	assert.Equal(t, 2, opts[valkey.OptValkeyOptions].(valkey2.ClientOption).SelectDB)
+
+	_, err = sut.SetItem("foo", 1)
+	assert.NoError(t, err)
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	rs.Select(2)
+	assert.True(t, rs.Exists("users:foo"))
+
+	_, err = storage.OpenDSN("valkey:///0")
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+	_, err = storage.OpenDSN(fmt.Sprintf("valkey://%s/db", rs.Addr()))
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+	_, err = storage.OpenDSN(fmt.Sprintf("valkey://%s?manageTypes=maybe", rs.Addr()))
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+}
+
+func TestValkeyAdapter_Options(t *testing.T) {
+	rs := miniredis.RunT(t)
+	sut := valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, true,
+		valkey.WithDatetimeFormat(time.DateOnly),
+		valkey.WithClientOption(func(o *valkey2.ClientOption) {
+			o.SelectDB = 3
+		}),
+	)
+	assert.Equal(t, time.DateOnly, sut.GetOptions().String(valkey.OptDatetimeFormat, ""))
+	clientOpts := storage.OptionValue(sut.GetOptions(), valkey.OptValkeyOptions, valkey2.ClientOption{})
+	assert.Equal(t, 3, clientOpts.SelectDB)
+	assert.Equal(t, []string{rs.Addr()}, clientOpts.InitAddress)
+
+	sut, err := sut.Open()
+	assert.NoError(t, err)
+	date := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	_, err = sut.SetItem("foo", date)
+	assert.NoError(t, err)
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, date, val)
+	rs.Select(3)
+	stored, err := rs.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-02", stored)
+
+	_, err = valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, false, storage.WithOption(valkey.OptPort, 0)).Open()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+	assert.Contains(t, err.Error(), "port: must be between 1 and 65535")
+}
+
+func TestValkeyAdapter_Limits(t *testing.T) {
+	rs := miniredis.RunT(t)
+	sut, err := valkey.New("ns:", rs.Addr(), time.Second*60, false, time.Second*0, true,
+		storage.WithMaxKeyLength(8),
+		storage.WithMaxValueLength(5),
+	).Open()
+	assert.NoError(t, err)
+
+	_, err = sut.SetItem("toolong", "foo")
+	assert.ErrorIs(t, err, errors.ErrKeyTooLong)
+	assert.Contains(t, err.Error(), "key: toolong")
+	assert.False(t, rs.Exists("ns:toolong"))
+
+	_, err = sut.SetItem("foo", "toolarge")
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+	assert.Contains(t, err.Error(), "key: foo")
+	assert.False(t, rs.Exists("ns:foo"))
+
+	_, err = sut.SetItems(map[string]any{"foo": "bar", "bar": "toolarge"})
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+	assert.False(t, rs.Exists("ns:foo"))
+
+	_, err = sut.CheckAndSetItem("foo", "toolarge")
+	assert.ErrorIs(t, err, errors.ErrValueTooLarge)
+
+	_, err = sut.Increment("toolong", 1)
+	assert.ErrorIs(t, err, errors.ErrKeyTooLong)
+
+	//unknown types cannot be stored when the data types are managed, as they cannot be converted back
+	type myStruct struct{ Name string }
+	_, err = sut.SetItem("foo", myStruct{})
+	assert.ErrorIs(t, err, errors.ErrUnsupportedDataType)
+	assert.False(t, rs.Exists("ns:foo"))
+}
+
+func TestValkeyAdapter_KeyPolicy(t *testing.T) {
+	rs := miniredis.RunT(t)
+	long := strings.Repeat("x", 100)
+	sut, err := valkey.New("ns:", rs.Addr(), time.Second*60, false, time.Second*0, true,
+		storage.WithKeyCodec(storage.SafeKeyCodec{}),
+		storage.WithKeyHashing(storage.KeyHashing{MaxLength: 70}),
+	).Open()
+	assert.NoError(t, err)
+	vals := map[string]any{
+		"a:ns:b":    1,
+		"users/a b": 2,
+		long:        3,
+	}
+	_, err = sut.SetItems(vals)
+	assert.NoError(t, err)
+	assert.True(t, rs.Exists("ns:a%3Ans%3Ab"))
+	assert.True(t, rs.Exists("ns:users/a%20b"))
+	assert.False(t, rs.Exists("ns:"+long))
+
+	got, err := sut.GetItems(slices.Collect(maps.Keys(vals)))
+	assert.NoError(t, err)
+	assert.Equal(t, vals, got)
+	val, err := sut.GetItem(long)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, val)
+	assert.Equal(t, vals, maps.Collect(sut.(storage.Iterable).Iterate(storage.IterateOptions{})))
+	assert.Equal(t,
+		[]string{"users/a b"},
+		slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{Prefix: "users/a "})),
+	)
+
+	//the key of the hashed key is kept in the backend with the same TTL as the item
+	nsKey := sut.(*adapter.AbstractAdapter).NamespacedKey(long)
+	keyKey := fmt.Sprintf(adapter.KeyKeyTpl, nsKey)
+	assert.True(t, rs.Exists(keyKey))
+	assert.Equal(t, rs.TTL(nsKey), rs.TTL(keyKey))
+
+	//so another adapter over the same backend returns the keys as they were set
+	other, err := valkey.New("ns:", rs.Addr(), time.Second*60, false, time.Second*0, true,
+		storage.WithKeyCodec(storage.SafeKeyCodec{}),
+		storage.WithKeyHashing(storage.KeyHashing{MaxLength: 70}),
+	).Open()
+	assert.NoError(t, err)
+	assert.Equal(t, vals, maps.Collect(other.(storage.Iterable).Iterate(storage.IterateOptions{})))
+	assert.ElementsMatch(t, slices.Collect(maps.Keys(vals)), slices.Collect(other.(storage.Iterable).IterateKeys(storage.IterateOptions{})))
+
+	assert.True(t, other.RemoveItem(long))
+	assert.False(t, rs.Exists(keyKey))
+	_, err = sut.SetItem(long, 3)
+	assert.NoError(t, err)
+	//hashed keys are matched by their keys
+	assert.NoError(t, other.(storage.Clearable).ClearByPattern("x*"))
+	assert.False(t, rs.Exists(keyKey))
+	_, err = sut.SetItem(long, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, other.(storage.Flushable).Flush())
+	assert.False(t, rs.Exists(keyKey))
+}
+
+func TestValkeyAdapter_ClearMatchesMemory(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	vals := map[string]any{
+		"users/a b": 1,
+		"users/ab":  2,
+		"posts/a":   3,
+		long:        4,
+	}
+	opts := []storage.Option{
+		storage.WithKeyCodec(storage.SafeKeyCodec{}),
+		storage.WithKeyHashing(storage.KeyHashing{MaxLength: 70}),
+	}
+	clears := map[string]func(c storage.Clearable) error{
+		"pattern users/a?b": func(c storage.Clearable) error { return c.ClearByPattern("users/a?b") },
+		"pattern *b":        func(c storage.Clearable) error { return c.ClearByPattern("*b") },
+		"pattern x*":        func(c storage.Clearable) error { return c.ClearByPattern("x*") },
+		"prefix users/a ":   func(c storage.Clearable) error { return c.ClearByPrefix("users/a ") },
+		"prefix xx":         func(c storage.Clearable) error { return c.ClearByPrefix("xx") },
+	}
+	for name, clear := range clears {
+		t.Run(name, func(t *testing.T) {
+			rs := miniredis.RunT(t)
+			sut, err := valkey.New("ns:", rs.Addr(), time.Second*60, false, time.Second*0, false, opts...).Open()
+			assert.NoError(t, err)
+			mem := memory.New("ns:", time.Minute, time.Minute*2, opts...)
+			for _, s := range []storage.Storage{sut, mem} {
+				_, err = s.SetItems(vals)
+				assert.NoError(t, err)
+				assert.NoError(t, clear(s.(storage.Clearable)))
+			}
+			//the keys are matched after they are decoded by both adapters
+			want := slices.Collect(mem.(storage.Iterable).IterateKeys(storage.IterateOptions{}))
+			assert.Less(t, len(want), len(vals))
+			assert.ElementsMatch(t, want, slices.Collect(sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})))
+		})
+	}
+}
+
+func TestValkeyAdapter_ManagedTypeErrors(t *testing.T) {
+	rs := miniredis.RunT(t)
+	sut, err := valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, true).Open()
+	assert.NoError(t, err)
+	//the items are set, but their managed data types are not
+	rs.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if strings.EqualFold(cmd, "set") && len(args) > 0 && strings.HasPrefix(args[0], valkey.ManagedDataTypeCacheKeyPrefix) {
+			c.WriteError("ERR type store unavailable")
+			return true
+		}
+		return false
+	})
+	ok, err := sut.SetItem("foo", 1)
+	assert.True(t, ok)
+	assert.ErrorContains(t, err, "failed to set managed data type: type store unavailable")
+	_, err = sut.SetItems(map[string]any{"bar": 1})
+	assert.ErrorContains(t, err, "failed to set managed data types: type store unavailable")
+	assert.True(t, rs.Exists("foo"))
+	assert.True(t, rs.Exists("bar"))
+}
+
+func TestValkeyAdapter_NegativeCaching(t *testing.T) {
+	rs := miniRedis(t)
+	rs2 := miniredis.RunT(t)
+	chainedAdapter, err := valkey.New("one:", rs2.Addr(), time.Second*60, false, time.Second*0, false).Open()
+	assert.NoError(t, err)
+	sut, err := valkey.New("two:", rs.Addr(), time.Second*60, false, time.Second*0, true, storage.WithNegativeTTL(time.Second*10)).Open()
+	assert.NoError(t, err)
+	sut.(storage.Chainable).ChainAdapter(chainedAdapter)
+
+	_, err = sut.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	negKey := fmt.Sprintf(adapter.NegativeKeyTpl, "two:foo")
+	assert.True(t, rs.Exists(negKey))
+	assert.Equal(t, time.Second*10, rs.TTL(negKey))
+	//the miss is served by this adapter, without walking the chain
+	_, err = chainedAdapter.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = sut.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	_, err = sut.GetItems([]string{"foo"})
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.False(t, sut.HasItem("foo"))
+	//until the negative TTL expires
+	rs.FastForward(time.Second * 11)
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+
+	//setting the item forgets the miss
+	_, err = sut.GetItems([]string{"baz"})
+	assert.Error(t, err)
+	assert.True(t, rs.Exists(fmt.Sprintf(adapter.NegativeKeyTpl, "two:baz")))
+	_, err = sut.SetItems(map[string]any{"baz": 1})
+	assert.NoError(t, err)
+	assert.False(t, rs.Exists(fmt.Sprintf(adapter.NegativeKeyTpl, "two:baz")))
+	val, err = sut.GetItem("baz")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+
+	_, err = sut.GetItem("counter")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	_, err = sut.Increment("counter", 1)
+	assert.NoError(t, err)
+	assert.False(t, rs.Exists(fmt.Sprintf(adapter.NegativeKeyTpl, "two:counter")))
+
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.Empty(t, rs.Keys())
+}
+
+func TestValkeyAdapter_StaleWhileRevalidate(t *testing.T) {
+	rs := miniRedis(t)
+	loader := func(ctx context.Context, key string) (any, error) {
+		return "fresh:" + key, nil
+	}
+	sut, err := valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, false,
+		storage.WithSoftTTL(time.Second*10), storage.WithRefreshLoader(loader)).Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = sut.SetItems(map[string]any{"key1": "one", "key2": "two"})
+	assert.NoError(t, err)
+	softKey := fmt.Sprintf(adapter.SoftKeyTpl, "foo")
+	assert.True(t, rs.Exists(softKey))
+	assert.Equal(t, time.Second*10, rs.TTL(softKey))
+
+	//after the soft TTL the stale values are returned and refreshed
+	rs.FastForward(time.Second * 11)
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	vals, err := sut.GetItems([]string{"key1", "key2"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"key1": "one", "key2": "two"}, vals)
+	assert.Eventually(t, func() bool {
+		vals, err := sut.GetItems([]string{"foo", "key1", "key2"})
+		return err == nil && maps.Equal(vals, map[string]any{"foo": "fresh:foo", "key1": "fresh:key1", "key2": "fresh:key2"})
+	}, time.Second, time.Millisecond*10)
+	assert.True(t, rs.Exists(softKey))
+
+	//after the hard TTL the item is a miss
+	rs.FastForward(time.Second * 61)
+	_, err = sut.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.Empty(t, rs.Keys())
+}
+
+func TestValkeyAdapter_GetOrSetXFetch(t *testing.T) {
+	rs := miniRedis(t)
+	sut, err := valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, false, storage.WithXFetchBeta(1e12)).Open()
+	assert.NoError(t, err)
+	calls := 0
+	loader := func() (any, error) {
+		time.Sleep(time.Millisecond)
+		calls++
+		return strconv.Itoa(calls), nil
+	}
+
+	val, err := sut.(storage.ReadThrough).GetOrSet("foo", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", val)
+	deltaKey := fmt.Sprintf(adapter.DeltaKeyTpl, "foo")
+	assert.True(t, rs.Exists(deltaKey))
+	assert.Equal(t, time.Second*60, rs.TTL(deltaKey))
+	delta, err := rs.Get(deltaKey)
+	assert.NoError(t, err)
+	ns, err := strconv.ParseInt(delta, 10, 64)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Duration(ns), time.Millisecond)
+
+	//recomputed early
+	val, err = sut.(storage.ReadThrough).GetOrSet("foo", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", val)
+	//not without a delta
+	_, err = sut.SetItem("bar", "baz")
+	assert.NoError(t, err)
+	val, err = sut.(storage.ReadThrough).GetOrSet("bar", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", val)
+	assert.Equal(t, 2, calls)
+
+	assert.NoError(t, sut.(storage.Flushable).Flush())
+	assert.Empty(t, rs.Keys())
+}
@@ -0,0 +1,63 @@
+package adapter
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// DeltaKeyTpl formatting string for the key that holds the time a namespaced item key took to compute.
+// Adapters set it for storage.OptXFetchBeta when GetOrSet or GetOrSetItems loads the item, with the item's TTL
+const DeltaKeyTpl = MetaKeyPrefix + "delta:%s"
+
+// Delta is the time an item took to compute, and the time until it expires
+type Delta struct {
+	Compute time.Duration
+	TTL     time.Duration
+}
+
+// SetGetDeltasFunc sets the function that returns the Delta of the items, for the keys that have one.
+// An item without a TTL has a TTL of storage.NoExpiry
+func (a *AbstractAdapter) SetGetDeltasFunc(f func(ctx context.Context, keys []string) map[string]Delta) *AbstractAdapter {
+	a.getDeltas = f
+	return a
+}
+
+// SetSetDeltasFunc sets the function that stores the time the items took to compute
+func (a *AbstractAdapter) SetSetDeltasFunc(f func(ctx context.Context, deltas map[string]time.Duration)) *AbstractAdapter {
+	a.setDeltas = f
+	return a
+}
+
+// expiresEarly returns the keys whose items should be recomputed before they expire, with the XFetch algorithm.
+// An item is recomputed if delta * beta * -ln(rand) reaches its TTL, so the closer it is to expiring, and the longer
+// it took to compute, the more likely it is. Items without a delta or a TTL are not recomputed
+func (a *AbstractAdapter) expiresEarly(ctx context.Context, keys []string) []string {
+	beta := a.options.XFetchBeta()
+	if beta <= 0 || a.getDeltas == nil || len(keys) == 0 {
+		return nil
+	}
+	ret := make([]string, 0)
+	for key, d := range a.getDeltas(ctx, keys) {
+		if d.TTL <= 0 || d.Compute <= 0 {
+			continue
+		}
+		if -float64(d.Compute)*beta*math.Log(rand.Float64()) >= float64(d.TTL) {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+// storeDeltas stores the time the items took to compute, if XFetch is enabled
+func (a *AbstractAdapter) storeDeltas(ctx context.Context, keys []string, delta time.Duration) {
+	if a.options.XFetchBeta() <= 0 || a.setDeltas == nil || len(keys) == 0 {
+		return
+	}
+	deltas := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		deltas[key] = delta
+	}
+	a.setDeltas(ctx, deltas)
+}
@@ -0,0 +1,313 @@
+package breaker
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"iter"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenDuration     = time.Second * 10
+	DefaultHalfOpenProbes   = 1
+)
+
+// State is the state of a Breaker
+type State int
+
+const (
+	//Closed operations are called, and their failures counted
+	Closed State = iota
+	//Open operations fail fast, or are sent to the chained adapter
+	Open
+	//HalfOpen a limited number of operations are called as probes, to find out if the backend has recovered
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "halfOpen"
+	}
+	return "unknown"
+}
+
+// The events fired by a Breaker when its state changes. Attach a listener to "breaker.*" for all of them.
+// The event Target is the Breaker, and the Err of EventOpen is the failure that opened it
+const (
+	EventClosed   = "breaker.closed"
+	EventOpen     = "breaker.open"
+	EventHalfOpen = "breaker.halfOpen"
+)
+
+// Options configures a Breaker. Zero values use the defaults
+type Options struct {
+	//FailureThreshold is the number of consecutive failures that opens the breaker. Defaults to
+	//DefaultFailureThreshold
+	FailureThreshold int
+	//OpenDuration is how long the breaker stays open before it lets probes through. Defaults to DefaultOpenDuration
+	OpenDuration time.Duration
+	//HalfOpenProbes is the number of probes let through at a time when the breaker is half open, all of which must
+	//succeed to close it. Defaults to DefaultHalfOpenProbes
+	HalfOpenProbes int
+	//UseChained sends the operations to the adapter chained to the decorated storage while the breaker is open,
+	//rather than failing them. Operations fail if there is no chained adapter
+	UseChained bool
+	//IsFailure returns true if an error is a failure of the backend. Defaults to IsFailure
+	IsFailure func(err error) bool
+}
+
+// withDefaults returns the options with the defaults for the zero values
+func (o Options) withDefaults() Options {
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = DefaultFailureThreshold
+	}
+	if o.OpenDuration <= 0 {
+		o.OpenDuration = DefaultOpenDuration
+	}
+	if o.HalfOpenProbes <= 0 {
+		o.HalfOpenProbes = DefaultHalfOpenProbes
+	}
+	if o.IsFailure == nil {
+		o.IsFailure = IsFailure
+	}
+	return o
+}
+
+// IsFailure returns true for any error, except a cancelled context and the errors the storage returns for the
+// keys and values it is called with, such as errors.ErrKeyNotFound
+func IsFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, target := range []error{
+		context.Canceled,
+		errors.ErrKeyNotFound,
+		errors.ErrKeyInvalid,
+		errors.ErrNotReadable,
+		errors.ErrNotWritable,
+		errors.ErrUnsupportedDataType,
+		errors.ErrNotImplemented,
+		errors.ErrTypeConversion,
+		errors.ErrKeyTooLong,
+		errors.ErrValueTooLarge,
+		errors.ErrBackendUnavailable,
+	} {
+		if errs.Is(err, target) {
+			return false
+		}
+	}
+	return true
+}
+
+// Breaker decorates a storage.Storage with a circuit breaker, so that operations fail fast while its backend is
+// down, rather than each waiting for the backend to fail.
+//
+// The breaker opens after Options.FailureThreshold consecutive failures. The operations of an open breaker return
+// errors.ErrBackendUnavailable, or with Options.UseChained, are sent straight to the adapter chained to the
+// decorated storage. After Options.OpenDuration the breaker is half open, and lets Options.HalfOpenProbes operations
+// at a time through. It closes when they all succeed, and opens again if one fails. Iterations are lazy, so their
+// outcome is recorded when they finish, and they are not let through as probes, as an iteration that is never
+// consumed never finishes.
+//
+// Failures are the errors that operations return, and the errors that the decorated storage reports with
+// storage.ReportFailure, e.g. the Valkey adapter treats a failed read as a miss, but reports it. State changes fire
+// events to the listeners attached to the event manager, see GetEventManager:
+//
+//	b := breaker.New(valkeyAdapter, breaker.Options{UseChained: true})
+//	b.GetEventManager().Attach("breaker.*", func(e *event.Event) {
+//		slog.Warn("valkey circuit breaker", "state", e.Name, "error", e.Err)
+//	}, 0)
+type Breaker struct {
+	*decorator.Decorator
+	inner     storage.Storage
+	opts      Options
+	events    *event.Manager
+	mu        sync.Mutex
+	state     State
+	gen       int
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+// New returns s decorated with a circuit breaker
+func New(s storage.Storage, opts Options) *Breaker {
+	b := &Breaker{
+		inner:  s,
+		opts:   opts.withDefaults(),
+		events: event.NewManager(),
+	}
+	b.Decorator = decorator.New(s, b.intercept)
+	return b
+}
+
+// State returns the state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// GetEventManager returns the event manager that the state changes are fired to
+func (b *Breaker) GetEventManager() *event.Manager {
+	return b.events
+}
+
+// Open opens the decorated storage and returns the Breaker
+func (b *Breaker) Open() (storage.Storage, error) {
+	if _, err := b.inner.Open(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Close closes the decorated storage
+func (b *Breaker) Close() error {
+	return b.inner.Close()
+}
+
+// intercept calls the operation if the breaker allows it, and records its outcome
+func (b *Breaker) intercept(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+	gen, probe, ok := b.allow(ctx, op)
+	if !ok {
+		return b.reject(ctx, op, p)
+	}
+	//failures are only reported by the decorated storage, not by the adapters chained to it
+	backend := decorator.Unwrap(b.inner)
+	reported := atomic.Pointer[error]{}
+	ctx = storage.WithFailureReporter(ctx, func(s storage.Storage, err error) {
+		if s == backend && b.opts.IsFailure(err) {
+			reported.Store(&err)
+		}
+	})
+	return decorator.Observe(ctx, op, p, next, func(_ any, err error) {
+		var failure error
+		if b.opts.IsFailure(err) {
+			failure = err
+		} else if r := reported.Swap(nil); r != nil {
+			failure = *r
+		}
+		b.record(ctx, gen, probe, failure)
+	})
+}
+
+// allow returns true if an operation can be called, and if it is a probe, with the generation of the state.
+// Iterations are not probes
+func (b *Breaker) allow(ctx context.Context, op storage.Operation) (gen int, probe bool, ok bool) {
+	b.mu.Lock()
+	halfOpened := false
+	if b.state == Open && time.Since(b.openedAt) >= b.opts.OpenDuration {
+		b.transition(HalfOpen)
+		halfOpened = true
+	}
+	switch b.state {
+	case Closed:
+		ok = true
+	case HalfOpen:
+		if b.probes < b.opts.HalfOpenProbes && op != storage.OpIterate && op != storage.OpIterateKeys {
+			b.probes++
+			probe, ok = true, true
+		}
+	}
+	gen = b.gen
+	b.mu.Unlock()
+	if halfOpened {
+		b.fire(ctx, EventHalfOpen, nil)
+	}
+	return gen, probe, ok
+}
+
+// record counts the outcome of an operation, and changes the state if need be. Outcomes of operations allowed in
+// an earlier state are ignored
+func (b *Breaker) record(ctx context.Context, gen int, probe bool, failure error) {
+	b.mu.Lock()
+	name := ""
+	switch {
+	case gen != b.gen:
+	case probe && failure != nil:
+		b.transition(Open)
+		name = EventOpen
+	case probe:
+		b.probes--
+		b.successes++
+		if b.successes >= b.opts.HalfOpenProbes {
+			b.transition(Closed)
+			name = EventClosed
+		}
+	case failure != nil:
+		b.failures++
+		if b.failures >= b.opts.FailureThreshold {
+			b.transition(Open)
+			name = EventOpen
+		}
+	default:
+		b.failures = 0
+	}
+	b.mu.Unlock()
+	if name != "" {
+		b.fire(ctx, name, failure)
+	}
+}
+
+// transition changes the state and resets the counts. The caller must hold the lock
+func (b *Breaker) transition(state State) {
+	b.state = state
+	b.gen++
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == Open {
+		b.openedAt = time.Now()
+	}
+}
+
+// fire fires a state change event
+func (b *Breaker) fire(ctx context.Context, name string, err error) {
+	b.events.Trigger(&event.Event{
+		Name:   name,
+		Ctx:    ctx,
+		Target: b,
+		Params: &event.Params{},
+		Err:    err,
+	})
+}
+
+// reject sends the operation to the chained adapter, with Options.UseChained, or fails it
+func (b *Breaker) reject(ctx context.Context, op storage.Operation, p *event.Params) (any, error) {
+	//the loaders of read through operations are not params, so they cannot be sent to the chained adapter
+	if chained := b.GetChained(); b.opts.UseChained && chained != nil &&
+		op != storage.OpGetOrSet && op != storage.OpGetOrSetItems {
+		return decorator.Call(ctx, chained, op, p)
+	}
+	switch op {
+	case storage.OpGetItems, storage.OpGetOrSetItems:
+		return map[string]any{}, errors.ErrBackendUnavailable
+	case storage.OpHasItems:
+		return map[string]bool{}, errors.ErrBackendUnavailable
+	case storage.OpSetItems, storage.OpSetItemsWithTTL, storage.OpCheckAndSetItems, storage.OpTouchItems,
+		storage.OpRemoveItems, storage.OpGetTags:
+		return []string{}, errors.ErrBackendUnavailable
+	case storage.OpIterate:
+		return iter.Seq2[string, any](func(yield func(string, any) bool) {
+			p.IterateOptions.Error(errors.ErrBackendUnavailable)
+		}), errors.ErrBackendUnavailable
+	case storage.OpIterateKeys:
+		return iter.Seq[string](func(yield func(string) bool) {
+			p.IterateOptions.Error(errors.ErrBackendUnavailable)
+		}), errors.ErrBackendUnavailable
+	}
+	return nil, errors.ErrBackendUnavailable
+}
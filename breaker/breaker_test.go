@@ -0,0 +1,264 @@
+package breaker_test
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/adapter/valkey"
+	"github.com/chippyash/go-cache-manager/breaker"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	valkey2 "github.com/valkey-io/valkey-go"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failing returns s decorated so that its operations fail while down is true, and counts the calls made to it
+func failing(s storage.Storage, down *atomic.Bool, calls *atomic.Int32) storage.Storage {
+	return decorator.New(s, func(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+		calls.Add(1)
+		if down.Load() {
+			return nil, errs.New("connection refused")
+		}
+		return next(ctx)
+	})
+}
+
+// listen returns a function that returns the names of the events fired by the breaker
+func listen(b *breaker.Breaker) func() []string {
+	mu := sync.Mutex{}
+	names := make([]string, 0)
+	b.GetEventManager().Attach("breaker.*", func(e *event.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, e.Name)
+	}, 0)
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(names)
+	}
+}
+
+func TestBreaker_Opens(t *testing.T) {
+	down := atomic.Bool{}
+	calls := atomic.Int32{}
+	inner := memory.New("", time.Minute, time.Minute*2)
+	sut := breaker.New(failing(inner, &down, &calls), breaker.Options{FailureThreshold: 3, OpenDuration: time.Hour})
+	events := listen(sut)
+
+	//misses are not failures
+	for range 5 {
+		_, err := sut.GetItem("foo")
+		assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	}
+	assert.Equal(t, breaker.Closed, sut.State())
+
+	down.Store(true)
+	for range 2 {
+		_, err := sut.GetItem("foo")
+		assert.EqualError(t, err, "connection refused")
+	}
+	//a success resets the count
+	down.Store(false)
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	down.Store(true)
+	for range 3 {
+		_, err = sut.GetItem("foo")
+		assert.EqualError(t, err, "connection refused")
+	}
+	assert.Equal(t, breaker.Open, sut.State())
+	assert.Equal(t, []string{breaker.EventOpen}, events())
+
+	//fails fast
+	calls.Store(0)
+	_, err = sut.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrBackendUnavailable)
+	vals, err := sut.GetItems([]string{"foo"})
+	assert.ErrorIs(t, err, errors.ErrBackendUnavailable)
+	assert.Empty(t, vals)
+	assert.False(t, sut.HasItem("foo"))
+	keys, err := sut.SetItems(map[string]any{"foo": "baz"})
+	assert.ErrorIs(t, err, errors.ErrBackendUnavailable)
+	assert.Empty(t, keys)
+	var iterErr error
+	assert.Empty(t, slices.Collect(sut.IterateKeys(storage.IterateOptions{OnError: func(err error) { iterErr = err }})))
+	assert.ErrorIs(t, iterErr, errors.ErrBackendUnavailable)
+	assert.Equal(t, int32(0), calls.Load())
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	down := atomic.Bool{}
+	calls := atomic.Int32{}
+	inner := memory.New("", time.Minute, time.Minute*2)
+	sut := breaker.New(failing(inner, &down, &calls), breaker.Options{
+		FailureThreshold: 1,
+		OpenDuration:     time.Millisecond * 20,
+		HalfOpenProbes:   2,
+	})
+	events := listen(sut)
+
+	down.Store(true)
+	_, err := sut.SetItem("foo", "bar")
+	assert.Error(t, err)
+	assert.Equal(t, breaker.Open, sut.State())
+
+	//a failed probe opens the breaker again
+	time.Sleep(time.Millisecond * 30)
+	_, err = sut.SetItem("foo", "bar")
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, breaker.Open, sut.State())
+	_, err = sut.SetItem("foo", "bar")
+	assert.ErrorIs(t, err, errors.ErrBackendUnavailable)
+
+	//the probes must all succeed to close it
+	time.Sleep(time.Millisecond * 30)
+	down.Store(false)
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, breaker.HalfOpen, sut.State())
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	assert.Equal(t, breaker.Closed, sut.State())
+	assert.Equal(t, []string{
+		breaker.EventOpen, breaker.EventHalfOpen, breaker.EventOpen, breaker.EventHalfOpen, breaker.EventClosed,
+	}, events())
+}
+
+func TestBreaker_Iterations(t *testing.T) {
+	down := atomic.Bool{}
+	inner := memory.New("", time.Minute, time.Minute*2)
+	_, err := inner.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	//iterations fail when they are consumed, rather than when they are called
+	lazy := decorator.New(inner, func(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+		if op != storage.OpIterateKeys {
+			return next(ctx)
+		}
+		opts := p.IterateOptions
+		return iter.Seq[string](func(yield func(string) bool) {
+			if down.Load() {
+				opts.Error(errs.New("connection refused"))
+				return
+			}
+			for key := range inner.(storage.Iterable).IterateKeys(opts) {
+				if !yield(key) {
+					return
+				}
+			}
+		}), nil
+	})
+	sut := breaker.New(lazy, breaker.Options{FailureThreshold: 2, OpenDuration: time.Millisecond * 20})
+	var iterErr error
+	opts := storage.IterateOptions{OnError: func(err error) { iterErr = err }}
+
+	down.Store(true)
+	seqs := []iter.Seq[string]{sut.IterateKeys(opts), sut.IterateKeys(opts)}
+	assert.Equal(t, breaker.Closed, sut.State())
+	for _, seq := range seqs {
+		assert.Empty(t, slices.Collect(seq))
+	}
+	assert.EqualError(t, iterErr, "connection refused")
+	assert.Equal(t, breaker.Open, sut.State())
+
+	//iterations are not probes
+	time.Sleep(time.Millisecond * 30)
+	down.Store(false)
+	assert.Empty(t, slices.Collect(sut.IterateKeys(opts)))
+	assert.ErrorIs(t, iterErr, errors.ErrBackendUnavailable)
+	assert.Equal(t, breaker.HalfOpen, sut.State())
+	assert.True(t, sut.HasItem("foo"))
+	assert.Equal(t, breaker.Closed, sut.State())
+	assert.Equal(t, []string{"foo"}, slices.Collect(sut.IterateKeys(opts)))
+}
+
+func TestBreaker_UseChained(t *testing.T) {
+	down := atomic.Bool{}
+	calls := atomic.Int32{}
+	chained := memory.New("", time.Minute, time.Minute*2)
+	inner := memory.New("", time.Minute, time.Minute*2)
+	inner.(storage.Chainable).ChainAdapter(chained)
+	sut := breaker.New(failing(inner, &down, &calls), breaker.Options{FailureThreshold: 1, OpenDuration: time.Hour, UseChained: true})
+	_, err := chained.SetItems(map[string]any{"foo": "bar", "counter": int64(1)})
+	assert.NoError(t, err)
+
+	down.Store(true)
+	_, err = sut.GetItem("foo")
+	assert.Error(t, err)
+	assert.Equal(t, breaker.Open, sut.State())
+
+	//sent straight to the chained adapter
+	calls.Store(0)
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	_, err = sut.SetItem("baz", "qux")
+	assert.NoError(t, err)
+	assert.True(t, chained.HasItem("baz"))
+	n, err := sut.Increment("counter", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, int32(0), calls.Load())
+	//except read through operations
+	_, err = sut.GetOrSet("foo", func() (any, error) {
+		return "loaded", nil
+	})
+	assert.ErrorIs(t, err, errors.ErrBackendUnavailable)
+}
+
+func TestBreaker_ReportedFailures(t *testing.T) {
+	rs := miniredis.RunT(t)
+	chained := memory.New("", time.Minute, time.Minute*2)
+	//without retries, so that the reads fail straight away
+	inner := valkey.New("", rs.Addr(), time.Minute, false, 0, false, storage.WithOption(valkey.OptValkeyOptions,
+		valkey2.ClientOption{InitAddress: []string{rs.Addr()}, DisableCache: true, DisableRetry: true}))
+	inner.(storage.Chainable).ChainAdapter(chained)
+	sut, err := breaker.New(inner, breaker.Options{FailureThreshold: 2, OpenDuration: time.Hour, UseChained: true}).Open()
+	assert.NoError(t, err)
+	defer sut.Close()
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	//the valkey adapter treats a failed read as a miss and reads the chained adapter, but reports the failure
+	rs.Close()
+	for range 2 {
+		val, err := sut.GetItem("foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "bar", val)
+	}
+	assert.Equal(t, breaker.Open, sut.(*breaker.Breaker).State())
+	assert.False(t, sut.HasItem("none"))
+	assert.Equal(t, breaker.Open, sut.(*breaker.Breaker).State())
+
+	//failures of the chained adapter are not failures of the valkey adapter
+	rs2 := miniredis.RunT(t)
+	chained2 := valkey.New("", rs2.Addr(), time.Minute, false, 0, false, storage.WithOption(valkey.OptValkeyOptions,
+		valkey2.ClientOption{InitAddress: []string{rs2.Addr()}, DisableCache: true, DisableRetry: true}))
+	inner2 := memory.New("", time.Minute, time.Minute*2)
+	inner2.(storage.Chainable).ChainAdapter(chained2)
+	sut2 := breaker.New(inner2, breaker.Options{FailureThreshold: 1})
+	_, err = chained2.Open()
+	assert.NoError(t, err)
+	rs2.Close()
+	_, err = sut2.GetItem("foo")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.Equal(t, breaker.Closed, sut2.State())
+}
+
+func TestIsFailure(t *testing.T) {
+	assert.False(t, breaker.IsFailure(nil))
+	assert.False(t, breaker.IsFailure(errs.Wrap(errors.ErrKeyNotFound, "failed to get item")))
+	assert.False(t, breaker.IsFailure(context.Canceled))
+	assert.True(t, breaker.IsFailure(context.DeadlineExceeded))
+	assert.True(t, breaker.IsFailure(errs.New("connection refused")))
+}
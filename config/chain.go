@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+)
+
+// Chain is a cache built from a configuration. It is the top tier of the cache, with the tiers not yet opened
+// or chained. Call Open to get the cache
+type Chain struct {
+	storage.Storage
+	tiers []storage.Storage
+}
+
+// Tiers returns the tiers, from the top of the chain down
+func (c *Chain) Tiers() []storage.Storage {
+	return c.tiers
+}
+
+// Open opens the tiers from the bottom up, chaining each opened tier to the tier above it, and returns the opened
+// top tier
+func (c *Chain) Open() (storage.Storage, error) {
+	var below storage.Storage
+	for i := len(c.tiers) - 1; i >= 0; i-- {
+		tier := c.tiers[i]
+		if below != nil {
+			tier.(storage.Chainable).ChainAdapter(below)
+		}
+		s, err := tier.Open()
+		if err != nil {
+			return nil, errs.Wrap(err, fmt.Sprintf("failed to open tier %d", i))
+		}
+		below = s
+	}
+	return below, nil
+}
+
+// Close closes every tier, and returns the first error, if any
+func (c *Chain) Close() error {
+	var ret error
+	for _, tier := range c.tiers {
+		if err := tier.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
@@ -0,0 +1,176 @@
+// Package config builds cache topologies from a JSON document describing named caches and their tiers, e.g.
+//
+//	{
+//	  "caches": {
+//	    "users": {
+//	      "tiers": [
+//	        {"adapter": "memory", "options": {"namespace": "users:", "ttl": "1m"}},
+//	        {"adapter": "valkey", "options": {"host": "localhost:6379", "namespace": "users:", "ttl": "1h"}},
+//	        {"adapter": "s3", "options": {"bucket": "config", "namespace": "users/", "suffix": ".json"}}
+//	      ]
+//	    }
+//	  }
+//	}
+//
+// The first tier is the top of the chain, and each tier is chained to the one before it. The adapters are the ones
+// registered with storage.RegisterParams, or storage.Register, so import the adapter packages that are configured.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// Config describes named caches
+type Config struct {
+	Caches map[string]Cache `json:"caches"`
+}
+
+// Cache describes the tiers of a cache, from the top of the chain down
+type Cache struct {
+	Tiers []Tier `json:"tiers"`
+}
+
+// Tier describes an adapter in a cache chain
+type Tier struct {
+	//Adapter the adapter name, e.g. "memory", "valkey" or "s3"
+	Adapter string `json:"adapter"`
+	//Options the adapter options, keyed by name. An adapter that is only registered with storage.Register has a
+	//"dsn" option
+	Options map[string]any `json:"options"`
+}
+
+// Parse reads a JSON configuration. Unknown fields are an error
+func Parse(r io.Reader) (*Config, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	cfg := new(Config)
+	if err := dec.Decode(cfg); err != nil {
+		return nil, errs.Wrap(err, "failed to parse config")
+	}
+	return cfg, nil
+}
+
+// Load reads the JSON configuration file and builds its caches
+func Load(path string) (map[string]storage.Storage, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read config")
+	}
+	cfg, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Build()
+}
+
+// Validate returns an errors.ValidationErrors listing every invalid value in the configuration, if any
+func (c *Config) Validate() error {
+	var verrs errors.ValidationErrors
+	if len(c.Caches) == 0 {
+		verrs = append(verrs, &errors.ValidationError{Path: "caches", Message: "at least one cache is required"})
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Caches)) {
+		_, tierErrs := c.Caches[name].options(fmt.Sprintf("caches.%s", name))
+		verrs = append(verrs, tierErrs...)
+	}
+	if len(verrs) == 0 {
+		return nil
+	}
+	return verrs
+}
+
+// Build validates the configuration and builds its caches, keyed by name. The caches are ready to Open()
+func (c *Config) Build() (map[string]storage.Storage, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]storage.Storage, len(c.Caches))
+	for name, cache := range c.Caches {
+		s, err := cache.build(fmt.Sprintf("caches.%s", name))
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = s
+	}
+	return ret, nil
+}
+
+// options returns the validated options of each tier, and the errors for any invalid values
+func (c Cache) options(path string) ([]storage.Params, errors.ValidationErrors) {
+	var verrs errors.ValidationErrors
+	if len(c.Tiers) == 0 {
+		verrs = append(verrs, &errors.ValidationError{Path: path + ".tiers", Message: "at least one tier is required"})
+	}
+	ret := make([]storage.Params, len(c.Tiers))
+	for i, tier := range c.Tiers {
+		tierPath := fmt.Sprintf("%s.tiers[%d]", path, i)
+		a, ok := storage.LookupParams(tier.Adapter)
+		if !ok {
+			verrs = append(verrs, &errors.ValidationError{
+				Path:    tierPath + ".adapter",
+				Message: fmt.Sprintf("unknown adapter %q, forgotten import?", tier.Adapter),
+			})
+			continue
+		}
+		ret[i] = make(storage.Params, len(tier.Options))
+		for _, name := range slices.Sorted(maps.Keys(tier.Options)) {
+			optPath := fmt.Sprintf("%s.options.%s", tierPath, name)
+			k, ok := a.Kind(name)
+			if !ok {
+				verrs = append(verrs, &errors.ValidationError{Path: optPath, Message: "unknown option"})
+				continue
+			}
+			v, msg := k.Convert(tier.Options[name])
+			if msg != "" {
+				verrs = append(verrs, &errors.ValidationError{Path: optPath, Message: msg})
+				continue
+			}
+			ret[i][name] = v
+		}
+		for _, name := range a.Required {
+			if _, ok := tier.Options[name]; !ok {
+				verrs = append(verrs, &errors.ValidationError{
+					Path:    fmt.Sprintf("%s.options.%s", tierPath, name),
+					Message: "required",
+				})
+			}
+		}
+	}
+	return ret, verrs
+}
+
+// build creates the tiers and chains them
+func (c Cache) build(path string) (storage.Storage, error) {
+	opts, verrs := c.options(path)
+	if len(verrs) > 0 {
+		return nil, verrs
+	}
+	tiers := make([]storage.Storage, len(c.Tiers))
+	for i, tier := range c.Tiers {
+		a, _ := storage.LookupParams(tier.Adapter)
+		s, err := a.Create(opts[i])
+		if err != nil {
+			return nil, errs.Wrap(err, fmt.Sprintf("%s.tiers[%d]: failed to create %s adapter", path, i, tier.Adapter))
+		}
+		if i < len(c.Tiers)-1 {
+			if _, ok := s.(storage.Chainable); !ok {
+				return nil, &errors.ValidationError{
+					Path:    fmt.Sprintf("%s.tiers[%d].adapter", path, i),
+					Message: fmt.Sprintf("%s adapter cannot chain the tiers below it", tier.Adapter),
+				}
+			}
+		}
+		tiers[i] = s
+	}
+	return &Chain{Storage: tiers[0], tiers: tiers}, nil
+}
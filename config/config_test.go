@@ -0,0 +1,175 @@
+package config_test
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chippyash/go-cache-manager/adapter/bucket"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/adapter/valkey"
+	"github.com/chippyash/go-cache-manager/config"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	valkey2 "github.com/valkey-io/valkey-go"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Build(t *testing.T) {
+	rs := miniredis.RunT(t)
+	cfg, err := config.Parse(strings.NewReader(fmt.Sprintf(`{
+		"caches": {
+			"users": {
+				"tiers": [
+					{"adapter": "memory", "options": {"namespace": "users:", "ttl": "1m", "xfetchBeta": 1}},
+					{"adapter": "valkey", "options": {"host": %q, "namespace": "users:", "ttl": "1h", "db": 1, "manageTypes": true}}
+				]
+			},
+			"config": {
+				"tiers": [
+					{"adapter": "s3", "options": {"bucket": "config", "namespace": "folder/", "suffix": ".json", "region": "eu-west-2", "readable": false}}
+				]
+			}
+		}
+	}`, rs.Addr())))
+	assert.NoError(t, err)
+
+	caches, err := cfg.Build()
+	assert.NoError(t, err)
+	assert.Len(t, caches, 2)
+
+	users := caches["users"].(*config.Chain)
+	tiers := users.Tiers()
+	assert.Len(t, tiers, 2)
+	assert.Equal(t, time.Minute, tiers[0].GetOptions()[storage.OptTTL])
+	assert.Equal(t, time.Minute*2, tiers[0].GetOptions()[memory.OptPurgeTtl])
+	assert.Equal(t, 1.0, tiers[0].GetOptions()[storage.OptXFetchBeta])
+	assert.Equal(t, time.Hour, tiers[1].GetOptions()[storage.OptTTL])
+	assert.Equal(t, true, tiers[1].GetOptions()[valkey.OptManageTypes])
+	assert.Equal(t, 1, tiers[1].GetOptions()[valkey.OptValkeyOptions].(valkey2.ClientOption).SelectDB)
+
+	cache, err := users.Open()
+	assert.NoError(t, err)
+	defer users.Close()
+	assert.Same(t, tiers[0], cache)
+	assert.Same(t, tiers[1], cache.(storage.Chainable).GetChained())
+	_, err = cache.SetItem("foo", 1)
+	assert.NoError(t, err)
+	rs.Select(1)
+	assert.True(t, rs.Exists("users:foo"))
+
+	s3 := caches["config"].(*config.Chain).Tiers()[0]
+	assert.Equal(t, "config", s3.GetOptions()[bucket.OptS3Bucket])
+	assert.Equal(t, "folder/", s3.GetOptions()[storage.OptNamespace])
+	assert.Equal(t, bucket.MimeTypeJson, s3.GetOptions()[bucket.OptS3MimeType])
+	assert.Equal(t, false, s3.GetOptions()[storage.OptReadable])
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg, err := config.Parse(strings.NewReader(`{
+		"caches": {
+			"users": {
+				"tiers": [
+					{"adapter": "memory", "options": {"ttl": 60, "purgeTtl": "forever", "codec": "xml", "maxKeyLength": 1.5, "foo": 1}},
+					{"adapter": "valkey", "options": {"manageTypes": "yes"}},
+					{"adapter": "redis"}
+				]
+			},
+			"empty": {"tiers": []}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	err = cfg.Validate()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+	var verrs errors.ValidationErrors
+	assert.True(t, errs.As(err, &verrs))
+	paths := make([]string, 0, len(verrs))
+	for _, verr := range verrs {
+		paths = append(paths, verr.Path)
+	}
+	assert.Equal(t, []string{
+		"caches.empty.tiers",
+		"caches.users.tiers[0].options.codec",
+		"caches.users.tiers[0].options.foo",
+		"caches.users.tiers[0].options.maxKeyLength",
+		"caches.users.tiers[0].options.purgeTtl",
+		"caches.users.tiers[0].options.ttl",
+		"caches.users.tiers[1].options.manageTypes",
+		"caches.users.tiers[1].options.host",
+		"caches.users.tiers[2].adapter",
+	}, paths)
+	assert.Contains(t, err.Error(), `caches.users.tiers[0].options.ttl: expected a duration string, e.g. "5m", got number`)
+	assert.Contains(t, err.Error(), `caches.users.tiers[2].adapter: unknown adapter "redis"`)
+
+	_, err = cfg.Build()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+
+	_, err = config.Parse(strings.NewReader(`{"caches": {}, "extra": true}`))
+	assert.Error(t, err)
+	err = (&config.Config{}).Validate()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	err := os.WriteFile(path, []byte(`{"caches": {"local": {"tiers": [{"adapter": "memory", "options": {"ttl": "1m", "codec": "json"}}]}}}`), 0600)
+	assert.NoError(t, err)
+
+	caches, err := config.Load(path)
+	assert.NoError(t, err)
+	cache, err := caches["local"].Open()
+	assert.NoError(t, err)
+	assert.Equal(t, storage.JsonCodec{}, cache.GetOptions()[storage.OptCodec])
+
+	_, err = config.Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestConfig_RegisteredAdapters(t *testing.T) {
+	//an adapter registered with storage.RegisterParams is configured with its own options
+	storage.RegisterParams("test-memory", storage.ParamsFactory{
+		Params:   map[string]storage.Kind{"size": storage.KindInt},
+		Required: []string{"size"},
+		New: func(p storage.Params) (storage.Storage, error) {
+			return memory.New("", time.Duration(p.Int("size", 0))*time.Second, 0), nil
+		},
+	})
+	//an adapter only registered with storage.Register is configured with a DSN
+	storage.Register("test-dsn", func(dsn storage.DSN) (storage.Storage, error) {
+		ttl, err := dsn.DurationParam("ttl", 0)
+		if err != nil {
+			return nil, err
+		}
+		return memory.New(dsn.Param("namespace", ""), ttl, 0), nil
+	})
+
+	cfg := &config.Config{Caches: map[string]config.Cache{
+		"test": {Tiers: []config.Tier{
+			{Adapter: "test-memory", Options: map[string]any{"size": 5, "namespace": "test:"}},
+			{Adapter: "test-dsn", Options: map[string]any{"dsn": "test-dsn://?ttl=1m", "codec": "json"}},
+		}},
+	}}
+	caches, err := cfg.Build()
+	assert.NoError(t, err)
+	tiers := caches["test"].(*config.Chain).Tiers()
+	assert.Equal(t, time.Second*5, tiers[0].GetOptions()[storage.OptTTL])
+	assert.Equal(t, "test:", tiers[0].GetOptions()[storage.OptNamespace])
+	assert.Equal(t, time.Minute, tiers[1].GetOptions()[storage.OptTTL])
+	assert.Equal(t, storage.JsonCodec{}, tiers[1].GetOptions()[storage.OptCodec])
+
+	cfg = &config.Config{Caches: map[string]config.Cache{
+		"test": {Tiers: []config.Tier{{Adapter: "test-dsn", Options: map[string]any{"ttl": "1m"}}}},
+	}}
+	err = cfg.Validate()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+	assert.Contains(t, err.Error(), "caches.test.tiers[0].options.dsn: required")
+	assert.Contains(t, err.Error(), "caches.test.tiers[0].options.ttl: unknown option")
+	cfg.Caches["test"].Tiers[0].Options = map[string]any{"dsn": "memory://?ttl=1m"}
+	_, err = cfg.Build()
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+}
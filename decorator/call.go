@@ -0,0 +1,77 @@
+package decorator
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+)
+
+// passThrough calls every operation unchanged
+func passThrough(ctx context.Context, op storage.Operation, p *event.Params, next Next) (any, error) {
+	return next(ctx)
+}
+
+// Call calls the operation on s with the params, and returns its result as an Interceptor would, so that an
+// interceptor can send an operation to another storage than the one it decorates. storage.OpGetOrSet and
+// storage.OpGetOrSetItems cannot be called, as their loaders are not params, and return errors.ErrNotImplemented
+func Call(ctx context.Context, s storage.Storage, op storage.Operation, p *event.Params) (any, error) {
+	d := New(s, passThrough)
+	switch op {
+	case storage.OpGetItem:
+		return d.GetItemCtx(ctx, p.Key)
+	case storage.OpGetItems:
+		return d.GetItemsCtx(ctx, p.Keys)
+	case storage.OpHasItem:
+		return d.HasItemCtx(ctx, p.Key), nil
+	case storage.OpHasItems:
+		return d.HasItemsCtx(ctx, p.Keys), nil
+	case storage.OpSetItem:
+		return d.SetItemCtx(ctx, p.Key, p.Value)
+	case storage.OpSetItems:
+		return d.SetItemsCtx(ctx, p.Values)
+	case storage.OpCheckAndSetItem:
+		return d.CheckAndSetItemCtx(ctx, p.Key, p.Value)
+	case storage.OpCheckAndSetItems:
+		return d.CheckAndSetItemsCtx(ctx, p.Values)
+	case storage.OpTouchItem:
+		return d.TouchItemCtx(ctx, p.Key), nil
+	case storage.OpTouchItems:
+		return d.TouchItemsCtx(ctx, p.Keys), nil
+	case storage.OpRemoveItem:
+		return d.RemoveItemCtx(ctx, p.Key), nil
+	case storage.OpRemoveItems:
+		return d.RemoveItemsCtx(ctx, p.Keys), nil
+	case storage.OpIncrement:
+		return d.IncrementCtx(ctx, p.Key, p.N)
+	case storage.OpDecrement:
+		return d.DecrementCtx(ctx, p.Key, p.N)
+	case storage.OpSetItemWithTTL:
+		return d.SetItemWithTTLCtx(ctx, p.Key, p.Value, p.TTL)
+	case storage.OpSetItemsWithTTL:
+		return d.SetItemsWithTTLCtx(ctx, p.Values, p.TTL)
+	case storage.OpTouchItemWithTTL:
+		return d.TouchItemWithTTLCtx(ctx, p.Key, p.TTL), nil
+	case storage.OpGetTTL:
+		return d.GetTTLCtx(ctx, p.Key)
+	case storage.OpSetTags:
+		return d.SetTagsCtx(ctx, p.Key, p.Tags...)
+	case storage.OpGetTags:
+		return d.GetTagsCtx(ctx, p.Key)
+	case storage.OpClearByTags:
+		return d.ClearByTagsCtx(ctx, p.Tags, p.Disjunction)
+	case storage.OpFlush:
+		return nil, d.FlushCtx(ctx)
+	case storage.OpClearExpired:
+		return nil, d.ClearExpiredCtx(ctx)
+	case storage.OpClearByPrefix:
+		return nil, d.ClearByPrefixCtx(ctx, p.Prefix)
+	case storage.OpClearByPattern:
+		return nil, d.ClearByPatternCtx(ctx, p.Pattern)
+	case storage.OpIterate:
+		return d.IterateCtx(ctx, p.IterateOptions), nil
+	case storage.OpIterateKeys:
+		return d.IterateKeysCtx(ctx, p.IterateOptions), nil
+	}
+	return nil, errors.ErrNotImplemented
+}
@@ -0,0 +1,484 @@
+package decorator
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"iter"
+	"time"
+)
+
+// Next calls the decorated operation with the current params
+type Next func(ctx context.Context) (any, error)
+
+// Interceptor is called around every operation of a Decorator. It can change the params before calling next,
+// change the result or error that next returns, or not call next at all. The result must be of the type the
+// operation returns, e.g. []string for storage.OpRemoveItems. Operations that do not return an error, such as
+// storage.OpHasItem, ignore the returned error
+type Interceptor func(ctx context.Context, op storage.Operation, p *event.Params, next Next) (any, error)
+
+// Decorator wraps a storage.Storage and calls an Interceptor around each of its operations.
+// It implements the Storage, StorageContext, ReadThrough, Expirable, Taggable, Flushable, ClearExpiredable,
+// Clearable, Iterable, Chainable and Capable interfaces. Operations of an interface that the decorated storage does
+// not implement return errors.ErrNotImplemented
+type Decorator struct {
+	inner     storage.Storage
+	intercept Interceptor
+}
+
+// New returns s decorated with the interceptor
+func New(s storage.Storage, intercept Interceptor) *Decorator {
+	return &Decorator{
+		inner:     s,
+		intercept: intercept,
+	}
+}
+
+// Unwrap returns the decorated storage
+func (d *Decorator) Unwrap() storage.Storage {
+	return d.inner
+}
+
+// Unwrap returns s with any decorators removed
+func Unwrap(s storage.Storage) storage.Storage {
+	for {
+		d, ok := s.(interface{ Unwrap() storage.Storage })
+		if !ok {
+			return s
+		}
+		s = d.Unwrap()
+	}
+}
+
+// call calls fn for the operation through the interceptor
+func call[T any](ctx context.Context, d *Decorator, op storage.Operation, p event.Params, fn func(ctx context.Context, p *event.Params) (T, error)) (T, error) {
+	ret, err := d.intercept(ctx, op, &p, func(ctx context.Context) (any, error) {
+		return fn(ctx, &p)
+	})
+	r, _ := ret.(T)
+	return r, err
+}
+
+func (d *Decorator) ctx() storage.StorageContext {
+	return storage.WithContext(d.inner)
+}
+
+/** Storage Interface **/
+
+func (d *Decorator) SetOptions(opts storage.StorageOptions) {
+	d.inner.SetOptions(opts)
+}
+
+func (d *Decorator) GetOptions() storage.StorageOptions {
+	return d.inner.GetOptions()
+}
+
+func (d *Decorator) GetItem(key string) (any, error) {
+	return d.GetItemCtx(context.TODO(), key)
+}
+
+func (d *Decorator) GetItems(keys []string) (map[string]any, error) {
+	return d.GetItemsCtx(context.TODO(), keys)
+}
+
+func (d *Decorator) HasItem(key string) bool {
+	return d.HasItemCtx(context.TODO(), key)
+}
+
+func (d *Decorator) HasItems(keys []string) map[string]bool {
+	return d.HasItemsCtx(context.TODO(), keys)
+}
+
+func (d *Decorator) SetItem(key string, value any) (bool, error) {
+	return d.SetItemCtx(context.TODO(), key, value)
+}
+
+func (d *Decorator) SetItems(values map[string]any) ([]string, error) {
+	return d.SetItemsCtx(context.TODO(), values)
+}
+
+func (d *Decorator) CheckAndSetItem(key string, value any) (bool, error) {
+	return d.CheckAndSetItemCtx(context.TODO(), key, value)
+}
+
+func (d *Decorator) CheckAndSetItems(values map[string]any) ([]string, error) {
+	return d.CheckAndSetItemsCtx(context.TODO(), values)
+}
+
+func (d *Decorator) TouchItem(key string) bool {
+	return d.TouchItemCtx(context.TODO(), key)
+}
+
+func (d *Decorator) TouchItems(keys []string) []string {
+	return d.TouchItemsCtx(context.TODO(), keys)
+}
+
+func (d *Decorator) RemoveItem(key string) bool {
+	return d.RemoveItemCtx(context.TODO(), key)
+}
+
+func (d *Decorator) RemoveItems(keys []string) []string {
+	return d.RemoveItemsCtx(context.TODO(), keys)
+}
+
+func (d *Decorator) Increment(key string, n int64) (int64, error) {
+	return d.IncrementCtx(context.TODO(), key, n)
+}
+
+func (d *Decorator) Decrement(key string, n int64) (int64, error) {
+	return d.DecrementCtx(context.TODO(), key, n)
+}
+
+// Open opens the decorated storage and returns the decorator
+func (d *Decorator) Open() (storage.Storage, error) {
+	if _, err := d.inner.Open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Decorator) Close() error {
+	return d.inner.Close()
+}
+
+/** StorageContext Interface **/
+
+func (d *Decorator) GetItemCtx(ctx context.Context, key string) (any, error) {
+	return call(ctx, d, storage.OpGetItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (any, error) {
+		return d.ctx().GetItemCtx(ctx, p.Key)
+	})
+}
+
+func (d *Decorator) GetItemsCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	return call(ctx, d, storage.OpGetItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) (map[string]any, error) {
+		return d.ctx().GetItemsCtx(ctx, p.Keys)
+	})
+}
+
+func (d *Decorator) HasItemCtx(ctx context.Context, key string) bool {
+	ret, _ := call(ctx, d, storage.OpHasItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return d.ctx().HasItemCtx(ctx, p.Key), nil
+	})
+	return ret
+}
+
+func (d *Decorator) HasItemsCtx(ctx context.Context, keys []string) map[string]bool {
+	ret, _ := call(ctx, d, storage.OpHasItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) (map[string]bool, error) {
+		return d.ctx().HasItemsCtx(ctx, p.Keys), nil
+	})
+	return ret
+}
+
+func (d *Decorator) SetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	return call(ctx, d, storage.OpSetItem, event.Params{Key: key, Value: value}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return d.ctx().SetItemCtx(ctx, p.Key, p.Value)
+	})
+}
+
+func (d *Decorator) SetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	return call(ctx, d, storage.OpSetItems, event.Params{Values: values}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return d.ctx().SetItemsCtx(ctx, p.Values)
+	})
+}
+
+func (d *Decorator) CheckAndSetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	return call(ctx, d, storage.OpCheckAndSetItem, event.Params{Key: key, Value: value}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return d.ctx().CheckAndSetItemCtx(ctx, p.Key, p.Value)
+	})
+}
+
+func (d *Decorator) CheckAndSetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	return call(ctx, d, storage.OpCheckAndSetItems, event.Params{Values: values}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return d.ctx().CheckAndSetItemsCtx(ctx, p.Values)
+	})
+}
+
+func (d *Decorator) TouchItemCtx(ctx context.Context, key string) bool {
+	ret, _ := call(ctx, d, storage.OpTouchItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return d.ctx().TouchItemCtx(ctx, p.Key), nil
+	})
+	return ret
+}
+
+func (d *Decorator) TouchItemsCtx(ctx context.Context, keys []string) []string {
+	ret, _ := call(ctx, d, storage.OpTouchItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return d.ctx().TouchItemsCtx(ctx, p.Keys), nil
+	})
+	return ret
+}
+
+func (d *Decorator) RemoveItemCtx(ctx context.Context, key string) bool {
+	ret, _ := call(ctx, d, storage.OpRemoveItem, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return d.ctx().RemoveItemCtx(ctx, p.Key), nil
+	})
+	return ret
+}
+
+func (d *Decorator) RemoveItemsCtx(ctx context.Context, keys []string) []string {
+	ret, _ := call(ctx, d, storage.OpRemoveItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return d.ctx().RemoveItemsCtx(ctx, p.Keys), nil
+	})
+	return ret
+}
+
+func (d *Decorator) IncrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	return call(ctx, d, storage.OpIncrement, event.Params{Key: key, N: n}, func(ctx context.Context, p *event.Params) (int64, error) {
+		return d.ctx().IncrementCtx(ctx, p.Key, p.N)
+	})
+}
+
+func (d *Decorator) DecrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	return call(ctx, d, storage.OpDecrement, event.Params{Key: key, N: n}, func(ctx context.Context, p *event.Params) (int64, error) {
+		return d.ctx().DecrementCtx(ctx, p.Key, p.N)
+	})
+}
+
+/** ReadThrough Interface **/
+
+// GetOrSet calls GetOrSet on the decorated storage. The gets and sets it makes are not intercepted
+func (d *Decorator) GetOrSet(key string, loader func() (any, error)) (any, error) {
+	return call(context.TODO(), d, storage.OpGetOrSet, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (any, error) {
+		rt, ok := d.inner.(storage.ReadThrough)
+		if !ok {
+			return nil, errors.ErrNotImplemented
+		}
+		return rt.GetOrSet(p.Key, loader)
+	})
+}
+
+// GetOrSetItems calls GetOrSetItems on the decorated storage. The gets and sets it makes are not intercepted
+func (d *Decorator) GetOrSetItems(keys []string, loader func(keys []string) (map[string]any, error)) (map[string]any, error) {
+	return call(context.TODO(), d, storage.OpGetOrSetItems, event.Params{Keys: keys}, func(ctx context.Context, p *event.Params) (map[string]any, error) {
+		rt, ok := d.inner.(storage.ReadThrough)
+		if !ok {
+			return map[string]any{}, errors.ErrNotImplemented
+		}
+		return rt.GetOrSetItems(p.Keys, loader)
+	})
+}
+
+/** Expirable Interface **/
+
+func (d *Decorator) SetItemWithTTL(key string, value any, ttl time.Duration) (bool, error) {
+	return d.SetItemWithTTLCtx(context.TODO(), key, value, ttl)
+}
+
+func (d *Decorator) SetItemsWithTTL(values map[string]any, ttl time.Duration) ([]string, error) {
+	return d.SetItemsWithTTLCtx(context.TODO(), values, ttl)
+}
+
+func (d *Decorator) TouchItemWithTTL(key string, ttl time.Duration) bool {
+	return d.TouchItemWithTTLCtx(context.TODO(), key, ttl)
+}
+
+func (d *Decorator) GetTTL(key string) (time.Duration, error) {
+	return d.GetTTLCtx(context.TODO(), key)
+}
+
+func (d *Decorator) SetItemWithTTLCtx(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+	return call(ctx, d, storage.OpSetItemWithTTL, event.Params{Key: key, Value: value, TTL: ttl}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return storage.SetItemWithTTL(ctx, d.inner, p.Key, p.Value, p.TTL)
+	})
+}
+
+func (d *Decorator) SetItemsWithTTLCtx(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error) {
+	return call(ctx, d, storage.OpSetItemsWithTTL, event.Params{Values: values, TTL: ttl}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		return storage.SetItemsWithTTL(ctx, d.inner, p.Values, p.TTL)
+	})
+}
+
+func (d *Decorator) TouchItemWithTTLCtx(ctx context.Context, key string, ttl time.Duration) bool {
+	ret, _ := call(ctx, d, storage.OpTouchItemWithTTL, event.Params{Key: key, TTL: ttl}, func(ctx context.Context, p *event.Params) (bool, error) {
+		return storage.TouchItemWithTTL(ctx, d.inner, p.Key, p.TTL), nil
+	})
+	return ret
+}
+
+func (d *Decorator) GetTTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	return call(ctx, d, storage.OpGetTTL, event.Params{Key: key}, func(ctx context.Context, p *event.Params) (time.Duration, error) {
+		e, ok := d.inner.(storage.Expirable)
+		if !ok {
+			return 0, errors.ErrNotImplemented
+		}
+		return e.GetTTLCtx(ctx, p.Key)
+	})
+}
+
+/** Taggable Interface **/
+
+func (d *Decorator) SetTags(key string, tags ...string) (bool, error) {
+	return d.SetTagsCtx(context.TODO(), key, tags...)
+}
+
+func (d *Decorator) GetTags(key string) ([]string, error) {
+	return d.GetTagsCtx(context.TODO(), key)
+}
+
+func (d *Decorator) ClearByTags(tags []string, disjunction bool) (bool, error) {
+	return d.ClearByTagsCtx(context.TODO(), tags, disjunction)
+}
+
+func (d *Decorator) SetTagsCtx(ctx context.Context, key string, tags ...string) (bool, error) {
+	return call(ctx, d, storage.OpSetTags, event.Params{Key: key, Tags: tags}, func(ctx context.Context, p *event.Params) (bool, error) {
+		t, ok := d.inner.(storage.Taggable)
+		if !ok {
+			return false, errors.ErrNotImplemented
+		}
+		return t.SetTagsCtx(ctx, p.Key, p.Tags...)
+	})
+}
+
+func (d *Decorator) GetTagsCtx(ctx context.Context, key string) ([]string, error) {
+	return call(ctx, d, storage.OpGetTags, event.Params{Key: key}, func(ctx context.Context, p *event.Params) ([]string, error) {
+		t, ok := d.inner.(storage.Taggable)
+		if !ok {
+			return []string{}, errors.ErrNotImplemented
+		}
+		return t.GetTagsCtx(ctx, p.Key)
+	})
+}
+
+func (d *Decorator) ClearByTagsCtx(ctx context.Context, tags []string, disjunction bool) (bool, error) {
+	return call(ctx, d, storage.OpClearByTags, event.Params{Tags: tags, Disjunction: disjunction}, func(ctx context.Context, p *event.Params) (bool, error) {
+		t, ok := d.inner.(storage.Taggable)
+		if !ok {
+			return false, errors.ErrNotImplemented
+		}
+		return t.ClearByTagsCtx(ctx, p.Tags, p.Disjunction)
+	})
+}
+
+/** Flushable and ClearExpiredable Interfaces **/
+
+func (d *Decorator) Flush() error {
+	return d.FlushCtx(context.TODO())
+}
+
+func (d *Decorator) ClearExpired() error {
+	return d.ClearExpiredCtx(context.TODO())
+}
+
+func (d *Decorator) FlushCtx(ctx context.Context) error {
+	_, err := call(ctx, d, storage.OpFlush, event.Params{}, func(ctx context.Context, p *event.Params) (any, error) {
+		f, ok := d.inner.(storage.Flushable)
+		if !ok {
+			return nil, errors.ErrNotImplemented
+		}
+		return nil, f.FlushCtx(ctx)
+	})
+	return err
+}
+
+func (d *Decorator) ClearExpiredCtx(ctx context.Context) error {
+	_, err := call(ctx, d, storage.OpClearExpired, event.Params{}, func(ctx context.Context, p *event.Params) (any, error) {
+		c, ok := d.inner.(storage.ClearExpiredable)
+		if !ok {
+			return nil, errors.ErrNotImplemented
+		}
+		return nil, c.ClearExpiredCtx(ctx)
+	})
+	return err
+}
+
+/** Clearable Interface **/
+
+func (d *Decorator) ClearByPrefix(prefix string) error {
+	return d.ClearByPrefixCtx(context.TODO(), prefix)
+}
+
+func (d *Decorator) ClearByPattern(pattern string) error {
+	return d.ClearByPatternCtx(context.TODO(), pattern)
+}
+
+func (d *Decorator) ClearByPrefixCtx(ctx context.Context, prefix string) error {
+	_, err := call(ctx, d, storage.OpClearByPrefix, event.Params{Prefix: prefix}, func(ctx context.Context, p *event.Params) (any, error) {
+		c, ok := d.inner.(storage.Clearable)
+		if !ok {
+			return nil, errors.ErrNotImplemented
+		}
+		return nil, c.ClearByPrefixCtx(ctx, p.Prefix)
+	})
+	return err
+}
+
+func (d *Decorator) ClearByPatternCtx(ctx context.Context, pattern string) error {
+	_, err := call(ctx, d, storage.OpClearByPattern, event.Params{Pattern: pattern}, func(ctx context.Context, p *event.Params) (any, error) {
+		c, ok := d.inner.(storage.Clearable)
+		if !ok {
+			return nil, errors.ErrNotImplemented
+		}
+		return nil, c.ClearByPatternCtx(ctx, p.Pattern)
+	})
+	return err
+}
+
+/** Iterable Interface **/
+
+func (d *Decorator) Iterate(opts storage.IterateOptions) iter.Seq2[string, any] {
+	return d.IterateCtx(context.TODO(), opts)
+}
+
+func (d *Decorator) IterateKeys(opts storage.IterateOptions) iter.Seq[string] {
+	return d.IterateKeysCtx(context.TODO(), opts)
+}
+
+func (d *Decorator) IterateCtx(ctx context.Context, opts storage.IterateOptions) iter.Seq2[string, any] {
+	ret, _ := call(ctx, d, storage.OpIterate, event.Params{IterateOptions: opts}, func(ctx context.Context, p *event.Params) (iter.Seq2[string, any], error) {
+		i, ok := d.inner.(storage.Iterable)
+		if !ok {
+			return func(yield func(string, any) bool) {
+				p.IterateOptions.Error(errors.ErrNotImplemented)
+			}, nil
+		}
+		return i.IterateCtx(ctx, p.IterateOptions), nil
+	})
+	if ret == nil {
+		return func(yield func(string, any) bool) {}
+	}
+	return ret
+}
+
+func (d *Decorator) IterateKeysCtx(ctx context.Context, opts storage.IterateOptions) iter.Seq[string] {
+	ret, _ := call(ctx, d, storage.OpIterateKeys, event.Params{IterateOptions: opts}, func(ctx context.Context, p *event.Params) (iter.Seq[string], error) {
+		i, ok := d.inner.(storage.Iterable)
+		if !ok {
+			return func(yield func(string) bool) {
+				p.IterateOptions.Error(errors.ErrNotImplemented)
+			}, nil
+		}
+		return i.IterateKeysCtx(ctx, p.IterateOptions), nil
+	})
+	if ret == nil {
+		return func(yield func(string) bool) {}
+	}
+	return ret
+}
+
+/** Chainable Interface **/
+
+// ChainAdapter chains the adapter to the decorated storage if it is Chainable
+func (d *Decorator) ChainAdapter(adapter storage.Storage) storage.Storage {
+	if c, ok := d.inner.(storage.Chainable); ok {
+		c.ChainAdapter(adapter)
+	}
+	return d
+}
+
+// GetChained returns the adapter chained to the decorated storage, if any
+func (d *Decorator) GetChained() storage.Storage {
+	if c, ok := d.inner.(storage.Chainable); ok {
+		return c.GetChained()
+	}
+	return nil
+}
+
+/** Capable Interface **/
+
+// Capabilities returns the capabilities of the decorated storage, or none if it is not Capable
+func (d *Decorator) Capabilities() storage.Capabilities {
+	if c, ok := d.inner.(storage.Capable); ok {
+		return c.Capabilities()
+	}
+	return storage.Capabilities{}
+}
@@ -0,0 +1,101 @@
+package decorator_test
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDecorator_Intercept(t *testing.T) {
+	inner := memory.New("", time.Second*60, time.Second*120)
+	ops := make([]storage.Operation, 0)
+	sut := decorator.New(inner, func(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+		ops = append(ops, op)
+		if op == storage.OpSetItem {
+			p.Key = "prefixed:" + p.Key
+		}
+		return next(ctx)
+	})
+
+	ok, err := sut.SetItem("foo", "bar")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.True(t, inner.HasItem("prefixed:foo"))
+	val, err := sut.GetItem("prefixed:foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+	assert.False(t, sut.HasItem("foo"))
+	ok, err = sut.SetItemWithTTL("baz", "qux", time.Second)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ttl, err := sut.GetTTL("baz")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second)
+	keys := slices.Sorted(sut.IterateKeys(storage.IterateOptions{}))
+	assert.Equal(t, []string{"baz", "prefixed:foo"}, keys)
+	assert.NoError(t, sut.Flush())
+
+	assert.Equal(t, []storage.Operation{
+		storage.OpSetItem, storage.OpGetItem, storage.OpHasItem, storage.OpSetItemWithTTL, storage.OpGetTTL,
+		storage.OpIterateKeys, storage.OpFlush,
+	}, ops)
+	assert.Same(t, inner, sut.Unwrap())
+	assert.Same(t, inner, decorator.Unwrap(decorator.New(sut, nil)))
+}
+
+func TestDecorator_ShortCircuit(t *testing.T) {
+	inner := memory.New("", time.Second*60, time.Second*120)
+	sut := decorator.New(inner, func(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+		return nil, errors.ErrNotReadable
+	})
+
+	val, err := sut.GetItem("foo")
+	assert.Nil(t, val)
+	assert.ErrorIs(t, err, errors.ErrNotReadable)
+	assert.False(t, sut.HasItem("foo"))
+	assert.Empty(t, slices.Collect(sut.IterateKeys(storage.IterateOptions{})))
+}
+
+func TestDecorator_Chain(t *testing.T) {
+	inner := memory.New("", time.Second*60, time.Second*120)
+	chained := memory.New("", time.Second*60, time.Second*120)
+	sut := decorator.New(inner, func(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+		return next(ctx)
+	})
+	sut.ChainAdapter(chained)
+	assert.Same(t, chained, sut.GetChained())
+	assert.Same(t, chained, inner.(storage.Chainable).GetChained())
+	assert.True(t, sut.Capabilities().Supports(storage.OpIncrement))
+}
+
+func TestCall(t *testing.T) {
+	sut := memory.New("", time.Second*60, time.Second*120)
+
+	ret, err := decorator.Call(context.Background(), sut, storage.OpSetItem, &event.Params{Key: "foo", Value: "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret)
+	ret, err = decorator.Call(context.Background(), sut, storage.OpGetItems, &event.Params{Keys: []string{"foo"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": "bar"}, ret)
+	ret, err = decorator.Call(context.Background(), sut, storage.OpSetItemWithTTL, &event.Params{Key: "baz", Value: 1, TTL: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret)
+	ret, err = decorator.Call(context.Background(), sut, storage.OpIterateKeys, &event.Params{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo", "baz"}, slices.Collect(ret.(iter.Seq[string])))
+	_, err = decorator.Call(context.Background(), sut, storage.OpFlush, &event.Params{})
+	assert.NoError(t, err)
+	ret, _ = decorator.Call(context.Background(), sut, storage.OpHasItem, &event.Params{Key: "foo"})
+	assert.Equal(t, false, ret)
+
+	_, err = decorator.Call(context.Background(), sut, storage.OpGetOrSet, &event.Params{Key: "foo"})
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+}
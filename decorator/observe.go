@@ -0,0 +1,60 @@
+package decorator
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"iter"
+	"sync"
+)
+
+// Observe calls next, and calls done with the result and error of the operation once it is complete, for interceptors
+// that record outcomes. The iterators returned by storage.OpIterate and storage.OpIterateKeys are lazy, so their outcome is
+// the first error passed to IterateOptions.OnError, and done is called each time an iteration finishes
+func Observe(ctx context.Context, op storage.Operation, p *event.Params, next Next, done func(ret any, err error)) (any, error) {
+	if op != storage.OpIterate && op != storage.OpIterateKeys {
+		ret, err := next(ctx)
+		done(ret, err)
+		return ret, err
+	}
+	var mu sync.Mutex
+	var iterErr error
+	onError := p.IterateOptions.OnError
+	p.IterateOptions.OnError = func(err error) {
+		mu.Lock()
+		if iterErr == nil {
+			iterErr = err
+		}
+		mu.Unlock()
+		if onError != nil {
+			onError(err)
+		}
+	}
+	//finish calls done with the outcome of an iteration
+	finish := func(ret any) {
+		mu.Lock()
+		err := iterErr
+		iterErr = nil
+		mu.Unlock()
+		done(ret, err)
+	}
+	ret, err := next(ctx)
+	if err != nil {
+		done(ret, err)
+		return ret, err
+	}
+	switch seq := ret.(type) {
+	case iter.Seq2[string, any]:
+		return iter.Seq2[string, any](func(yield func(string, any) bool) {
+			seq(yield)
+			finish(seq)
+		}), nil
+	case iter.Seq[string]:
+		return iter.Seq[string](func(yield func(string) bool) {
+			seq(yield)
+			finish(seq)
+		}), nil
+	}
+	done(ret, nil)
+	return ret, nil
+}
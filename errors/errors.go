@@ -1,10 +1,111 @@
 package errors
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
 
 var ErrKeyNotFound  = errors.New("key not found")
 var ErrKeyInvalid = errors.New("key invalid")
 var ErrNotReadable = errors.New("not readable")
 var ErrNotWritable = errors.New("not writable")
 var ErrUnsupportedDataType = errors.New("unsupported data type")
-var ErrNotImplemented = errors.New("not implemented")
\ No newline at end of file
+var ErrNotImplemented = errors.New("not implemented")
+var ErrTypeConversion = errors.New("type conversion failed")
+var ErrUnknownAdapter = errors.New("unknown adapter")
+var ErrInvalidDSN = errors.New("invalid dsn")
+var ErrValidation = errors.New("validation failed")
+var ErrKeyTooLong = errors.New("key too long")
+var ErrValueTooLarge = errors.New("value too large")
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
+// TypeConversionError is returned when a stored value cannot be converted to the requested type.
+// It matches ErrTypeConversion when tested with errors.Is
+type TypeConversionError struct {
+	Key   string
+	Value any
+	Type  string
+	Err   error
+}
+
+func (e *TypeConversionError) Error() string {
+	msg := fmt.Sprintf("%s: key: %s, value type: %T, required type: %s", ErrTypeConversion.Error(), e.Key, e.Value, e.Type)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *TypeConversionError) Is(target error) bool {
+	return target == ErrTypeConversion
+}
+
+func (e *TypeConversionError) Unwrap() error {
+	return e.Err
+}
+
+// KeyTooLongError is returned when a key is longer than the adapter's OptMaxKeyLength.
+// Length is the length of the key sent to the backend, including any namespace.
+// It matches ErrKeyTooLong when tested with errors.Is
+type KeyTooLongError struct {
+	Key    string
+	Length int
+	Max    int
+}
+
+func (e *KeyTooLongError) Error() string {
+	return fmt.Sprintf("%s: key: %s, length: %d, max: %d", ErrKeyTooLong.Error(), e.Key, e.Length, e.Max)
+}
+
+func (e *KeyTooLongError) Is(target error) bool {
+	return target == ErrKeyTooLong
+}
+
+// ValueTooLargeError is returned when a value is larger than the adapter's OptMaxValueLength.
+// Size is the size of the value sent to the backend, after encoding.
+// It matches ErrValueTooLarge when tested with errors.Is
+type ValueTooLargeError struct {
+	Key  string
+	Size int
+	Max  int
+}
+
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("%s: key: %s, size: %d, max: %d", ErrValueTooLarge.Error(), e.Key, e.Size, e.Max)
+}
+
+func (e *ValueTooLargeError) Is(target error) bool {
+	return target == ErrValueTooLarge
+}
+
+// ValidationError is returned when a configuration value is invalid. Path locates the value, e.g.
+// "caches.users.tiers[0].options.ttl". It matches ErrValidation when tested with errors.Is
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ValidationErrors is returned when one or more configuration values are invalid.
+// It matches ErrValidation when tested with errors.Is
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation.Error(), strings.Join(msgs, "; "))
+}
+
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
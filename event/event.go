@@ -0,0 +1,111 @@
+package event
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/storage"
+	"strings"
+	"time"
+)
+
+const (
+	//Pre the phase before an operation is called. A listener can change the Params, or short-circuit the operation
+	//by setting the Result and Err and calling StopPropagation
+	Pre = "pre"
+	//Post the phase after an operation succeeds. A listener can change the Result
+	Post = "post"
+	//Exception the phase after an operation returns an error. A listener can change the Result, or suppress the
+	//error by setting Err to nil
+	Exception = "exception"
+)
+
+// Name returns the event name for the phase and operation, e.g. Name(Pre, storage.OpGetItem) is "pre.getItem"
+func Name(phase string, op storage.Operation) string {
+	return phase + "." + string(op)
+}
+
+// Params are the arguments of an operation. Only those used by the operation are set
+type Params struct {
+	Key            string
+	Keys           []string
+	Value          any
+	Values         map[string]any
+	TTL            time.Duration
+	N              int64
+	Tags           []string
+	Disjunction    bool
+	Prefix         string
+	Pattern        string
+	IterateOptions storage.IterateOptions
+}
+
+// Event is passed to the listeners of an operation
+type Event struct {
+	//Name the event name, e.g. "pre.getItem"
+	Name string
+	//Ctx the operation context. A pre listener can replace it
+	Ctx context.Context
+	//Target the adapter firing the event
+	Target storage.Storage
+	//Params the operation arguments
+	Params *Params
+	//Result the operation result. It must be of the operation's return type, e.g. []string for removeItems
+	Result any
+	//Err the operation error
+	Err     error
+	stopped bool
+}
+
+// Phase returns the event phase, e.g. Pre
+func (e *Event) Phase() string {
+	phase, _, _ := strings.Cut(e.Name, ".")
+	return phase
+}
+
+// Operation returns the event operation, e.g. storage.OpGetItem
+func (e *Event) Operation() storage.Operation {
+	_, op, _ := strings.Cut(e.Name, ".")
+	return storage.Operation(op)
+}
+
+// StopPropagation stops the remaining listeners being called. For a Pre event it also stops the operation being
+// called, and the Result and Err are returned instead
+func (e *Event) StopPropagation() {
+	e.stopped = true
+}
+
+// PropagationStopped returns true if a listener called StopPropagation
+func (e *Event) PropagationStopped() bool {
+	return e.stopped
+}
+
+// Hits returns the number of items found and not found by a read operation, from its params and outcome.
+// Returns false if the operation is not a read operation, i.e. is not storage.OpGetItem, storage.OpGetItems,
+// storage.OpHasItem or storage.OpHasItems
+func Hits(op storage.Operation, p *Params, ret any, err error) (hits, misses int, ok bool) {
+	switch op {
+	case storage.OpGetItem:
+		if err == nil {
+			return 1, 0, true
+		}
+		return 0, 1, true
+	case storage.OpGetItems:
+		found, _ := ret.(map[string]any)
+		return len(found), max(len(p.Keys)-len(found), 0), true
+	case storage.OpHasItem:
+		if found, _ := ret.(bool); found {
+			return 1, 0, true
+		}
+		return 0, 1, true
+	case storage.OpHasItems:
+		found, _ := ret.(map[string]bool)
+		for _, key := range p.Keys {
+			if found[key] {
+				hits++
+			} else {
+				misses++
+			}
+		}
+		return hits, misses, true
+	}
+	return 0, 0, false
+}
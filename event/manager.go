@@ -0,0 +1,101 @@
+package event
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Listener is called with the events it is attached to
+type Listener func(e *Event)
+
+// Capable is implemented by adapters that fire events
+type Capable interface {
+	//GetEventManager returns the adapter's event manager
+	GetEventManager() *Manager
+}
+
+type listener struct {
+	id       int
+	pattern  string
+	priority int
+	fn       Listener
+}
+
+// Manager holds the listeners for events. It is safe for concurrent use
+type Manager struct {
+	mu        sync.RWMutex
+	listeners []listener
+	nextId    int
+}
+
+// NewManager returns a new event manager
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Attach attaches the listener to the events matching the pattern and returns a function that detaches it.
+// The pattern is an event name such as "pre.getItem", or uses "*" for the phase, the operation or both,
+// e.g. "exception.*", "*.setItem" or "*".
+// Listeners with a higher priority are called first, and listeners with the same priority in the order attached
+func (m *Manager) Attach(pattern string, l Listener, priority int) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextId++
+	id := m.nextId
+	i, _ := slices.BinarySearchFunc(m.listeners, priority, func(l listener, p int) int {
+		//descending priority, after listeners of the same priority
+		if l.priority >= p {
+			return -1
+		}
+		return 1
+	})
+	m.listeners = slices.Insert(m.listeners, i, listener{id: id, pattern: pattern, priority: priority, fn: l})
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.listeners = slices.DeleteFunc(m.listeners, func(l listener) bool {
+			return l.id == id
+		})
+	}
+}
+
+// HasListeners returns true if any listener is attached to the named event
+func (m *Manager) HasListeners(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.ContainsFunc(m.listeners, func(l listener) bool {
+		return matches(l.pattern, name)
+	})
+}
+
+// Trigger calls the listeners attached to the event until one stops propagation
+func (m *Manager) Trigger(e *Event) {
+	m.mu.RLock()
+	listeners := make([]Listener, 0, len(m.listeners))
+	for _, l := range m.listeners {
+		if matches(l.pattern, e.Name) {
+			listeners = append(listeners, l.fn)
+		}
+	}
+	m.mu.RUnlock()
+	for _, l := range listeners {
+		l(e)
+		if e.PropagationStopped() {
+			return
+		}
+	}
+}
+
+// matches returns true if the event name matches the listener pattern
+func matches(pattern, name string) bool {
+	if pattern == "*" || pattern == name {
+		return true
+	}
+	pPhase, pOp, ok := strings.Cut(pattern, ".")
+	if !ok {
+		return false
+	}
+	phase, op, _ := strings.Cut(name, ".")
+	return (pPhase == "*" || pPhase == phase) && (pOp == "*" || pOp == op)
+}
@@ -0,0 +1,67 @@
+package event_test
+
+import (
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestManager_Trigger(t *testing.T) {
+	sut := event.NewManager()
+	called := make([]string, 0)
+	sut.Attach("pre.getItem", func(e *event.Event) {
+		called = append(called, "exact")
+	}, 0)
+	sut.Attach("pre.*", func(e *event.Event) {
+		called = append(called, "phase")
+	}, 10)
+	sut.Attach("*.getItem", func(e *event.Event) {
+		called = append(called, "operation")
+	}, 0)
+	sut.Attach("*", func(e *event.Event) {
+		called = append(called, "all")
+	}, -10)
+	sut.Attach("post.*", func(e *event.Event) {
+		called = append(called, "post")
+	}, 100)
+
+	e := &event.Event{Name: event.Name(event.Pre, storage.OpGetItem)}
+	sut.Trigger(e)
+	assert.Equal(t, []string{"phase", "exact", "operation", "all"}, called)
+	assert.Equal(t, event.Pre, e.Phase())
+	assert.Equal(t, storage.OpGetItem, e.Operation())
+	assert.True(t, sut.HasListeners("post.setItem"))
+	assert.True(t, sut.HasListeners("foo"), "* matches all events")
+}
+
+func TestManager_StopPropagation(t *testing.T) {
+	sut := event.NewManager()
+	called := 0
+	sut.Attach("*", func(e *event.Event) {
+		called++
+		e.StopPropagation()
+	}, 0)
+	sut.Attach("*", func(e *event.Event) {
+		called++
+	}, 0)
+
+	e := &event.Event{Name: event.Name(event.Post, storage.OpSetItem)}
+	sut.Trigger(e)
+	assert.Equal(t, 1, called)
+	assert.True(t, e.PropagationStopped())
+}
+
+func TestManager_Detach(t *testing.T) {
+	sut := event.NewManager()
+	called := 0
+	detach := sut.Attach("*", func(e *event.Event) {
+		called++
+	}, 0)
+
+	sut.Trigger(&event.Event{Name: event.Name(event.Pre, storage.OpGetItem)})
+	detach()
+	sut.Trigger(&event.Event{Name: event.Name(event.Pre, storage.OpGetItem)})
+	assert.Equal(t, 1, called)
+	assert.False(t, sut.HasListeners("pre.getItem"))
+}
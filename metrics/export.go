@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"bufio"
+	"cmp"
+	"expvar"
+	"fmt"
+	"github.com/chippyash/go-cache-manager/storage"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Snapshot is a point in time copy of the metrics for an adapter operation
+type Snapshot struct {
+	Adapter   string            `json:"adapter"`
+	Namespace string            `json:"namespace"`
+	Tier      int               `json:"tier"`
+	Operation storage.Operation `json:"operation"`
+	Hits      uint64            `json:"hits"`
+	Misses    uint64            `json:"misses"`
+	Errors    uint64            `json:"errors"`
+	//BytesWritten the approximate size of the values set, before they are encoded
+	BytesWritten uint64 `json:"bytesWritten"`
+	//Count the number of calls
+	Count uint64 `json:"count"`
+	//Seconds the total latency of the calls
+	Seconds float64 `json:"seconds"`
+	//Buckets the cumulative number of calls with a latency less than or equal to each of Bounds
+	Buckets []uint64 `json:"buckets"`
+	//Bounds the upper bounds, in seconds, of the latency histogram buckets
+	Bounds []float64 `json:"bounds"`
+}
+
+// Snapshot returns a copy of the metrics, ordered by adapter, namespace, tier and operation
+func (c *Collector) Snapshot() []Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := make([]Snapshot, 0, len(c.series))
+	for k, s := range c.series {
+		buckets := make([]uint64, len(s.buckets))
+		var n uint64
+		for i, b := range s.buckets {
+			n += b
+			buckets[i] = n
+		}
+		ret = append(ret, Snapshot{
+			Adapter:      k.Adapter,
+			Namespace:    k.Namespace,
+			Tier:         k.Tier,
+			Operation:    k.Operation,
+			Hits:         s.hits,
+			Misses:       s.misses,
+			Errors:       s.errors,
+			BytesWritten: s.bytesWritten,
+			Count:        s.count,
+			Seconds:      s.sum,
+			Buckets:      buckets,
+			Bounds:       c.bounds,
+		})
+	}
+	slices.SortFunc(ret, func(a, b Snapshot) int {
+		return cmp.Or(
+			cmp.Compare(a.Adapter, b.Adapter),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Tier, b.Tier),
+			cmp.Compare(a.Operation, b.Operation),
+		)
+	})
+	return ret
+}
+
+// Publish publishes the metrics snapshot as the expvar variable name. As with expvar.Publish, it panics if the name
+// is already published
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return c.Snapshot()
+	}))
+}
+
+// Handler returns an http.Handler that writes the metrics in the Prometheus text exposition format
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.WriteText(w)
+	})
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format
+func (c *Collector) WriteText(w io.Writer) error {
+	snapshots := c.Snapshot()
+	bw := bufio.NewWriter(w)
+	counter := func(name, help string, value func(s Snapshot) (uint64, bool)) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, s := range snapshots {
+			if v, ok := value(s); ok {
+				fmt.Fprintf(bw, "%s{%s} %d\n", name, labels(s), v)
+			}
+		}
+	}
+	counter("gcm_hits_total", "Number of items found by read operations.", func(s Snapshot) (uint64, bool) {
+		return s.Hits, isRead(s.Operation)
+	})
+	counter("gcm_misses_total", "Number of items not found by read operations.", func(s Snapshot) (uint64, bool) {
+		return s.Misses, isRead(s.Operation)
+	})
+	counter("gcm_errors_total", "Number of operations that returned an error other than key not found.", func(s Snapshot) (uint64, bool) {
+		return s.Errors, true
+	})
+	counter("gcm_written_bytes_total", "Approximate number of bytes written by set operations.", func(s Snapshot) (uint64, bool) {
+		return s.BytesWritten, isWrite(s.Operation)
+	})
+
+	name := "gcm_operation_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Latency of the operations.\n# TYPE %s histogram\n", name, name)
+	for _, s := range snapshots {
+		l := labels(s)
+		for i, b := range s.Bounds {
+			fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", name, l, strconv.FormatFloat(b, 'g', -1, 64), s.Buckets[i])
+		}
+		fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, l, s.Count)
+		fmt.Fprintf(bw, "%s_sum{%s} %s\n", name, l, strconv.FormatFloat(s.Seconds, 'g', -1, 64))
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", name, l, s.Count)
+	}
+	return bw.Flush()
+}
+
+// labels returns the Prometheus labels for the snapshot
+func labels(s Snapshot) string {
+	return fmt.Sprintf(`adapter="%s",namespace="%s",tier="%d",operation="%s"`,
+		escape(s.Adapter), escape(s.Namespace), s.Tier, escape(string(s.Operation)))
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes a Prometheus label value
+func escape(v string) string {
+	return escaper.Replace(v)
+}
+
+// isRead returns true if hits and misses are counted for the operation
+func isRead(op storage.Operation) bool {
+	return slices.Contains([]storage.Operation{storage.OpGetItem, storage.OpGetItems, storage.OpHasItem, storage.OpHasItems}, op)
+}
+
+// isWrite returns true if bytes written are counted for the operation
+func isWrite(op storage.Operation) bool {
+	return slices.Contains([]storage.Operation{
+		storage.OpSetItem, storage.OpSetItems, storage.OpCheckAndSetItem, storage.OpCheckAndSetItems,
+		storage.OpSetItemWithTTL, storage.OpSetItemsWithTTL,
+	}, op)
+}
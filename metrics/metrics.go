@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/chippyash/go-cache-manager/adapter"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the default upper bounds, in seconds, of the latency histogram buckets
+var DefaultBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Labels identify the adapter that a metric is recorded for
+type Labels struct {
+	//Adapter the adapter Name
+	Adapter string
+	//Namespace the adapter namespace
+	Namespace string
+	//Tier the position of the adapter in its chain. 0 is the adapter that is called, 1 the adapter chained to it
+	//and so on. Hits recorded for tier 1 and below are hits in a chained adapter after a miss in the tiers above
+	Tier int
+}
+
+// key identifies a series of metrics
+type key struct {
+	Labels
+	Operation storage.Operation
+}
+
+// series holds the metrics for an adapter operation
+type series struct {
+	hits         uint64
+	misses       uint64
+	errors       uint64
+	bytesWritten uint64
+	count        uint64
+	sum          float64
+	buckets      []uint64
+}
+
+// Collector records the metrics of the storages it instruments. It is safe for concurrent use
+type Collector struct {
+	mu     sync.Mutex
+	bounds []float64
+	series map[key]*series
+}
+
+// NewCollector returns a new collector. The latency histogram buckets are DefaultBuckets, or the upper bounds
+// in seconds if any are given
+func NewCollector(bounds ...float64) *Collector {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	return &Collector{
+		bounds: slices.Compact(bounds),
+		series: make(map[key]*series),
+	}
+}
+
+// instrumented is a storage decorated by a Collector
+type instrumented struct {
+	*decorator.Decorator
+}
+
+// Open opens the decorated storage and returns the instrumented storage
+func (i *instrumented) Open() (storage.Storage, error) {
+	if _, err := i.Decorator.Open(); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Instrument returns s decorated to record its metrics, labelled with its Name and namespace. Each adapter chained
+// below s is instrumented too, so that hits in a chained adapter are counted separately by their tier.
+//
+// NB. The instrumented chained adapters replace the adapters chained to s and below, so that s calls them, and their
+// metrics are recorded when s is called directly too. A chained adapter that is already instrumented, by this or
+// another Collector, is not instrumented again, so calling Instrument again does not count its operations twice
+func (c *Collector) Instrument(s storage.Storage) storage.Storage {
+	return c.instrument(s, 0)
+}
+
+func (c *Collector) instrument(s storage.Storage, tier int) storage.Storage {
+	if ch, ok := s.(storage.Chainable); ok && ch.GetChained() != nil && !isInstrumented(ch.GetChained()) {
+		ch.ChainAdapter(c.instrument(ch.GetChained(), tier+1))
+	}
+	labels := Labels{
+		Adapter:   Name(s),
+		Namespace: namespace(s),
+		Tier:      tier,
+	}
+	return &instrumented{decorator.New(s, func(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+		start := time.Now()
+		//iterations are recorded when they finish
+		return decorator.Observe(ctx, op, p, next, func(ret any, err error) {
+			c.record(key{Labels: labels, Operation: op}, time.Since(start), p, ret, err)
+		})
+	})}
+}
+
+// isInstrumented returns true if s, or a storage that it decorates, is instrumented
+func isInstrumented(s storage.Storage) bool {
+	for {
+		if _, ok := s.(*instrumented); ok {
+			return true
+		}
+		d, ok := s.(interface{ Unwrap() storage.Storage })
+		if !ok {
+			return false
+		}
+		s = d.Unwrap()
+	}
+}
+
+// record records the outcome of an operation. The bytes written are the sizes of the values of the keys that were set,
+// before they are encoded by the adapter's codec, see Size
+func (c *Collector) record(k key, elapsed time.Duration, p *event.Params, ret any, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[k]
+	if !ok {
+		s = &series{buckets: make([]uint64, len(c.bounds))}
+		c.series[k] = s
+	}
+	s.count++
+	secs := elapsed.Seconds()
+	s.sum += secs
+	if i, _ := slices.BinarySearch(c.bounds, secs); i < len(s.buckets) {
+		s.buckets[i]++
+	}
+	if err != nil && !errs.Is(err, errors.ErrKeyNotFound) {
+		s.errors++
+		return
+	}
+	if hits, misses, ok := event.Hits(k.Operation, p, ret, err); ok {
+		s.hits += uint64(hits)
+		s.misses += uint64(misses)
+		return
+	}
+	switch k.Operation {
+	case storage.OpSetItem, storage.OpCheckAndSetItem, storage.OpSetItemWithTTL:
+		if set, _ := ret.(bool); set {
+			s.bytesWritten += uint64(Size(p.Value))
+		}
+	case storage.OpSetItems, storage.OpCheckAndSetItems, storage.OpSetItemsWithTTL:
+		//the adapters return the keys that were set
+		set, _ := ret.([]string)
+		for _, key := range set {
+			if v, ok := p.Values[key]; ok {
+				s.bytesWritten += uint64(Size(v))
+			}
+		}
+	}
+}
+
+// Name returns the Name of the adapter, or its type if it is not an adapter.AbstractAdapter
+func Name(s storage.Storage) string {
+	s = decorator.Unwrap(s)
+	if a, ok := s.(*adapter.AbstractAdapter); ok {
+		return a.Name
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// namespace returns the namespace of the storage
+func namespace(s storage.Storage) string {
+	return s.GetOptions().Namespace()
+}
+
+// Size returns the approximate size in bytes of a value before it is encoded by the adapter, see storage.Size
+func Size(v any) int {
+	return storage.Size(v)
+}
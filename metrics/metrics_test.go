@@ -0,0 +1,164 @@
+package metrics_test
+
+import (
+	"expvar"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/metrics"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector_Instrument(t *testing.T) {
+	top := memory.New("app:", time.Second*60, time.Second*120)
+	chained := memory.New("app:", time.Second*60, time.Second*120)
+	top.(storage.Chainable).ChainAdapter(chained)
+	_, err := chained.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	c := metrics.NewCollector()
+	sut := c.Instrument(top)
+
+	//miss in the top tier, hit in the chained tier
+	_, err = sut.GetItem("foo")
+	assert.NoError(t, err)
+	//hit in the top tier
+	_, err = sut.GetItem("foo")
+	assert.NoError(t, err)
+	//miss in both tiers
+	_, err = sut.GetItem("baz")
+	assert.Error(t, err)
+	_, err = sut.SetItem("qux", "quux")
+	assert.NoError(t, err)
+
+	byTier := make(map[int]metrics.Snapshot)
+	for _, s := range c.Snapshot() {
+		assert.Equal(t, "memory", s.Adapter)
+		assert.Equal(t, "app:", s.Namespace)
+		if s.Operation == storage.OpGetItem {
+			byTier[s.Tier] = s
+		}
+		if s.Operation == storage.OpSetItem && s.Tier == 0 {
+			assert.Equal(t, uint64(4), s.BytesWritten)
+		}
+	}
+	assert.Equal(t, uint64(2), byTier[0].Hits)
+	assert.Equal(t, uint64(1), byTier[0].Misses)
+	assert.Equal(t, uint64(3), byTier[0].Count)
+	assert.Equal(t, uint64(3), byTier[0].Buckets[len(byTier[0].Buckets)-1])
+	assert.Equal(t, uint64(1), byTier[1].Hits)
+	assert.Equal(t, uint64(1), byTier[1].Misses)
+	assert.Equal(t, uint64(0), byTier[1].Errors)
+}
+
+func TestCollector_BytesWritten(t *testing.T) {
+	c := metrics.NewCollector()
+	sut := c.Instrument(memory.New("", time.Second*60, time.Second*120, storage.WithCodec(storage.JsonCodec{})))
+	//the values are measured before they are encoded
+	_, err := sut.SetItems(map[string]any{"foo": "bar"})
+	assert.NoError(t, err)
+	//only the values of the keys that were set are counted
+	_, err = sut.CheckAndSetItems(map[string]any{"foo": "baz", "missing": "quux"})
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	written := make(map[storage.Operation]uint64)
+	for _, s := range c.Snapshot() {
+		written[s.Operation] = s.BytesWritten
+	}
+	assert.Equal(t, uint64(3), written[storage.OpSetItems])
+	assert.Equal(t, uint64(3), written[storage.OpCheckAndSetItems])
+}
+
+func TestCollector_InstrumentTwice(t *testing.T) {
+	top := memory.New("app:", time.Second*60, time.Second*120)
+	chained := memory.New("app:", time.Second*60, time.Second*120)
+	top.(storage.Chainable).ChainAdapter(chained)
+	_, err := chained.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	c := metrics.NewCollector()
+	c.Instrument(top)
+	sut := c.Instrument(top)
+	//the chained adapter is only instrumented once, by the first Collector
+	other := metrics.NewCollector()
+	other.Instrument(top)
+	assert.Equal(t, chained, decorator.Unwrap(top.(storage.Chainable).GetChained()))
+
+	//miss in the top tier, hit in the chained tier
+	_, err = sut.GetItem("foo")
+	assert.NoError(t, err)
+
+	for _, s := range c.Snapshot() {
+		if s.Operation == storage.OpGetItem {
+			assert.Equal(t, uint64(1), s.Hits, "tier %d", s.Tier)
+			assert.Equal(t, uint64(1), s.Count, "tier %d", s.Tier)
+		}
+	}
+	assert.Empty(t, other.Snapshot())
+}
+
+func TestCollector_Iterate(t *testing.T) {
+	inner := memory.New("app:", time.Second*60, time.Second*120)
+	inner.GetOptions()[storage.OptReadable] = false
+	c := metrics.NewCollector()
+	sut := c.Instrument(inner)
+
+	//the iteration is recorded when it finishes, with the error that stopped it
+	seq := sut.(storage.Iterable).IterateKeys(storage.IterateOptions{})
+	assert.Empty(t, c.Snapshot())
+	for range seq {
+	}
+	snapshots := c.Snapshot()
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, storage.OpIterateKeys, snapshots[0].Operation)
+	assert.Equal(t, uint64(1), snapshots[0].Count)
+	assert.Equal(t, uint64(1), snapshots[0].Errors)
+}
+
+func TestCollector_Handler(t *testing.T) {
+	c := metrics.NewCollector(0.5, 1)
+	sut := c.Instrument(memory.New("", time.Second*60, time.Second*120))
+	_, _ = sut.GetItem("foo")
+	_, _ = sut.SetItems(map[string]any{"foo": "bar", "baz": []byte("qux")})
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE gcm_hits_total counter",
+		`gcm_misses_total{adapter="memory",namespace="",tier="0",operation="getItem"} 1`,
+		`gcm_written_bytes_total{adapter="memory",namespace="",tier="0",operation="setItems"} 6`,
+		"# TYPE gcm_operation_duration_seconds histogram",
+		`gcm_operation_duration_seconds_bucket{adapter="memory",namespace="",tier="0",operation="getItem",le="0.5"} 1`,
+		`gcm_operation_duration_seconds_bucket{adapter="memory",namespace="",tier="0",operation="getItem",le="+Inf"} 1`,
+		`gcm_operation_duration_seconds_count{adapter="memory",namespace="",tier="0",operation="setItems"} 1`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, `gcm_hits_total{adapter="memory",namespace="",tier="0",operation="setItems"}`)
+}
+
+func TestCollector_Publish(t *testing.T) {
+	c := metrics.NewCollector()
+	sut := c.Instrument(memory.New("", time.Second*60, time.Second*120))
+	_, _ = sut.GetItem("foo")
+
+	c.Publish("gcm_test")
+	v := expvar.Get("gcm_test").String()
+	assert.True(t, strings.HasPrefix(v, `[{"adapter":"memory","namespace":"","tier":0,"operation":"getItem","hits":0,"misses":1`), v)
+}
+
+func TestSize(t *testing.T) {
+	assert.Equal(t, 3, metrics.Size("foo"))
+	assert.Equal(t, 2, metrics.Size([]byte("ab")))
+	assert.Equal(t, 8, metrics.Size(int64(1)))
+	assert.Equal(t, 1, metrics.Size(true))
+	assert.Equal(t, 13, metrics.Size(map[string]int{"a": 1, "b": 2}))
+	assert.Equal(t, 0, metrics.Size(nil))
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	errs "github.com/pkg/errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Operation names an adapter operation
+type Operation string
+
+const (
+	OpGetItem          Operation = "getItem"
+	OpGetItems         Operation = "getItems"
+	OpHasItem          Operation = "hasItem"
+	OpHasItems         Operation = "hasItems"
+	OpSetItem          Operation = "setItem"
+	OpSetItems         Operation = "setItems"
+	OpCheckAndSetItem  Operation = "checkAndSetItem"
+	OpCheckAndSetItems Operation = "checkAndSetItems"
+	OpTouchItem        Operation = "touchItem"
+	OpTouchItems       Operation = "touchItems"
+	OpRemoveItem       Operation = "removeItem"
+	OpRemoveItems      Operation = "removeItems"
+	OpIncrement        Operation = "increment"
+	OpDecrement        Operation = "decrement"
+	OpGetOrSet         Operation = "getOrSet"
+	OpGetOrSetItems    Operation = "getOrSetItems"
+	OpSetItemWithTTL   Operation = "setItemWithTTL"
+	OpSetItemsWithTTL  Operation = "setItemsWithTTL"
+	OpTouchItemWithTTL Operation = "touchItemWithTTL"
+	OpGetTTL           Operation = "getTTL"
+	OpSetTags          Operation = "setTags"
+	OpGetTags          Operation = "getTags"
+	OpClearByTags      Operation = "clearByTags"
+	OpFlush            Operation = "flush"
+	OpClearExpired     Operation = "clearExpired"
+	OpClearByPrefix    Operation = "clearByPrefix"
+	OpClearByPattern   Operation = "clearByPattern"
+	OpIterate          Operation = "iterate"
+	OpIterateKeys      Operation = "iterateKeys"
+)
+
+// Capabilities describes what an adapter, or a chain of adapters, supports
+type Capabilities struct {
+	//DataTypes the data types that can be stored, from OptDataTypes
+	DataTypes DataTypes
+	//Operations the supported operations
+	Operations []Operation
+	//TTL is true if items expire
+	TTL bool
+	//TTLPrecision the smallest TTL unit that is honoured
+	TTLPrecision time.Duration
+	//MaxKeyLength the maximum key length, from OptMaxKeyLength. 0 is unlimited
+	MaxKeyLength int
+	//MaxValueLength the maximum value length, from OptMaxValueLength. 0 is unlimited
+	MaxValueLength int
+	//Persistent is true if items survive the application process restarting
+	Persistent bool
+}
+
+// Capable is implemented by adapters that can describe their capabilities.
+// The capabilities of a chained adapter are combined with those of the adapters chained below it
+type Capable interface {
+	Capabilities() Capabilities
+}
+
+// Supports returns true if all the operations are supported
+func (c Capabilities) Supports(ops ...Operation) bool {
+	for _, op := range ops {
+		if !slices.Contains(c.Operations, op) {
+			return false
+		}
+	}
+	return true
+}
+
+// SupportsType returns true if the data type, e.g. TypeString, can be stored
+func (c Capabilities) SupportsType(t int) bool {
+	return c.DataTypes[t]
+}
+
+// Require returns an error wrapping errors.ErrNotImplemented that lists the operations that are not supported, if any.
+// Use it to validate a configuration at startup
+func (c Capabilities) Require(ops ...Operation) error {
+	missing := make([]string, 0)
+	for _, op := range ops {
+		if !c.Supports(op) {
+			missing = append(missing, string(op))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errs.Wrap(errors.ErrNotImplemented, fmt.Sprintf("unsupported operations: %s", strings.Join(missing, ", ")))
+}
+
+// Combine returns the capabilities of a chain where c is chained above other.
+// A value is written to every adapter in a chain, so only the data types and operations supported by both are
+// supported, TTLs are only supported if both support them and the precision is the coarser of the two. The maximum
+// lengths are the smaller of the two, and the chain is persistent if either adapter is
+func (c Capabilities) Combine(other Capabilities) Capabilities {
+	ret := Capabilities{
+		DataTypes:      make(DataTypes, len(c.DataTypes)),
+		Operations:     make([]Operation, 0, len(c.Operations)),
+		TTL:            c.TTL && other.TTL,
+		TTLPrecision:   max(c.TTLPrecision, other.TTLPrecision),
+		MaxKeyLength:   minLength(c.MaxKeyLength, other.MaxKeyLength),
+		MaxValueLength: minLength(c.MaxValueLength, other.MaxValueLength),
+		Persistent:     c.Persistent || other.Persistent,
+	}
+	for t := range maps.Keys(c.DataTypes) {
+		ret.DataTypes[t] = c.DataTypes[t] && other.DataTypes[t]
+	}
+	for _, op := range c.Operations {
+		if other.Supports(op) {
+			ret.Operations = append(ret.Operations, op)
+		}
+	}
+	if !ret.TTL {
+		ret.TTLPrecision = 0
+	}
+	return ret
+}
+
+// minLength returns the smaller of two maximum lengths, where 0 is unlimited
+func minLength(a, b int) int {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
@@ -0,0 +1,53 @@
+package storage_test
+
+import (
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCapabilities_Combine(t *testing.T) {
+	a := storage.Capabilities{
+		DataTypes:      storage.DataTypes{storage.TypeString: true, storage.TypeInteger: true},
+		Operations:     []storage.Operation{storage.OpGetItem, storage.OpSetItem, storage.OpIncrement},
+		TTL:            true,
+		TTLPrecision:   time.Millisecond,
+		MaxKeyLength:   0,
+		MaxValueLength: 1024,
+	}
+	b := storage.Capabilities{
+		DataTypes:    storage.DataTypes{storage.TypeString: true, storage.TypeInteger: false},
+		Operations:   []storage.Operation{storage.OpGetItem, storage.OpSetItem},
+		TTL:          true,
+		TTLPrecision: time.Second,
+		MaxKeyLength: 250,
+		Persistent:   true,
+	}
+
+	c := a.Combine(b)
+	assert.True(t, c.SupportsType(storage.TypeString))
+	assert.False(t, c.SupportsType(storage.TypeInteger))
+	assert.Equal(t, []storage.Operation{storage.OpGetItem, storage.OpSetItem}, c.Operations)
+	assert.True(t, c.TTL)
+	assert.Equal(t, time.Second, c.TTLPrecision)
+	assert.Equal(t, 250, c.MaxKeyLength)
+	assert.Equal(t, 1024, c.MaxValueLength)
+	assert.True(t, c.Persistent)
+
+	b.TTL = false
+	c = a.Combine(b)
+	assert.False(t, c.TTL)
+	assert.Equal(t, time.Duration(0), c.TTLPrecision)
+}
+
+func TestCapabilities_Require(t *testing.T) {
+	c := storage.Capabilities{
+		Operations: []storage.Operation{storage.OpGetItem, storage.OpSetItem},
+	}
+	assert.NoError(t, c.Require(storage.OpGetItem, storage.OpSetItem))
+	err := c.Require(storage.OpGetItem, storage.OpIncrement)
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+	assert.Contains(t, err.Error(), "unsupported operations: increment")
+}
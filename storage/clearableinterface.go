@@ -0,0 +1,16 @@
+package storage
+
+import "context"
+
+// Clearable is implemented by adapters that can remove the items whose keys match a prefix or a pattern.
+// The prefix and pattern are relative to the adapter namespace, so only items in the namespace are removed
+type Clearable interface {
+	//ClearByPrefix removes the items whose keys start with prefix
+	ClearByPrefix(prefix string) error
+	//ClearByPattern removes the items whose keys match the Redis style glob pattern. See adapter.MatchGlob
+	ClearByPattern(pattern string) error
+	//ClearByPrefixCtx is the context aware variant of ClearByPrefix
+	ClearByPrefixCtx(ctx context.Context, prefix string) error
+	//ClearByPatternCtx is the context aware variant of ClearByPattern
+	ClearByPatternCtx(ctx context.Context, pattern string) error
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	errs "github.com/pkg/errors"
+)
+
+// Codec encodes values to bytes before they are written to the cache backend and decodes them when they are read back.
+// Set a codec for an adapter with options[storage.OptCodec]
+type Codec interface {
+	//Encode encodes the value
+	Encode(v any) ([]byte, error)
+	//Decode decodes the data into a value
+	Decode(data []byte) (any, error)
+}
+
+// JsonCodec encodes values as JSON. Values are decoded as the generic JSON types, i.e. structs are returned as
+// map[string]any and numbers as float64. Use a TypedStorage to have them returned as your own types
+type JsonCodec struct{}
+
+func (c JsonCodec) Encode(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to json encode value")
+	}
+	return b, nil
+}
+
+func (c JsonCodec) Decode(data []byte) (any, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, errs.Wrap(err, "failed to json decode value")
+	}
+	return v, nil
+}
+
+// GobCodec encodes values using encoding/gob. Values are decoded to their original type, but your own types must be
+// registered with gob.Register() before use
+type GobCodec struct{}
+
+// gobEnvelope allows gob to carry the concrete type of the encoded value
+type gobEnvelope struct {
+	V any
+}
+
+func (c GobCodec) Encode(v any) ([]byte, error) {
+	var w bytes.Buffer
+	if err := gob.NewEncoder(&w).Encode(gobEnvelope{V: v}); err != nil {
+		return nil, errs.Wrap(err, "failed to gob encode value")
+	}
+	return w.Bytes(), nil
+}
+
+func (c GobCodec) Decode(data []byte) (any, error) {
+	var env gobEnvelope
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&env); err != nil {
+		return nil, errs.Wrap(err, "failed to gob decode value")
+	}
+	return env.V, nil
+}
+
+// RawCodec stores string and []byte values as-is. Any other type is rejected. Values are decoded as []byte
+type RawCodec struct{}
+
+func (c RawCodec) Encode(v any) ([]byte, error) {
+	switch vv := v.(type) {
+	case []byte:
+		return vv, nil
+	case string:
+		return []byte(vv), nil
+	default:
+		return nil, errs.Wrap(errors.ErrUnsupportedDataType, fmt.Sprintf("raw codec cannot encode type: %T", v))
+	}
+}
+
+func (c RawCodec) Decode(data []byte) (any, error) {
+	return bytes.Clone(data), nil
+}
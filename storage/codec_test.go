@@ -0,0 +1,78 @@
+package storage_test
+
+import (
+	"encoding/gob"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type codecTestStruct struct {
+	Name  string
+	Count int
+}
+
+func init() {
+	gob.Register(codecTestStruct{})
+}
+
+func TestJsonCodec(t *testing.T) {
+	sut := storage.JsonCodec{}
+	b, err := sut.Encode(codecTestStruct{Name: "foo", Count: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Name":"foo","Count":2}`, string(b))
+
+	v, err := sut.Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"Name": "foo", "Count": float64(2)}, v)
+
+	_, err = sut.Decode([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestGobCodec(t *testing.T) {
+	sut := storage.GobCodec{}
+	for _, val := range []any{codecTestStruct{Name: "foo", Count: 2}, "bar", int64(3), []byte("baz")} {
+		b, err := sut.Encode(val)
+		assert.NoError(t, err)
+		v, err := sut.Decode(b)
+		assert.NoError(t, err)
+		assert.Equal(t, val, v)
+	}
+
+	_, err := sut.Decode([]byte("not gob"))
+	assert.Error(t, err)
+}
+
+func TestRawCodec(t *testing.T) {
+	sut := storage.RawCodec{}
+	b, err := sut.Encode("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+	b, err = sut.Encode([]byte("bar"))
+	assert.NoError(t, err)
+	v, err := sut.Decode(b)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), v)
+
+	_, err = sut.Encode(2)
+	assert.ErrorIs(t, err, errors.ErrUnsupportedDataType)
+}
+
+func TestTypedStorage_JsonCodec(t *testing.T) {
+	mem := memoryWithCodec(storage.JsonCodec{})
+	sut := storage.NewTypedStorage[codecTestStruct](mem)
+	_, err := sut.SetItem("foo", codecTestStruct{Name: "foo", Count: 2})
+	assert.NoError(t, err)
+
+	//the adapter returns the generic JSON types
+	v, err := mem.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"Name": "foo", "Count": float64(2)}, v)
+
+	//the typed storage returns the struct
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, codecTestStruct{Name: "foo", Count: 2}, val)
+}
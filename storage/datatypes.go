@@ -2,7 +2,9 @@ package storage
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"encoding/json"
 	"strconv"
 	"time"
 )
@@ -92,6 +94,33 @@ func GetType(v any) int {
 	}
 }
 
+// Size returns the approximate size in bytes of a value.
+// The size of a value that is not a string, []byte or fixed size type is the size of its JSON encoding
+func Size(v any) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(t)
+	case []byte:
+		return len(t)
+	case int:
+		return 8
+	case uint:
+		return 8
+	case time.Time:
+		return len(t.Format(time.RFC3339Nano))
+	}
+	if n := binary.Size(v); n >= 0 {
+		return n
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
 func GetTypedValue(t int, v string, dateFormat string) (any, error) {
 	switch t {
 	case TypeString:
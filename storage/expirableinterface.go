@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	//DefaultTTL denotes that the adapter's default TTL, options[OptTTL], is to be used
+	DefaultTTL time.Duration = 0
+	//NoExpiry denotes an item that does not expire
+	NoExpiry time.Duration = -1
+)
+
+// Expirable is implemented by adapters that support a TTL per item.
+// A ttl of DefaultTTL uses the adapter's default TTL and a ttl of NoExpiry sets an item that does not expire
+type Expirable interface {
+	//SetItemWithTTL sets the value of the requested key with the ttl. Returns true if set, else false and a possible error
+	SetItemWithTTL(key string, value any, ttl time.Duration) (bool, error)
+	//SetItemsWithTTL sets multiple key values with the ttl. Returns an array of keys set and a possible error
+	SetItemsWithTTL(values map[string]any, ttl time.Duration) ([]string, error)
+	//TouchItemWithTTL resets the TTL for the given key to ttl. Returns true if reset, else false
+	TouchItemWithTTL(key string, ttl time.Duration) bool
+	//GetTTL returns the remaining TTL for the given key, NoExpiry if the item does not expire, or errors.ErrKeyNotFound
+	GetTTL(key string) (time.Duration, error)
+	//SetItemWithTTLCtx is the context aware variant of SetItemWithTTL
+	SetItemWithTTLCtx(ctx context.Context, key string, value any, ttl time.Duration) (bool, error)
+	//SetItemsWithTTLCtx is the context aware variant of SetItemsWithTTL
+	SetItemsWithTTLCtx(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error)
+	//TouchItemWithTTLCtx is the context aware variant of TouchItemWithTTL
+	TouchItemWithTTLCtx(ctx context.Context, key string, ttl time.Duration) bool
+	//GetTTLCtx is the context aware variant of GetTTL
+	GetTTLCtx(ctx context.Context, key string) (time.Duration, error)
+}
+
+// SetItemWithTTL sets the item in s with the ttl if s is Expirable, else with the default TTL of s.
+// Adapters use it to pass a ttl on to the chained adapter
+func SetItemWithTTL(ctx context.Context, s Storage, key string, value any, ttl time.Duration) (bool, error) {
+	if e, ok := s.(Expirable); ok {
+		return e.SetItemWithTTLCtx(ctx, key, value, ttl)
+	}
+	return WithContext(s).SetItemCtx(ctx, key, value)
+}
+
+// SetItemsWithTTL sets the items in s with the ttl if s is Expirable, else with the default TTL of s
+func SetItemsWithTTL(ctx context.Context, s Storage, values map[string]any, ttl time.Duration) ([]string, error) {
+	if e, ok := s.(Expirable); ok {
+		return e.SetItemsWithTTLCtx(ctx, values, ttl)
+	}
+	return WithContext(s).SetItemsCtx(ctx, values)
+}
+
+// TouchItemWithTTL resets the TTL for the key in s to ttl if s is Expirable, else to the default TTL of s
+func TouchItemWithTTL(ctx context.Context, s Storage, key string, ttl time.Duration) bool {
+	if e, ok := s.(Expirable); ok {
+		return e.TouchItemWithTTLCtx(ctx, key, ttl)
+	}
+	return WithContext(s).TouchItemCtx(ctx, key)
+}
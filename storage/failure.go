@@ -0,0 +1,21 @@
+package storage
+
+import "context"
+
+// FailureReporter is called with the backend errors that a storage handles rather than returns
+type FailureReporter func(s Storage, err error)
+
+type failureReporterKey struct{}
+
+// WithFailureReporter returns a copy of ctx whose failures, see ReportFailure, are reported to fn
+func WithFailureReporter(ctx context.Context, fn FailureReporter) context.Context {
+	return context.WithValue(ctx, failureReporterKey{}, fn)
+}
+
+// ReportFailure reports an error of the backend of s that s does not return, e.g. a failed read that it treats as a
+// miss, to the FailureReporter of ctx, if there is one
+func ReportFailure(ctx context.Context, s Storage, err error) {
+	if fn, ok := ctx.Value(failureReporterKey{}).(FailureReporter); ok && err != nil {
+		fn(s, err)
+	}
+}
@@ -0,0 +1,55 @@
+package storage
+
+import "context"
+
+// Flushable is implemented by adapters that can remove all their items
+type Flushable interface {
+	//Flush removes all the items in the adapter's namespace, or all the items if there is no namespace
+	Flush() error
+	//FlushCtx is the context aware variant of Flush
+	FlushCtx(ctx context.Context) error
+}
+
+// ClearExpiredable is implemented by adapters that can remove their expired items
+type ClearExpiredable interface {
+	//ClearExpired removes the expired items and any metadata they leave behind
+	ClearExpired() error
+	//ClearExpiredCtx is the context aware variant of ClearExpired
+	ClearExpiredCtx(ctx context.Context) error
+}
+
+// FlushChain flushes s and each adapter chained below it that is Flushable.
+// All the adapters are flushed even if one fails, and the first error is returned
+func FlushChain(ctx context.Context, s Storage) error {
+	var err error
+	for ; s != nil; s = chained(s) {
+		if f, ok := s.(Flushable); ok {
+			if e := f.FlushCtx(ctx); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// ClearExpiredChain clears the expired items from s and each adapter chained below it that is ClearExpiredable.
+// All the adapters are cleared even if one fails, and the first error is returned
+func ClearExpiredChain(ctx context.Context, s Storage) error {
+	var err error
+	for ; s != nil; s = chained(s) {
+		if c, ok := s.(ClearExpiredable); ok {
+			if e := c.ClearExpiredCtx(ctx); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// chained returns the adapter chained to s, or nil if there is none
+func chained(s Storage) Storage {
+	if c, ok := s.(Chainable); ok {
+		return c.GetChained()
+	}
+	return nil
+}
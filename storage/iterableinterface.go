@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"iter"
+)
+
+// DefaultIterateBatchSize is the number of items fetched from the backend at a time if IterateOptions.BatchSize is not set
+const DefaultIterateBatchSize = 100
+
+// IterateOptions configures an iteration over the items of an Iterable adapter
+type IterateOptions struct {
+	//Prefix only iterates the keys that start with Prefix. It is relative to the adapter namespace
+	Prefix string
+	//BatchSize is the number of items fetched from the backend at a time. Defaults to DefaultIterateBatchSize
+	BatchSize int
+	//OnError, if set, is called with any error that stops the iteration early
+	OnError func(err error)
+}
+
+// Batch returns the batch size, or DefaultIterateBatchSize if it is not set
+func (o IterateOptions) Batch() int {
+	if o.BatchSize <= 0 {
+		return DefaultIterateBatchSize
+	}
+	return o.BatchSize
+}
+
+// Error calls OnError with err if both are set
+func (o IterateOptions) Error(err error) {
+	if err != nil && o.OnError != nil {
+		o.OnError(err)
+	}
+}
+
+// Iterable is implemented by adapters that can iterate over the items in their namespace.
+// Keys are returned without the namespace. Items that expire or are removed during the iteration may not be returned,
+// and items that are added during the iteration may or may not be returned
+type Iterable interface {
+	//Iterate returns an iterator over the keys and values
+	Iterate(opts IterateOptions) iter.Seq2[string, any]
+	//IterateKeys returns an iterator over the keys only, which does not fetch the values
+	IterateKeys(opts IterateOptions) iter.Seq[string]
+	//IterateCtx is the context aware variant of Iterate. The iteration stops when the context is done
+	IterateCtx(ctx context.Context, opts IterateOptions) iter.Seq2[string, any]
+	//IterateKeysCtx is the context aware variant of IterateKeys. The iteration stops when the context is done
+	IterateKeysCtx(ctx context.Context, opts IterateOptions) iter.Seq[string]
+}
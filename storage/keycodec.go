@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	errs "github.com/pkg/errors"
+	"net/url"
+	"strings"
+)
+
+// KeyCodec encodes keys before they are sent to the cache backend and decodes the keys read back from it, e.g. when
+// iterating. Set a key codec for an adapter with options[storage.OptKeyCodec].
+// The encoding must preserve prefixes, i.e. the encoding of a key must start with the encoding of each of its
+// prefixes, so that the items with a prefix can be found
+type KeyCodec interface {
+	//EncodeKey encodes the key
+	EncodeKey(key string) string
+	//DecodeKey decodes an encoded key
+	DecodeKey(s string) (string, error)
+}
+
+// KeyHashing hashes keys that are too long, or unsafe, for the backend. The hash cannot be reversed, so the adapter
+// remembers the keys it has hashed, to return them from GetItems and when iterating. Set it for an adapter with
+// options[storage.OptKeyHashing]
+type KeyHashing struct {
+	//MaxLength hash a key if its backend key is longer than MaxLength. 0 uses OptMaxKeyLength, if it is set
+	MaxLength int
+	//Unsafe returns true if the key must be hashed, e.g. UnsafeKey. nil does not hash keys for being unsafe
+	Unsafe func(key string) bool
+}
+
+// SafeKeyCodec percent encodes the characters of keys that are not safe in S3 object keys and URLs.
+// Letters, digits and the characters !-_.*'()/ are not encoded
+type SafeKeyCodec struct{}
+
+func (c SafeKeyCodec) EncodeKey(key string) string {
+	if !UnsafeKey(key) {
+		return key
+	}
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		if safeKeyChar(key[i]) {
+			b.WriteByte(key[i])
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "%%%02X", key[i])
+	}
+	return b.String()
+}
+
+func (c SafeKeyCodec) DecodeKey(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	key, err := url.PathUnescape(s)
+	if err != nil {
+		return "", errs.Wrap(errors.ErrKeyInvalid, fmt.Sprintf("failed to decode key %s: %s", s, err.Error()))
+	}
+	return key, nil
+}
+
+// UnsafeKey returns true if the key has characters that are not safe in S3 object keys and URLs, i.e. that
+// SafeKeyCodec encodes
+func UnsafeKey(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if !safeKeyChar(key[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeKeyChar returns true if the byte is a safe key character
+func safeKeyChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!-_.*'()/", c) >= 0
+}
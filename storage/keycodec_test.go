@@ -0,0 +1,33 @@
+package storage_test
+
+import (
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSafeKeyCodec(t *testing.T) {
+	sut := storage.SafeKeyCodec{}
+	testCases := []struct {
+		key     string
+		encoded string
+	}{
+		{"foo", "foo"},
+		{"folder/file-1_a.json", "folder/file-1_a.json"},
+		{"a b", "a%20b"},
+		{"100%", "100%25"},
+		{"a+b&c", "a%2Bb%26c"},
+		{"ümlaut", "%C3%BCmlaut"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.encoded, sut.EncodeKey(tc.key))
+		key, err := sut.DecodeKey(tc.encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.key, key)
+		assert.Equal(t, tc.key != tc.encoded, storage.UnsafeKey(tc.key))
+	}
+
+	_, err := sut.DecodeKey("%zz")
+	assert.ErrorIs(t, err, errors.ErrKeyInvalid)
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	"reflect"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Each adapter package numbers its own options from the start of its range, so that they do not collide
+const (
+	//OptRangeMemory the first memory adapter option
+	OptRangeMemory = 100
+	//OptRangeValkey the first valkey adapter option
+	OptRangeValkey = 200
+	//OptRangeS3 the first S3 bucket adapter option
+	OptRangeS3 = 300
+	//OptRangeUser the first option for your own adapters. Use a separate range of 100 for each adapter
+	OptRangeUser = 1000
+)
+
+// Option sets a storage option. Adapter constructors accept Options to change the defaults
+type Option func(opts StorageOptions)
+
+// WithNamespace sets OptNamespace
+func WithNamespace(namespace string) Option {
+	return WithOption(OptNamespace, namespace)
+}
+
+// WithKeyPattern sets OptKeyPattern, the regular expression that keys must match
+func WithKeyPattern(pattern string) Option {
+	return WithOption(OptKeyPattern, pattern)
+}
+
+// WithReadable sets OptReadable
+func WithReadable(readable bool) Option {
+	return WithOption(OptReadable, readable)
+}
+
+// WithWritable sets OptWritable
+func WithWritable(writable bool) Option {
+	return WithOption(OptWritable, writable)
+}
+
+// WithTTL sets OptTTL
+func WithTTL(ttl time.Duration) Option {
+	return WithOption(OptTTL, ttl)
+}
+
+// WithMaxKeyLength sets OptMaxKeyLength. 0 is unlimited
+func WithMaxKeyLength(n int) Option {
+	return WithOption(OptMaxKeyLength, n)
+}
+
+// WithMaxValueLength sets OptMaxValueLength. 0 is unlimited
+func WithMaxValueLength(n int) Option {
+	return WithOption(OptMaxValueLength, n)
+}
+
+// WithDataTypes sets OptDataTypes
+func WithDataTypes(dTypes DataTypes) Option {
+	return WithOption(OptDataTypes, dTypes)
+}
+
+// WithCodec sets OptCodec
+func WithCodec(codec Codec) Option {
+	return WithOption(OptCodec, codec)
+}
+
+// WithKeyCodec sets OptKeyCodec
+func WithKeyCodec(codec KeyCodec) Option {
+	return WithOption(OptKeyCodec, codec)
+}
+
+// WithKeyHashing sets OptKeyHashing
+func WithKeyHashing(hashing KeyHashing) Option {
+	return WithOption(OptKeyHashing, hashing)
+}
+
+// WithNegativeTTL sets OptNegativeTTL
+func WithNegativeTTL(ttl time.Duration) Option {
+	return WithOption(OptNegativeTTL, ttl)
+}
+
+// WithSoftTTL sets OptSoftTTL
+func WithSoftTTL(ttl time.Duration) Option {
+	return WithOption(OptSoftTTL, ttl)
+}
+
+// WithRefreshLoader sets OptRefreshLoader
+func WithRefreshLoader(loader RefreshLoader) Option {
+	return WithOption(OptRefreshLoader, loader)
+}
+
+// WithXFetchBeta sets OptXFetchBeta
+func WithXFetchBeta(beta float64) Option {
+	return WithOption(OptXFetchBeta, beta)
+}
+
+// WithOption sets any option. The value is checked when the options are validated
+func WithOption(opt int, v any) Option {
+	return func(opts StorageOptions) {
+		opts[opt] = v
+	}
+}
+
+// Apply sets the options
+func (o StorageOptions) Apply(opts ...Option) StorageOptions {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Namespace returns OptNamespace, or "" if it is not set
+func (o StorageOptions) Namespace() string {
+	return o.String(OptNamespace, "")
+}
+
+// KeyPattern returns OptKeyPattern, or "" if it is not set
+func (o StorageOptions) KeyPattern() string {
+	return o.String(OptKeyPattern, "")
+}
+
+// Readable returns OptReadable, or true if it is not set
+func (o StorageOptions) Readable() bool {
+	return o.Bool(OptReadable, true)
+}
+
+// Writable returns OptWritable, or true if it is not set
+func (o StorageOptions) Writable() bool {
+	return o.Bool(OptWritable, true)
+}
+
+// TTL returns OptTTL, or 0 if it is not set
+func (o StorageOptions) TTL() time.Duration {
+	return o.Duration(OptTTL, 0)
+}
+
+// MaxKeyLength returns OptMaxKeyLength, or 0 (unlimited) if it is not set
+func (o StorageOptions) MaxKeyLength() int {
+	return o.Int(OptMaxKeyLength, 0)
+}
+
+// MaxValueLength returns OptMaxValueLength, or 0 (unlimited) if it is not set
+func (o StorageOptions) MaxValueLength() int {
+	return o.Int(OptMaxValueLength, 0)
+}
+
+// DataTypes returns OptDataTypes, or DefaultDataTypes if it is not set
+func (o StorageOptions) DataTypes() DataTypes {
+	return OptionValue(o, OptDataTypes, DefaultDataTypes)
+}
+
+// Codec returns OptCodec, or nil if it is not set
+func (o StorageOptions) Codec() Codec {
+	return OptionValue[Codec](o, OptCodec, nil)
+}
+
+// KeyCodec returns OptKeyCodec, or nil if it is not set
+func (o StorageOptions) KeyCodec() KeyCodec {
+	return OptionValue[KeyCodec](o, OptKeyCodec, nil)
+}
+
+// KeyHashing returns OptKeyHashing, and true if it is set
+func (o StorageOptions) KeyHashing() (KeyHashing, bool) {
+	h, ok := o[OptKeyHashing].(KeyHashing)
+	return h, ok
+}
+
+// NegativeTTL returns OptNegativeTTL, or 0 if it is not set
+func (o StorageOptions) NegativeTTL() time.Duration {
+	return o.Duration(OptNegativeTTL, 0)
+}
+
+// SoftTTL returns OptSoftTTL, or 0 if it is not set
+func (o StorageOptions) SoftTTL() time.Duration {
+	return o.Duration(OptSoftTTL, 0)
+}
+
+// RefreshLoader returns OptRefreshLoader, or nil if it is not set
+func (o StorageOptions) RefreshLoader() RefreshLoader {
+	return OptionValue[RefreshLoader](o, OptRefreshLoader, nil)
+}
+
+// XFetchBeta returns OptXFetchBeta, or 0 if it is not set
+func (o StorageOptions) XFetchBeta() float64 {
+	return OptionValue[float64](o, OptXFetchBeta, 0)
+}
+
+// String returns a string option, or def if it is not set or is not a string
+func (o StorageOptions) String(opt int, def string) string {
+	return OptionValue(o, opt, def)
+}
+
+// Bool returns a bool option, or def if it is not set or is not a bool
+func (o StorageOptions) Bool(opt int, def bool) bool {
+	return OptionValue(o, opt, def)
+}
+
+// Int returns an int option, or def if it is not set or is not an int
+func (o StorageOptions) Int(opt int, def int) int {
+	return OptionValue(o, opt, def)
+}
+
+// Duration returns a time.Duration option, or def if it is not set or is not a time.Duration
+func (o StorageOptions) Duration(opt int, def time.Duration) time.Duration {
+	return OptionValue(o, opt, def)
+}
+
+// OptionValue returns an option of any type, or def if it is not set or is not a T
+func OptionValue[T any](o StorageOptions, opt int, def T) T {
+	v, ok := o[opt].(T)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// optionDef describes an option for validation
+type optionDef struct {
+	name     string
+	validate func(v any) string
+}
+
+var (
+	optionDefsMu sync.RWMutex
+	optionDefs   = make(map[int]optionDef)
+)
+
+func init() {
+	DefineOption[string](OptNamespace, "namespace", nil)
+	DefineOption(OptKeyPattern, "keyPattern", func(pattern string) string {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err.Error()
+		}
+		return ""
+	})
+	DefineOption[bool](OptReadable, "readable", nil)
+	DefineOption[bool](OptWritable, "writable", nil)
+	DefineOption(OptTTL, "ttl", NotNegative[time.Duration])
+	DefineOption(OptMaxKeyLength, "maxKeyLength", NotNegative[int])
+	DefineOption(OptMaxValueLength, "maxValueLength", NotNegative[int])
+	DefineOption[DataTypes](OptDataTypes, "dataTypes", nil)
+	DefineOption[Codec](OptCodec, "codec", nil)
+	DefineOption[KeyCodec](OptKeyCodec, "keyCodec", nil)
+	DefineOption(OptKeyHashing, "keyHashing", func(h KeyHashing) string {
+		return NotNegative(h.MaxLength)
+	})
+	DefineOption(OptNegativeTTL, "negativeTtl", NotNegative[time.Duration])
+	DefineOption(OptSoftTTL, "softTtl", NotNegative[time.Duration])
+	DefineOption[RefreshLoader](OptRefreshLoader, "refreshLoader", nil)
+	DefineOption(OptXFetchBeta, "xfetchBeta", NotNegative[float64])
+}
+
+// DefineOption declares the name and type of an option, so that it is checked when options are validated.
+// check, if not nil, returns the reason a value of the right type is invalid, or "".
+// Adapters define their options in an init function. DefineOption panics if the option is already defined, which
+// catches options that collide
+func DefineOption[T any](opt int, name string, check func(v T) string) {
+	optionDefsMu.Lock()
+	defer optionDefsMu.Unlock()
+	if d, dup := optionDefs[opt]; dup {
+		panic(fmt.Sprintf("storage: option %d (%s) is already defined as %s", opt, name, d.name))
+	}
+	optionDefs[opt] = optionDef{
+		name: name,
+		validate: func(v any) string {
+			t, ok := v.(T)
+			if !ok {
+				return fmt.Sprintf("expected %s, got %T", reflect.TypeFor[T]().String(), v)
+			}
+			if check == nil {
+				return ""
+			}
+			return check(t)
+		},
+	}
+}
+
+// NotNegative is an option check that returns a reason if the value is negative
+func NotNegative[T int | int64 | float64 | time.Duration](v T) string {
+	if v < 0 {
+		return "must not be negative"
+	}
+	return ""
+}
+
+// Validate checks the type, and any constraint, of each defined option that is set. A nil value is not set.
+// It returns an errors.ValidationErrors whose paths are the option names, or nil if the options are valid
+func (o StorageOptions) Validate() error {
+	optionDefsMu.RLock()
+	defer optionDefsMu.RUnlock()
+	var verrs errors.ValidationErrors
+	opts := make([]int, 0, len(o))
+	for opt := range o {
+		opts = append(opts, opt)
+	}
+	slices.Sort(opts)
+	for _, opt := range opts {
+		d, ok := optionDefs[opt]
+		if !ok || o[opt] == nil {
+			continue
+		}
+		if msg := d.validate(o[opt]); msg != "" {
+			verrs = append(verrs, &errors.ValidationError{Path: d.name, Message: msg})
+		}
+	}
+	if len(verrs) == 0 {
+		return nil
+	}
+	return verrs
+}
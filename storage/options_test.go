@@ -0,0 +1,90 @@
+package storage_test
+
+import (
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStorageOptions_Accessors(t *testing.T) {
+	opts := storage.StorageOptions{}
+	assert.Equal(t, "", opts.Namespace())
+	assert.Equal(t, "", opts.KeyPattern())
+	assert.True(t, opts.Readable())
+	assert.True(t, opts.Writable())
+	assert.Equal(t, time.Duration(0), opts.TTL())
+	assert.Equal(t, 0, opts.MaxKeyLength())
+	assert.Equal(t, 0, opts.MaxValueLength())
+	assert.Equal(t, storage.DefaultDataTypes, opts.DataTypes())
+	assert.Nil(t, opts.Codec())
+
+	opts.Apply(
+		storage.WithNamespace("ns:"),
+		storage.WithKeyPattern("^[a-z]+$"),
+		storage.WithReadable(false),
+		storage.WithWritable(false),
+		storage.WithTTL(time.Minute),
+		storage.WithMaxKeyLength(10),
+		storage.WithMaxValueLength(100),
+		storage.WithDataTypes(storage.DataTypes{storage.TypeString: true}),
+		storage.WithCodec(storage.JsonCodec{}),
+	)
+	assert.Equal(t, "ns:", opts.Namespace())
+	assert.Equal(t, "^[a-z]+$", opts.KeyPattern())
+	assert.False(t, opts.Readable())
+	assert.False(t, opts.Writable())
+	assert.Equal(t, time.Minute, opts.TTL())
+	assert.Equal(t, 10, opts.MaxKeyLength())
+	assert.Equal(t, 100, opts.MaxValueLength())
+	assert.Equal(t, storage.DataTypes{storage.TypeString: true}, opts.DataTypes())
+	assert.Equal(t, storage.JsonCodec{}, opts.Codec())
+	assert.NoError(t, opts.Validate())
+
+	//the wrong type does not panic, and returns the default
+	opts[storage.OptTTL] = "5m"
+	assert.Equal(t, time.Duration(0), opts.TTL())
+	assert.Equal(t, time.Second, opts.Duration(storage.OptTTL, time.Second))
+	assert.Equal(t, "5m", opts.String(storage.OptTTL, ""))
+}
+
+func TestStorageOptions_Validate(t *testing.T) {
+	opts := storage.StorageOptions{
+		storage.OptNamespace:    1,
+		storage.OptReadable:     "true",
+		storage.OptTTL:          -time.Second,
+		storage.OptMaxKeyLength: -1,
+		storage.OptCodec:        nil,
+		storage.OptRangeUser:    "undefined options are not checked",
+	}
+	err := opts.Validate()
+	assert.ErrorIs(t, err, errors.ErrValidation)
+	var verrs errors.ValidationErrors
+	assert.True(t, errs.As(err, &verrs))
+	assert.Len(t, verrs, 4)
+	assert.Equal(t, "namespace: expected string, got int", verrs[0].Error())
+	assert.Equal(t, "readable: expected bool, got string", verrs[1].Error())
+	assert.Equal(t, "ttl: must not be negative", verrs[2].Error())
+	assert.Equal(t, "maxKeyLength: must not be negative", verrs[3].Error())
+}
+
+func TestDefineOption(t *testing.T) {
+	opt := storage.OptRangeUser + 1
+	storage.DefineOption(opt, "size", func(v int) string {
+		if v > 10 {
+			return "too big"
+		}
+		return ""
+	})
+	assert.NoError(t, storage.StorageOptions{opt: 10}.Validate())
+	assert.EqualError(t, storage.StorageOptions{opt: 11}.Validate(), "validation failed: size: too big")
+
+	assert.Panics(t, func() {
+		storage.DefineOption[string](opt, "other", nil)
+	})
+	assert.Panics(t, func() {
+		storage.DefineOption[string](storage.OptTTL, "ttl", nil)
+	})
+}
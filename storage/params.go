@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Kind is the type of a named parameter value, see ParamsFactory
+type Kind int
+
+const (
+	//KindString a JSON string
+	KindString Kind = iota
+	//KindBool a JSON boolean
+	KindBool
+	//KindInt a JSON integer
+	KindInt
+	//KindDuration a JSON string parsed with time.ParseDuration, e.g. "5m"
+	KindDuration
+	//KindCodec a JSON string naming a value codec: "json", "gob" or "raw"
+	KindCodec
+	//KindFloat a JSON number
+	KindFloat
+)
+
+// codecs are the value codecs that can be named by a KindCodec parameter
+var codecs = map[string]Codec{
+	"json": JsonCodec{},
+	"gob":  GobCodec{},
+	"raw":  RawCodec{},
+}
+
+// Convert returns the value, as decoded from JSON, converted to the Go type of the kind: string, bool, int,
+// time.Duration, Codec or float64.
+// It returns the reason if the value cannot be converted
+func (k Kind) Convert(v any) (any, string) {
+	switch k {
+	case KindString:
+		if s, ok := v.(string); ok {
+			return s, ""
+		}
+		return nil, fmt.Sprintf("expected a string, got %s", jsonType(v))
+	case KindBool:
+		if b, ok := v.(bool); ok {
+			return b, ""
+		}
+		return nil, fmt.Sprintf("expected a boolean, got %s", jsonType(v))
+	case KindInt:
+		switch n := v.(type) {
+		case int:
+			return n, ""
+		case json.Number:
+			if i, err := n.Int64(); err == nil {
+				return int(i), ""
+			}
+		case float64:
+			if n == math.Trunc(n) {
+				return int(n), ""
+			}
+		}
+		return nil, fmt.Sprintf("expected an integer, got %s", jsonType(v))
+	case KindDuration:
+		switch d := v.(type) {
+		case time.Duration:
+			return d, ""
+		case string:
+			ttl, err := time.ParseDuration(d)
+			if err != nil {
+				return nil, fmt.Sprintf("expected a duration, e.g. \"5m\": %s", err.Error())
+			}
+			return ttl, ""
+		}
+		return nil, fmt.Sprintf("expected a duration string, e.g. \"5m\", got %s", jsonType(v))
+	case KindCodec:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Sprintf("expected a codec name, got %s", jsonType(v))
+		}
+		codec, ok := codecs[s]
+		if !ok {
+			return nil, fmt.Sprintf("unknown codec %q, expected json, gob or raw", s)
+		}
+		return codec, ""
+	case KindFloat:
+		switch n := v.(type) {
+		case float64:
+			return n, ""
+		case int:
+			return float64(n), ""
+		case json.Number:
+			if f, err := n.Float64(); err == nil {
+				return f, ""
+			}
+		}
+		return nil, fmt.Sprintf("expected a number, got %s", jsonType(v))
+	}
+	return nil, "unknown parameter kind"
+}
+
+// jsonType returns the JSON type name of a decoded value
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number, float64, int:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// CommonParam is a parameter that every ParamsFactory accepts, and the option that it sets
+type CommonParam struct {
+	Kind Kind
+	Opt  int
+}
+
+// CommonParams are the parameters that are set as options on every storage created by a ParamsFactory, after it is
+// created, keyed by name
+var CommonParams = map[string]CommonParam{
+	"namespace":      {KindString, OptNamespace},
+	"keyPattern":     {KindString, OptKeyPattern},
+	"readable":       {KindBool, OptReadable},
+	"writable":       {KindBool, OptWritable},
+	"maxKeyLength":   {KindInt, OptMaxKeyLength},
+	"maxValueLength": {KindInt, OptMaxValueLength},
+	"codec":          {KindCodec, OptCodec},
+	"negativeTtl":    {KindDuration, OptNegativeTTL},
+	"softTtl":        {KindDuration, OptSoftTTL},
+	"xfetchBeta":     {KindFloat, OptXFetchBeta},
+}
+
+// Params are named parameter values, converted to the Go type of their Kind
+type Params map[string]any
+
+// Has returns true if the parameter is set
+func (p Params) Has(name string) bool {
+	_, ok := p[name]
+	return ok
+}
+
+// String returns a KindString parameter, or def if it is not set
+func (p Params) String(name, def string) string {
+	return paramValue(p, name, def)
+}
+
+// Bool returns a KindBool parameter, or def if it is not set
+func (p Params) Bool(name string, def bool) bool {
+	return paramValue(p, name, def)
+}
+
+// Int returns a KindInt parameter, or def if it is not set
+func (p Params) Int(name string, def int) int {
+	return paramValue(p, name, def)
+}
+
+// Duration returns a KindDuration parameter, or def if it is not set
+func (p Params) Duration(name string, def time.Duration) time.Duration {
+	return paramValue(p, name, def)
+}
+
+// Float returns a KindFloat parameter, or def if it is not set
+func (p Params) Float(name string, def float64) float64 {
+	return paramValue(p, name, def)
+}
+
+// paramValue returns the parameter, or def if it is not set
+func paramValue[T any](p Params, name string, def T) T {
+	v, ok := p[name].(T)
+	if !ok {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,21 @@
+package storage
+
+import "context"
+
+// RefreshLoader loads the current value for a key, to refresh an item that is stale, see OptSoftTTL.
+// It returns errors.ErrKeyNotFound if the key no longer has a value
+type RefreshLoader func(ctx context.Context, key string) (any, error)
+
+// ReadThrough is implemented by adapters that can load and cache missing values on read
+type ReadThrough interface {
+	//GetOrSet returns the value for key. If the key is not found, the loader is called and its value is set in the
+	//storage and returned. Concurrent calls for the same key share a single call to the loader.
+	//Loader errors are returned and nothing is set.
+	//With OptXFetchBeta, the time the loader takes is stored with the value, and a value that is found is occasionally
+	//recomputed before it expires, more often the closer it is to expiring and the longer it took to compute
+	GetOrSet(key string, loader func() (any, error)) (any, error)
+	//GetOrSetItems returns the values for keys. The loader is called once with the keys that are not found, and the
+	//values it returns are set in the storage. Concurrent calls for the same keys share a single call to the loader.
+	//Keys that the loader does not return a value for are not included in the returned values
+	GetOrSetItems(keys []string, loader func(keys []string) (map[string]any, error)) (map[string]any, error)
+}
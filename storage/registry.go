@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	errs "github.com/pkg/errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Factory creates a storage from a DSN. The storage is opened by OpenDSN
+type Factory func(dsn DSN) (Storage, error)
+
+// ParamsFactory creates a storage from named parameters, e.g. the options of a tier in a configuration file
+type ParamsFactory struct {
+	//Params the parameters that the storage accepts, in addition to the CommonParams, and their kinds
+	Params map[string]Kind
+	//Required the names of the parameters that must be set
+	Required []string
+	//New creates the storage from the converted parameters. The common parameters are set on the storage afterwards
+	New func(p Params) (Storage, error)
+}
+
+var (
+	factoriesMu     sync.RWMutex
+	factories       = make(map[string]Factory)
+	paramsFactories = make(map[string]ParamsFactory)
+)
+
+// Register makes a storage factory available by name, which is the DSN scheme, e.g. "valkey".
+// Adapters register themselves in an init function, so import the adapter package to use it.
+// Register panics if the factory is nil or the name is already registered
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("storage: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// RegisterParams makes a storage params factory available by name, alongside the DSN factory registered with
+// Register, if any. Adapters register it in the same init function.
+// RegisterParams panics if New is nil, a parameter shadows a common parameter, or the name is already registered
+func RegisterParams(name string, factory ParamsFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory.New == nil {
+		panic("storage: RegisterParams New is nil")
+	}
+	for param := range factory.Params {
+		if _, ok := CommonParams[param]; ok {
+			panic(fmt.Sprintf("storage: RegisterParams parameter %s is a common parameter", param))
+		}
+	}
+	if _, dup := paramsFactories[name]; dup {
+		panic("storage: RegisterParams called twice for " + name)
+	}
+	paramsFactories[name] = factory
+}
+
+// Registered returns the sorted names of the registered factories, of either kind
+func Registered() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories)+len(paramsFactories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	for name := range paramsFactories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// LookupParams returns the params factory registered for the name. A storage that only has a DSN factory has a
+// params factory with a single, required, "dsn" parameter, whose scheme must be the name
+func LookupParams(name string) (ParamsFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	if f, ok := paramsFactories[name]; ok {
+		return f, true
+	}
+	factory, ok := factories[name]
+	if !ok {
+		return ParamsFactory{}, false
+	}
+	return ParamsFactory{
+		Params:   map[string]Kind{"dsn": KindString},
+		Required: []string{"dsn"},
+		New: func(p Params) (Storage, error) {
+			d, err := ParseDSN(p.String("dsn", ""))
+			if err != nil {
+				return nil, err
+			}
+			if d.Scheme != name {
+				return nil, errs.Wrap(errors.ErrInvalidDSN, fmt.Sprintf("scheme %q, expected %q", d.Scheme, name))
+			}
+			return factory(d)
+		},
+	}, true
+}
+
+// Kind returns the kind of the named parameter, which may be a common parameter
+func (f ParamsFactory) Kind(name string) (Kind, bool) {
+	if c, ok := CommonParams[name]; ok {
+		return c.Kind, true
+	}
+	k, ok := f.Params[name]
+	return k, ok
+}
+
+// Create creates the storage from the converted parameters, and sets the common parameters as its options.
+// The storage is not opened
+func (f ParamsFactory) Create(p Params) (Storage, error) {
+	s, err := f.New(p)
+	if err != nil {
+		return nil, err
+	}
+	opts := s.GetOptions()
+	for name, v := range p {
+		if c, ok := CommonParams[name]; ok {
+			opts[c.Opt] = v
+		}
+	}
+	s.SetOptions(opts)
+	return s, nil
+}
+
+// OpenDSN creates a storage with the factory registered for the DSN scheme, and opens it, e.g.
+//
+//	storage.OpenDSN("valkey://localhost:6379/0?namespace=users:&ttl=5m&manageTypes=true")
+func OpenDSN(dsn string) (Storage, error) {
+	d, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	factoriesMu.RLock()
+	factory, ok := factories[d.Scheme]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, errs.Wrap(errors.ErrUnknownAdapter, fmt.Sprintf("%q, forgotten import?", d.Scheme))
+	}
+	s, err := factory(d)
+	if err != nil {
+		return nil, err
+	}
+	return s.Open()
+}
+
+// DSN is a parsed storage data source name
+type DSN struct {
+	*url.URL
+}
+
+// ParseDSN parses a storage data source name
+func ParseDSN(dsn string) (DSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return DSN{}, errs.Wrap(errors.ErrInvalidDSN, err.Error())
+	}
+	if u.Scheme == "" {
+		return DSN{}, errs.Wrap(errors.ErrInvalidDSN, "missing scheme")
+	}
+	return DSN{URL: u}, nil
+}
+
+// Validate returns an error wrapping errors.ErrInvalidDSN if the DSN has a query parameter that is not known
+func (d DSN) Validate(known ...string) error {
+	unknown := make([]string, 0)
+	for name := range d.Query() {
+		if !slices.Contains(known, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	slices.Sort(unknown)
+	return errs.Wrap(errors.ErrInvalidDSN, fmt.Sprintf("unknown parameters: %s", strings.Join(unknown, ", ")))
+}
+
+// Param returns the query parameter, or def if it is not set
+func (d DSN) Param(name, def string) string {
+	if !d.Query().Has(name) {
+		return def
+	}
+	return d.Query().Get(name)
+}
+
+// DurationParam returns the query parameter parsed with time.ParseDuration, or def if it is not set
+func (d DSN) DurationParam(name string, def time.Duration) (time.Duration, error) {
+	return param(d, name, def, time.ParseDuration)
+}
+
+// BoolParam returns the query parameter parsed with strconv.ParseBool, or def if it is not set
+func (d DSN) BoolParam(name string, def bool) (bool, error) {
+	return param(d, name, def, strconv.ParseBool)
+}
+
+// IntParam returns the query parameter parsed with strconv.Atoi, or def if it is not set
+func (d DSN) IntParam(name string, def int) (int, error) {
+	return param(d, name, def, strconv.Atoi)
+}
+
+// param returns the query parameter parsed with parse, or def if it is not set
+func param[T any](d DSN, name string, def T, parse func(string) (T, error)) (T, error) {
+	if !d.Query().Has(name) {
+		return def, nil
+	}
+	v, err := parse(d.Query().Get(name))
+	if err != nil {
+		return def, errs.Wrap(errors.ErrInvalidDSN, fmt.Sprintf("parameter %s: %s", name, err.Error()))
+	}
+	return v, nil
+}
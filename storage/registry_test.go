@@ -0,0 +1,132 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRegister(t *testing.T) {
+	var got storage.DSN
+	storage.Register("test-register", func(dsn storage.DSN) (storage.Storage, error) {
+		got = dsn
+		return nil, errors.ErrNotImplemented
+	})
+	assert.Contains(t, storage.Registered(), "test-register")
+
+	_, err := storage.OpenDSN("test-register://host/path?a=b")
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+	assert.Equal(t, "host", got.Host)
+	assert.Equal(t, "b", got.Param("a", ""))
+
+	assert.Panics(t, func() {
+		storage.Register("test-register", func(dsn storage.DSN) (storage.Storage, error) { return nil, nil })
+	})
+	assert.Panics(t, func() {
+		storage.Register("test-nil", nil)
+	})
+}
+
+func TestRegisterParams(t *testing.T) {
+	storage.RegisterParams("test-params", storage.ParamsFactory{
+		Params: map[string]storage.Kind{"ttl": storage.KindDuration},
+		New: func(p storage.Params) (storage.Storage, error) {
+			return nil, errors.ErrNotImplemented
+		},
+	})
+	assert.Contains(t, storage.Registered(), "test-params")
+	f, ok := storage.LookupParams("test-params")
+	assert.True(t, ok)
+	k, ok := f.Kind("ttl")
+	assert.True(t, ok)
+	assert.Equal(t, storage.KindDuration, k)
+	k, ok = f.Kind("codec")
+	assert.True(t, ok)
+	assert.Equal(t, storage.KindCodec, k)
+	_, ok = f.Kind("unknown")
+	assert.False(t, ok)
+	_, err := f.Create(storage.Params{"ttl": time.Minute})
+	assert.ErrorIs(t, err, errors.ErrNotImplemented)
+	_, ok = storage.LookupParams("test-unknown")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		storage.RegisterParams("test-params", storage.ParamsFactory{New: func(p storage.Params) (storage.Storage, error) { return nil, nil }})
+	})
+	assert.Panics(t, func() {
+		storage.RegisterParams("test-shadow", storage.ParamsFactory{
+			Params: map[string]storage.Kind{"namespace": storage.KindString},
+			New:    func(p storage.Params) (storage.Storage, error) { return nil, nil },
+		})
+	})
+	assert.Panics(t, func() {
+		storage.RegisterParams("test-nil", storage.ParamsFactory{})
+	})
+}
+
+func TestKind_Convert(t *testing.T) {
+	v, msg := storage.KindDuration.Convert("5m")
+	assert.Empty(t, msg)
+	assert.Equal(t, time.Minute*5, v)
+	v, msg = storage.KindInt.Convert(json.Number("3"))
+	assert.Empty(t, msg)
+	assert.Equal(t, 3, v)
+	v, msg = storage.KindFloat.Convert(json.Number("0.5"))
+	assert.Empty(t, msg)
+	assert.Equal(t, 0.5, v)
+	v, msg = storage.KindCodec.Convert("gob")
+	assert.Empty(t, msg)
+	assert.Equal(t, storage.GobCodec{}, v)
+	_, msg = storage.KindBool.Convert("yes")
+	assert.Equal(t, "expected a boolean, got string", msg)
+	_, msg = storage.KindInt.Convert(1.5)
+	assert.Equal(t, "expected an integer, got number", msg)
+}
+
+func TestOpenDSN_Errors(t *testing.T) {
+	_, err := storage.OpenDSN("unknown://host")
+	assert.ErrorIs(t, err, errors.ErrUnknownAdapter)
+
+	_, err = storage.OpenDSN("no-scheme")
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+
+	_, err = storage.OpenDSN("://host")
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+}
+
+func TestDSN_Params(t *testing.T) {
+	dsn, err := storage.ParseDSN("test://?ttl=5m&flag=true&n=3&bad=x")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "5m", dsn.Param("ttl", ""))
+	assert.Equal(t, "def", dsn.Param("missing", "def"))
+
+	d, err := dsn.DurationParam("ttl", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute*5, d)
+	d, err = dsn.DurationParam("missing", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+	_, err = dsn.DurationParam("bad", 0)
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+
+	b, err := dsn.BoolParam("flag", false)
+	assert.NoError(t, err)
+	assert.True(t, b)
+	_, err = dsn.BoolParam("bad", false)
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+
+	n, err := dsn.IntParam("n", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	_, err = dsn.IntParam("bad", 0)
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+
+	assert.NoError(t, dsn.Validate("ttl", "flag", "n", "bad"))
+	err = dsn.Validate("ttl", "flag")
+	assert.ErrorIs(t, err, errors.ErrInvalidDSN)
+	assert.Contains(t, err.Error(), "unknown parameters: bad, n")
+}
@@ -0,0 +1,150 @@
+package storage
+
+import "context"
+
+// StorageContext is the context aware variant of the Storage interface.
+// The context is passed through to the cache backend and to any chained adapters so that deadlines and cancellations
+// are honoured for the whole chain
+type StorageContext interface {
+	Storage
+	//GetItemCtx returns the value for stored item identified by key
+	GetItemCtx(ctx context.Context, key string) (any, error)
+	//GetItemsCtx returns multiple values
+	GetItemsCtx(ctx context.Context, keys []string) (map[string]any, error)
+	//HasItemCtx returns true if storage has the requested key, else false
+	HasItemCtx(ctx context.Context, key string) bool
+	//HasItemsCtx returns a keyed array of bools denoting if required keys are in the storage
+	HasItemsCtx(ctx context.Context, keys []string) map[string]bool
+	//SetItemCtx sets the value of the requested key. Returns true if set, else false and a possible error
+	SetItemCtx(ctx context.Context, key string, value any) (bool, error)
+	//SetItemsCtx sets multiple key values. Returns an array of keys not set and a possible error
+	SetItemsCtx(ctx context.Context, values map[string]any) ([]string, error)
+	//CheckAndSetItemCtx sets the value of the requested key if the key already exists. Returns true if set, else false and a possible error
+	CheckAndSetItemCtx(ctx context.Context, key string, value any) (bool, error)
+	//CheckAndSetItemsCtx sets the value of multiple requested keys if the keys already exist. Returns an array of keys not set and a possible error
+	CheckAndSetItemsCtx(ctx context.Context, values map[string]any) ([]string, error)
+	//TouchItemCtx resets the TTL for the given key. Returns true if reset, else false
+	TouchItemCtx(ctx context.Context, key string) bool
+	//TouchItemsCtx resets the TTL for multiple keys. Returns an array of keys not reset
+	TouchItemsCtx(ctx context.Context, keys []string) []string
+	//RemoveItemCtx removes (deletes) the requested key. Returns true if removed, else false
+	RemoveItemCtx(ctx context.Context, key string) bool
+	//RemoveItemsCtx removes (deletes) multiple key. Returns array of keys not removed
+	RemoveItemsCtx(ctx context.Context, keys []string) []string
+	//IncrementCtx increments the key value by n. If the key is none numeric or not found, an error will be returned
+	IncrementCtx(ctx context.Context, key string, n int64) (int64, error)
+	//DecrementCtx decrements the key value by n. If the key is none numeric or not found, an error will be returned
+	DecrementCtx(ctx context.Context, key string, n int64) (int64, error)
+}
+
+// WithContext returns s as a StorageContext. If s does not support contexts natively, it is wrapped so that a
+// cancelled or expired context is checked before each call is delegated to s
+func WithContext(s Storage) StorageContext {
+	if sc, ok := s.(StorageContext); ok {
+		return sc
+	}
+	return &contextWrapper{Storage: s}
+}
+
+// contextWrapper adapts a plain Storage to the StorageContext interface
+type contextWrapper struct {
+	Storage
+}
+
+func (w *contextWrapper) GetItemCtx(ctx context.Context, key string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return w.GetItem(key)
+}
+
+func (w *contextWrapper) GetItemsCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return map[string]any{}, err
+	}
+	return w.GetItems(keys)
+}
+
+func (w *contextWrapper) HasItemCtx(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return w.HasItem(key)
+}
+
+func (w *contextWrapper) HasItemsCtx(ctx context.Context, keys []string) map[string]bool {
+	if ctx.Err() != nil {
+		return map[string]bool{}
+	}
+	return w.HasItems(keys)
+}
+
+func (w *contextWrapper) SetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return w.SetItem(key, value)
+}
+
+func (w *contextWrapper) SetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	return w.SetItems(values)
+}
+
+func (w *contextWrapper) CheckAndSetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return w.CheckAndSetItem(key, value)
+}
+
+func (w *contextWrapper) CheckAndSetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	return w.CheckAndSetItems(values)
+}
+
+func (w *contextWrapper) TouchItemCtx(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return w.TouchItem(key)
+}
+
+func (w *contextWrapper) TouchItemsCtx(ctx context.Context, keys []string) []string {
+	if ctx.Err() != nil {
+		return []string{}
+	}
+	return w.TouchItems(keys)
+}
+
+func (w *contextWrapper) RemoveItemCtx(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return w.RemoveItem(key)
+}
+
+func (w *contextWrapper) RemoveItemsCtx(ctx context.Context, keys []string) []string {
+	if ctx.Err() != nil {
+		return []string{}
+	}
+	return w.RemoveItems(keys)
+}
+
+func (w *contextWrapper) IncrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.Increment(key, n)
+}
+
+func (w *contextWrapper) DecrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.Decrement(key, n)
+}
@@ -7,9 +7,16 @@ const (
 	OptReadable
 	OptWritable
 	OptTTL
-	OptMaxKeyLength   //future use
-	OptMaxValueLength //future use
-	OptDataTypes      //future use
+	OptMaxKeyLength   //the maximum length of the key sent to the backend, 0 is unlimited. type: int
+	OptMaxValueLength //the maximum size of the encoded value sent to the backend, 0 is unlimited. type: int
+	OptDataTypes      //the data types that can be written, unless there is a codec. type: storage.DataTypes
+	OptCodec          //the value Codec, if any. type: storage.Codec
+	OptKeyCodec       //the KeyCodec, if any. type: storage.KeyCodec
+	OptKeyHashing     //hash keys that are too long or unsafe, if set. type: storage.KeyHashing
+	OptNegativeTTL    //how long a key that is not found is remembered as not found, 0 is not remembered. type: time.Duration
+	OptSoftTTL        //how long an item is fresh, after which it is served stale and refreshed, 0 is always fresh. type: time.Duration
+	OptRefreshLoader  //loads the value of a stale item, if set, else it is read from the chained adapter. type: storage.RefreshLoader
+	OptXFetchBeta     //how early GetOrSet recomputes items with the XFetch algorithm, 0 is not early. type: float64
 )
 
 type StorageOptions map[int]any
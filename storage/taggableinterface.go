@@ -0,0 +1,21 @@
+package storage
+
+import "context"
+
+// Taggable is implemented by adapters that can tag items and clear items by their tags
+type Taggable interface {
+	//SetTags sets the tags for an existing item, replacing any existing tags. Setting no tags removes the item's tags.
+	//Returns true if set, else false and a possible error
+	SetTags(key string, tags ...string) (bool, error)
+	//GetTags returns the tags for an item, or errors.ErrKeyNotFound if the item does not exist
+	GetTags(key string) ([]string, error)
+	//ClearByTags removes the items that have all the tags, or if disjunction is true, any of the tags.
+	//Returns true if cleared, else false and a possible error
+	ClearByTags(tags []string, disjunction bool) (bool, error)
+	//SetTagsCtx is the context aware variant of SetTags
+	SetTagsCtx(ctx context.Context, key string, tags ...string) (bool, error)
+	//GetTagsCtx is the context aware variant of GetTags
+	GetTagsCtx(ctx context.Context, key string) ([]string, error)
+	//ClearByTagsCtx is the context aware variant of ClearByTags
+	ClearByTagsCtx(ctx context.Context, tags []string, disjunction bool) (bool, error)
+}
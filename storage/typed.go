@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	errs "github.com/pkg/errors"
+	"time"
+)
+
+// TypedStorage wraps a Storage so that values are set and returned as type T, removing the need for callers to
+// type assert the values returned by the underlying adapter.
+// Values that are stored as strings by the underlying adapter, e.g. the Valkey adapter when types are not managed or
+// the S3 bucket adapter, are parsed into T where T is one of the supported DataTypes.
+// Other values, e.g. those decoded by the JsonCodec, and strings holding JSON are converted to T via JSON, so that
+// structs stored with a JsonCodec are returned as your own struct type.
+// If a stored value cannot be converted to T, an *errors.TypeConversionError is returned.
+type TypedStorage[T any] struct {
+	storage    Storage
+	dateFormat string
+}
+
+// NewTypedStorage returns a TypedStorage for the given storage
+func NewTypedStorage[T any](s Storage) *TypedStorage[T] {
+	return &TypedStorage[T]{
+		storage:    s,
+		dateFormat: time.RFC3339,
+	}
+}
+
+// WithDateFormat sets the format used to parse string values into time.Time. Defaults to time.RFC3339
+func (t *TypedStorage[T]) WithDateFormat(format string) *TypedStorage[T] {
+	t.dateFormat = format
+	return t
+}
+
+// Storage returns the underlying storage
+func (t *TypedStorage[T]) Storage() Storage {
+	return t.storage
+}
+
+func (t *TypedStorage[T]) SetOptions(opts StorageOptions) {
+	t.storage.SetOptions(opts)
+}
+
+func (t *TypedStorage[T]) GetOptions() StorageOptions {
+	return t.storage.GetOptions()
+}
+
+func (t *TypedStorage[T]) GetItem(key string) (T, error) {
+	v, err := t.storage.GetItem(key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return t.convert(key, v)
+}
+
+func (t *TypedStorage[T]) GetItems(keys []string) (map[string]T, error) {
+	vals, err := t.storage.GetItems(keys)
+	ret := make(map[string]T, len(vals))
+	for k, v := range vals {
+		tv, e := t.convert(k, v)
+		if e != nil {
+			err = e
+			continue
+		}
+		ret[k] = tv
+	}
+	return ret, err
+}
+
+func (t *TypedStorage[T]) HasItem(key string) bool {
+	return t.storage.HasItem(key)
+}
+
+func (t *TypedStorage[T]) HasItems(keys []string) map[string]bool {
+	return t.storage.HasItems(keys)
+}
+
+func (t *TypedStorage[T]) SetItem(key string, value T) (bool, error) {
+	return t.storage.SetItem(key, value)
+}
+
+func (t *TypedStorage[T]) SetItems(values map[string]T) ([]string, error) {
+	return t.storage.SetItems(toAnyMap(values))
+}
+
+func (t *TypedStorage[T]) CheckAndSetItem(key string, value T) (bool, error) {
+	return t.storage.CheckAndSetItem(key, value)
+}
+
+func (t *TypedStorage[T]) CheckAndSetItems(values map[string]T) ([]string, error) {
+	return t.storage.CheckAndSetItems(toAnyMap(values))
+}
+
+func (t *TypedStorage[T]) TouchItem(key string) bool {
+	return t.storage.TouchItem(key)
+}
+
+func (t *TypedStorage[T]) TouchItems(keys []string) []string {
+	return t.storage.TouchItems(keys)
+}
+
+func (t *TypedStorage[T]) RemoveItem(key string) bool {
+	return t.storage.RemoveItem(key)
+}
+
+func (t *TypedStorage[T]) RemoveItems(keys []string) []string {
+	return t.storage.RemoveItems(keys)
+}
+
+func (t *TypedStorage[T]) Increment(key string, n int64) (int64, error) {
+	return t.storage.Increment(key, n)
+}
+
+func (t *TypedStorage[T]) Decrement(key string, n int64) (int64, error) {
+	return t.storage.Decrement(key, n)
+}
+
+// Open opens the underlying storage
+func (t *TypedStorage[T]) Open() (*TypedStorage[T], error) {
+	s, err := t.storage.Open()
+	if err != nil {
+		return nil, err
+	}
+	t.storage = s
+	return t, nil
+}
+
+func (t *TypedStorage[T]) Close() error {
+	return t.storage.Close()
+}
+
+// convert converts a value returned by the underlying storage to T
+func (t *TypedStorage[T]) convert(key string, v any) (T, error) {
+	if tv, ok := v.(T); ok {
+		return tv, nil
+	}
+	var zero T
+	convErr := func(err error) error {
+		return &errors.TypeConversionError{Key: key, Value: v, Type: fmt.Sprintf("%T", zero), Err: err}
+	}
+	s, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return zero, convErr(errs.Wrap(err, "failed to convert value"))
+		}
+		return t.fromJson(key, v, b)
+	}
+	typ := GetType(zero)
+	if typ == TypeUnknown {
+		return t.fromJson(key, v, []byte(s))
+	}
+	tv, err := GetTypedValue(typ, s, t.dateFormat)
+	if err != nil {
+		if typ == TypeBytes {
+			//not a gob encoded value, so return the raw bytes
+			tv, err = []byte(s), nil
+		} else {
+			return zero, convErr(errs.Wrap(err, "failed to parse value"))
+		}
+	}
+	ret, ok := tv.(T)
+	if !ok {
+		return zero, convErr(nil)
+	}
+	return ret, nil
+}
+
+// fromJson unmarshals JSON data into T
+func (t *TypedStorage[T]) fromJson(key string, v any, data []byte) (T, error) {
+	var ret T
+	if err := json.Unmarshal(data, &ret); err != nil {
+		var zero T
+		return zero, &errors.TypeConversionError{Key: key, Value: v, Type: fmt.Sprintf("%T", zero), Err: err}
+	}
+	return ret, nil
+}
+
+func toAnyMap[T any](values map[string]T) map[string]any {
+	ret := make(map[string]any, len(values))
+	for k, v := range values {
+		ret[k] = v
+	}
+	return ret
+}
@@ -0,0 +1,131 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/chippyash/go-cache-manager/adapter"
+	"github.com/chippyash/go-cache-manager/adapter/bucket"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/adapter/valkey"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+type mockS3Client struct {
+	mock.Mock
+	bucket.S3Iface
+}
+
+func (m *mockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(options *s3.Options)) (*s3.GetObjectOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetObjectOutput), args.Error(1)
+}
+
+func TestTypedStorage_Memory(t *testing.T) {
+	sut := storage.NewTypedStorage[int64](memory.New("", time.Second*60, time.Second*120))
+	ok, err := sut.SetItem("foo", 10)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+
+	_, err = sut.SetItems(map[string]int64{"bar": 20, "baz": 30})
+	assert.NoError(t, err)
+	vals, err := sut.GetItems([]string{"foo", "bar", "baz"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"foo": 10, "bar": 20, "baz": 30}, vals)
+
+	_, err = sut.GetItem("bop")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+}
+
+func TestTypedStorage_ConversionError(t *testing.T) {
+	mem := memory.New("", time.Second*60, time.Second*120)
+	_, err := mem.SetItems(map[string]any{"foo": 10, "bar": true})
+	assert.NoError(t, err)
+
+	sut := storage.NewTypedStorage[int](mem)
+	val, err := sut.GetItem("bar")
+	assert.ErrorIs(t, err, errors.ErrTypeConversion)
+	var convErr *errors.TypeConversionError
+	assert.ErrorAs(t, err, &convErr)
+	assert.Equal(t, "bar", convErr.Key)
+	assert.Equal(t, "int", convErr.Type)
+	assert.Equal(t, 0, val)
+
+	vals, err := sut.GetItems([]string{"foo", "bar"})
+	assert.ErrorIs(t, err, errors.ErrTypeConversion)
+	assert.Equal(t, map[string]int{"foo": 10}, vals)
+}
+
+func TestTypedStorage_ValkeyUnmanaged(t *testing.T) {
+	rs := miniredis.RunT(t)
+	sut, err := storage.NewTypedStorage[time.Duration](valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, false)).Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItems(map[string]time.Duration{"foo": time.Second, "bar": time.Minute})
+	assert.NoError(t, err)
+
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, val)
+
+	vals, err := sut.GetItems([]string{"foo", "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"foo": time.Second, "bar": time.Minute}, vals)
+
+	rs.Set("baz", "not a duration")
+	_, err = sut.GetItem("baz")
+	assert.ErrorIs(t, err, errors.ErrTypeConversion)
+}
+
+func TestTypedStorage_ValkeyBytes(t *testing.T) {
+	rs := miniredis.RunT(t)
+	sut, err := storage.NewTypedStorage[[]byte](valkey.New("", rs.Addr(), time.Second*60, false, time.Second*0, false)).Open()
+	assert.NoError(t, err)
+	_, err = sut.SetItem("foo", []byte("bar"))
+	assert.NoError(t, err)
+
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), val)
+}
+
+func TestTypedStorage_Bucket(t *testing.T) {
+	s, err := bucket.New("testbucket", "folder/", ".json", bucket.MimeTypeJson, "eu-west-2")
+	assert.NoError(t, err)
+	mockS3 := new(mockS3Client)
+	s.(*adapter.AbstractAdapter).Client = mockS3
+	getInput := &s3.GetObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String("folder/key.json"),
+	}
+	getOutput := &s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewReader([]byte(`{"value":"foo"}`))),
+	}
+	mockS3.On("GetObject", context.TODO(), getInput).Return(getOutput, nil)
+
+	sut := storage.NewTypedStorage[[]byte](s)
+	val, err := sut.GetItem("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"value":"foo"}`), val)
+
+	mockS3.AssertExpectations(t)
+}
+
+func memoryWithCodec(codec storage.Codec) storage.Storage {
+	mem := memory.New("", time.Second*60, time.Second*120)
+	opts := mem.GetOptions()
+	opts[storage.OptCodec] = codec
+	mem.SetOptions(opts)
+	return mem
+}
@@ -0,0 +1,598 @@
+package tier
+
+import (
+	"context"
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OwnTTL is a Tier.BackfillTTL that backfills values with the default TTL of the tier being backfilled
+const OwnTTL time.Duration = -2
+
+// NotFound is the Result.Tier of a value that was not found in any tier
+const NotFound = -1
+
+// Tier is a storage.Storage in a Manager, with the policies that say how the Manager uses it.
+// The zero value policies backfill the tier and write through to it
+type Tier struct {
+	Storage storage.Storage
+	//NoBackfill stops a value found in a lower tier being set in this tier
+	NoBackfill bool
+	//BackfillTTL is the TTL a value found in a lower tier is set in this tier with.
+	//storage.DefaultTTL uses the remaining TTL of the value in the tier that served it, limited to the default TTL of
+	//this tier. OwnTTL uses the default TTL of this tier, storage.NoExpiry does not expire the value, and any other
+	//duration is used as is. Tiers that are not storage.Expirable are always backfilled with their default TTL
+	BackfillTTL time.Duration
+	//NoWriteThrough stops writes being set in this tier. The key is removed from this tier instead, so that it does
+	//not serve a stale value, and it is backfilled the next time it is read
+	NoWriteThrough bool
+}
+
+// Result is a value read from a Manager and the tier that served it
+type Result struct {
+	Value any
+	//Tier is the index of the tier that served the value, 0 being the top tier, or NotFound
+	Tier int
+}
+
+// Manager composes tiers of storage.Storage into a single cache. Reads are served by the first tier, from the top
+// down, that has the key, and the tiers above it are backfilled. Writes are set in the tiers, from the bottom up.
+// The tiers must not be chained to each other with storage.Chainable.
+// Failures to backfill a tier, or to remove a key from a tier that writes do not go through to, are not returned, as
+// the value is read or written, but are reported with storage.ReportFailure, see storage.WithFailureReporter.
+// Manager implements the Storage, StorageContext, Expirable, Flushable and Clearable interfaces
+type Manager struct {
+	tiers   []Tier
+	options storage.StorageOptions
+}
+
+// New returns a Manager for the tiers, from the top tier down. It panics if there are no tiers or a tier has no storage
+func New(tiers ...Tier) *Manager {
+	if len(tiers) == 0 {
+		panic("tier: no tiers")
+	}
+	for i, t := range tiers {
+		if t.Storage == nil {
+			panic(fmt.Sprintf("tier: tier %d has no storage", i))
+		}
+	}
+	return &Manager{
+		tiers:   tiers,
+		options: storage.StorageOptions{},
+	}
+}
+
+// Tiers returns the tiers, from the top tier down
+func (m *Manager) Tiers() []Tier {
+	return m.tiers
+}
+
+// Lookup returns the value for key and the tier that served it. The tiers above it are backfilled.
+// If no tier has the key, the first error other than errors.ErrKeyNotFound is returned, else errors.ErrKeyNotFound
+func (m *Manager) Lookup(ctx context.Context, key string) (Result, error) {
+	if !m.options.Readable() {
+		return Result{Tier: NotFound}, errors.ErrNotReadable
+	}
+	var ret error
+	for i, t := range m.tiers {
+		val, err := storage.WithContext(t.Storage).GetItemCtx(ctx, key)
+		if err == nil {
+			m.backfill(ctx, key, val, i)
+			return Result{Value: val, Tier: i}, nil
+		}
+		if ctx.Err() != nil {
+			return Result{Tier: NotFound}, ctx.Err()
+		}
+		if !errs.Is(err, errors.ErrKeyNotFound) && ret == nil {
+			ret = errs.Wrap(err, fmt.Sprintf("tier %d", i))
+		}
+	}
+	if ret == nil {
+		ret = errors.ErrKeyNotFound
+	}
+	return Result{Tier: NotFound}, ret
+}
+
+// LookupItems returns the values for keys and the tiers that served them. Keys that are not found are not returned
+func (m *Manager) LookupItems(ctx context.Context, keys []string) (map[string]Result, error) {
+	ret := make(map[string]Result)
+	var err error
+	for _, key := range keys {
+		res, e := m.Lookup(ctx, key)
+		if e != nil {
+			if ctx.Err() != nil {
+				return ret, ctx.Err()
+			}
+			if err == nil || errs.Is(err, errors.ErrKeyNotFound) {
+				err = e
+			}
+			continue
+		}
+		ret[key] = res
+	}
+	return ret, err
+}
+
+// backfill sets the value served by tier in the tiers above it that are backfilled
+func (m *Manager) backfill(ctx context.Context, key string, value any, served int) {
+	for i := served - 1; i >= 0; i-- {
+		t := m.tiers[i]
+		if t.NoBackfill {
+			continue
+		}
+		if _, err := storage.SetItemWithTTL(ctx, t.Storage, key, value, m.backfillTTL(ctx, t, key, served)); err != nil {
+			storage.ReportFailure(ctx, t.Storage, errs.Wrap(err, fmt.Sprintf("tier %d: failed to backfill %s", i, key)))
+		}
+	}
+}
+
+// invalidate removes the keys from tier, so that it does not serve stale values. The keys that it still has are
+// reported with storage.ReportFailure
+func (m *Manager) invalidate(ctx context.Context, tier int, keys []string) {
+	s := storage.WithContext(m.tiers[tier].Storage)
+	s.RemoveItemsCtx(ctx, keys)
+	stale := make([]string, 0)
+	for key, has := range s.HasItemsCtx(ctx, keys) {
+		if has {
+			stale = append(stale, key)
+		}
+	}
+	if len(stale) > 0 {
+		slices.Sort(stale)
+		storage.ReportFailure(ctx, m.tiers[tier].Storage, errs.Errorf("tier %d: failed to remove %s", tier, strings.Join(stale, ", ")))
+	}
+}
+
+// backfillTTL returns the TTL to backfill t with for a key served by tier
+func (m *Manager) backfillTTL(ctx context.Context, t Tier, key string, served int) time.Duration {
+	if t.BackfillTTL == OwnTTL {
+		return storage.DefaultTTL
+	}
+	if t.BackfillTTL != storage.DefaultTTL {
+		return t.BackfillTTL
+	}
+	e, ok := m.tiers[served].Storage.(storage.Expirable)
+	if !ok {
+		return storage.DefaultTTL
+	}
+	remaining, err := e.GetTTLCtx(ctx, key)
+	if err != nil {
+		return storage.DefaultTTL
+	}
+	own := t.Storage.GetOptions().TTL()
+	if own > 0 && (remaining == storage.NoExpiry || remaining > own) {
+		return own
+	}
+	if remaining > 0 || remaining == storage.NoExpiry {
+		return remaining
+	}
+	//the value has expired since it was read
+	return storage.DefaultTTL
+}
+
+// write calls set for each tier that writes go through to, from the bottom up, and removes key from the other tiers.
+// It returns true if every set succeeds, and the first error
+func (m *Manager) write(ctx context.Context, keys []string, set func(s storage.Storage) (bool, error)) (bool, error) {
+	if !m.options.Writable() {
+		return false, errors.ErrNotWritable
+	}
+	ok := true
+	var ret error
+	for i := len(m.tiers) - 1; i >= 0; i-- {
+		t := m.tiers[i]
+		if t.NoWriteThrough {
+			m.invalidate(ctx, i, keys)
+			continue
+		}
+		done, err := set(t.Storage)
+		ok = ok && done
+		if err != nil && ret == nil {
+			ret = errs.Wrap(err, fmt.Sprintf("tier %d", i))
+		}
+	}
+	return ok && ret == nil, ret
+}
+
+// lowest returns the lowest tier that writes go through to, or the lowest tier if there is none
+func (m *Manager) lowest() int {
+	for i := len(m.tiers) - 1; i >= 0; i-- {
+		if !m.tiers[i].NoWriteThrough {
+			return i
+		}
+	}
+	return len(m.tiers) - 1
+}
+
+// count applies an increment or decrement to the lowest tier that writes go through to, and removes key from the
+// other tiers so that they are backfilled with the new value
+func (m *Manager) count(ctx context.Context, key string, fn func(s storage.StorageContext) (int64, error)) (int64, error) {
+	if !m.options.Writable() {
+		return 0, errors.ErrNotWritable
+	}
+	lowest := m.lowest()
+	ret, err := fn(storage.WithContext(m.tiers[lowest].Storage))
+	for i := range m.tiers {
+		if i != lowest {
+			m.invalidate(ctx, i, []string{key})
+		}
+	}
+	return ret, err
+}
+
+// keysOf returns the keys of values
+func keysOf(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+/** Storage Interface **/
+
+// SetOptions sets the Manager options. Only storage.OptReadable and storage.OptWritable are used,
+// the tiers keep their own options
+func (m *Manager) SetOptions(opts storage.StorageOptions) {
+	m.options = opts
+}
+
+func (m *Manager) GetOptions() storage.StorageOptions {
+	return m.options
+}
+
+func (m *Manager) GetItem(key string) (any, error) {
+	return m.GetItemCtx(context.TODO(), key)
+}
+
+func (m *Manager) GetItems(keys []string) (map[string]any, error) {
+	return m.GetItemsCtx(context.TODO(), keys)
+}
+
+func (m *Manager) HasItem(key string) bool {
+	return m.HasItemCtx(context.TODO(), key)
+}
+
+func (m *Manager) HasItems(keys []string) map[string]bool {
+	return m.HasItemsCtx(context.TODO(), keys)
+}
+
+func (m *Manager) SetItem(key string, value any) (bool, error) {
+	return m.SetItemCtx(context.TODO(), key, value)
+}
+
+func (m *Manager) SetItems(values map[string]any) ([]string, error) {
+	return m.SetItemsCtx(context.TODO(), values)
+}
+
+func (m *Manager) CheckAndSetItem(key string, value any) (bool, error) {
+	return m.CheckAndSetItemCtx(context.TODO(), key, value)
+}
+
+func (m *Manager) CheckAndSetItems(values map[string]any) ([]string, error) {
+	return m.CheckAndSetItemsCtx(context.TODO(), values)
+}
+
+func (m *Manager) TouchItem(key string) bool {
+	return m.TouchItemCtx(context.TODO(), key)
+}
+
+func (m *Manager) TouchItems(keys []string) []string {
+	return m.TouchItemsCtx(context.TODO(), keys)
+}
+
+func (m *Manager) RemoveItem(key string) bool {
+	return m.RemoveItemCtx(context.TODO(), key)
+}
+
+func (m *Manager) RemoveItems(keys []string) []string {
+	return m.RemoveItemsCtx(context.TODO(), keys)
+}
+
+func (m *Manager) Increment(key string, n int64) (int64, error) {
+	return m.IncrementCtx(context.TODO(), key, n)
+}
+
+func (m *Manager) Decrement(key string, n int64) (int64, error) {
+	return m.DecrementCtx(context.TODO(), key, n)
+}
+
+// Open opens the tiers from the bottom up and returns the Manager. If a tier fails to open, the tiers that are
+// already open are closed
+func (m *Manager) Open() (storage.Storage, error) {
+	for i := len(m.tiers) - 1; i >= 0; i-- {
+		s, err := m.tiers[i].Storage.Open()
+		if err != nil {
+			for _, t := range m.tiers[i+1:] {
+				_ = t.Storage.Close()
+			}
+			return nil, errs.Wrap(err, fmt.Sprintf("failed to open tier %d", i))
+		}
+		m.tiers[i].Storage = s
+	}
+	return m, nil
+}
+
+// Close closes every tier, and returns the first error, if any
+func (m *Manager) Close() error {
+	var ret error
+	for _, t := range m.tiers {
+		if err := t.Storage.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+/** StorageContext Interface **/
+
+func (m *Manager) GetItemCtx(ctx context.Context, key string) (any, error) {
+	res, err := m.Lookup(ctx, key)
+	return res.Value, err
+}
+
+func (m *Manager) GetItemsCtx(ctx context.Context, keys []string) (map[string]any, error) {
+	res, err := m.LookupItems(ctx, keys)
+	ret := make(map[string]any, len(res))
+	for k, r := range res {
+		ret[k] = r.Value
+	}
+	return ret, err
+}
+
+// HasItemCtx returns true if any tier has the key. Nothing is backfilled
+func (m *Manager) HasItemCtx(ctx context.Context, key string) bool {
+	if !m.options.Readable() {
+		return false
+	}
+	for _, t := range m.tiers {
+		if storage.WithContext(t.Storage).HasItemCtx(ctx, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) HasItemsCtx(ctx context.Context, keys []string) map[string]bool {
+	ret := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		ret[key] = m.HasItemCtx(ctx, key)
+	}
+	return ret
+}
+
+// SetItemCtx sets the value in the tiers that writes go through to, with their default TTL
+func (m *Manager) SetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	return m.SetItemWithTTLCtx(ctx, key, value, storage.DefaultTTL)
+}
+
+// SetItemsCtx sets the values in the tiers that writes go through to, with their default TTL. Returns the keys
+func (m *Manager) SetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	return m.SetItemsWithTTLCtx(ctx, values, storage.DefaultTTL)
+}
+
+// CheckAndSetItemCtx sets the value in the tiers that writes go through to, and that have the key.
+// Returns true if any tier has the key
+func (m *Manager) CheckAndSetItemCtx(ctx context.Context, key string, value any) (bool, error) {
+	hit := false
+	_, err := m.write(ctx, []string{key}, func(s storage.Storage) (bool, error) {
+		ok, err := storage.WithContext(s).CheckAndSetItemCtx(ctx, key, value)
+		hit = hit || ok
+		if errs.Is(err, errors.ErrKeyNotFound) {
+			err = nil
+		}
+		return ok, err
+	})
+	if err == nil && !hit {
+		err = errors.ErrKeyNotFound
+	}
+	return hit, err
+}
+
+// CheckAndSetItemsCtx sets the values whose keys any tier has. Returns the keys that are set
+func (m *Manager) CheckAndSetItemsCtx(ctx context.Context, values map[string]any) ([]string, error) {
+	keys := make([]string, 0)
+	var err error
+	for key, value := range values {
+		ok, e := m.CheckAndSetItemCtx(ctx, key, value)
+		if !ok {
+			if err == nil || errs.Is(err, errors.ErrKeyNotFound) {
+				err = e
+			}
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, err
+}
+
+// TouchItemCtx resets the TTL of the key in every tier. Returns true if any tier has the key
+func (m *Manager) TouchItemCtx(ctx context.Context, key string) bool {
+	return m.TouchItemWithTTLCtx(ctx, key, storage.DefaultTTL)
+}
+
+// TouchItemsCtx resets the TTL of the keys in every tier. Returns the keys that any tier has
+func (m *Manager) TouchItemsCtx(ctx context.Context, keys []string) []string {
+	ret := make([]string, 0)
+	for _, key := range keys {
+		if m.TouchItemCtx(ctx, key) {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+// RemoveItemCtx removes the key from every tier. Returns true if every tier removes it
+func (m *Manager) RemoveItemCtx(ctx context.Context, key string) bool {
+	if !m.options.Writable() {
+		return false
+	}
+	ok := true
+	for i := len(m.tiers) - 1; i >= 0; i-- {
+		ok = storage.WithContext(m.tiers[i].Storage).RemoveItemCtx(ctx, key) && ok
+	}
+	return ok
+}
+
+// RemoveItemsCtx removes the keys from every tier. Returns the keys that any tier removes
+func (m *Manager) RemoveItemsCtx(ctx context.Context, keys []string) []string {
+	if !m.options.Writable() {
+		return []string{}
+	}
+	removed := make(map[string]bool, len(keys))
+	for i := len(m.tiers) - 1; i >= 0; i-- {
+		for _, key := range storage.WithContext(m.tiers[i].Storage).RemoveItemsCtx(ctx, keys) {
+			removed[key] = true
+		}
+	}
+	ret := make([]string, 0, len(removed))
+	for _, key := range keys {
+		if removed[key] {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+// IncrementCtx increments the key in the lowest tier that writes go through to, and removes it from the other tiers
+func (m *Manager) IncrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	return m.count(ctx, key, func(s storage.StorageContext) (int64, error) {
+		return s.IncrementCtx(ctx, key, n)
+	})
+}
+
+// DecrementCtx decrements the key in the lowest tier that writes go through to, and removes it from the other tiers
+func (m *Manager) DecrementCtx(ctx context.Context, key string, n int64) (int64, error) {
+	return m.count(ctx, key, func(s storage.StorageContext) (int64, error) {
+		return s.DecrementCtx(ctx, key, n)
+	})
+}
+
+/** Expirable Interface **/
+
+func (m *Manager) SetItemWithTTL(key string, value any, ttl time.Duration) (bool, error) {
+	return m.SetItemWithTTLCtx(context.TODO(), key, value, ttl)
+}
+
+func (m *Manager) SetItemsWithTTL(values map[string]any, ttl time.Duration) ([]string, error) {
+	return m.SetItemsWithTTLCtx(context.TODO(), values, ttl)
+}
+
+func (m *Manager) TouchItemWithTTL(key string, ttl time.Duration) bool {
+	return m.TouchItemWithTTLCtx(context.TODO(), key, ttl)
+}
+
+func (m *Manager) GetTTL(key string) (time.Duration, error) {
+	return m.GetTTLCtx(context.TODO(), key)
+}
+
+// SetItemWithTTLCtx sets the value in the tiers that writes go through to, with the ttl
+func (m *Manager) SetItemWithTTLCtx(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+	return m.write(ctx, []string{key}, func(s storage.Storage) (bool, error) {
+		return storage.SetItemWithTTL(ctx, s, key, value, ttl)
+	})
+}
+
+// SetItemsWithTTLCtx sets the values in the tiers that writes go through to, with the ttl. Returns the keys
+func (m *Manager) SetItemsWithTTLCtx(ctx context.Context, values map[string]any, ttl time.Duration) ([]string, error) {
+	if !m.options.Writable() {
+		return []string{}, errors.ErrNotWritable
+	}
+	keys := keysOf(values)
+	_, err := m.write(ctx, keys, func(s storage.Storage) (bool, error) {
+		_, err := storage.SetItemsWithTTL(ctx, s, values, ttl)
+		return err == nil, err
+	})
+	return keys, err
+}
+
+// TouchItemWithTTLCtx resets the TTL of the key in every tier to ttl. Returns true if any tier has the key
+func (m *Manager) TouchItemWithTTLCtx(ctx context.Context, key string, ttl time.Duration) bool {
+	if !m.options.Writable() {
+		return false
+	}
+	hit := false
+	for i := len(m.tiers) - 1; i >= 0; i-- {
+		hit = storage.TouchItemWithTTL(ctx, m.tiers[i].Storage, key, ttl) || hit
+	}
+	return hit
+}
+
+// GetTTLCtx returns the remaining TTL of the key in the first tier, from the top down, that has it
+func (m *Manager) GetTTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	for _, t := range m.tiers {
+		e, ok := t.Storage.(storage.Expirable)
+		if !ok {
+			continue
+		}
+		ttl, err := e.GetTTLCtx(ctx, key)
+		if err == nil {
+			return ttl, nil
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+	}
+	return 0, errors.ErrKeyNotFound
+}
+
+/** Flushable Interface **/
+
+func (m *Manager) Flush() error {
+	return m.FlushCtx(context.TODO())
+}
+
+// FlushCtx flushes every tier that is storage.Flushable, and returns the first error, if any
+func (m *Manager) FlushCtx(ctx context.Context) error {
+	return m.each(func(s storage.Storage) error {
+		if f, ok := s.(storage.Flushable); ok {
+			return f.FlushCtx(ctx)
+		}
+		return nil
+	})
+}
+
+/** Clearable Interface **/
+
+func (m *Manager) ClearByPrefix(prefix string) error {
+	return m.ClearByPrefixCtx(context.TODO(), prefix)
+}
+
+func (m *Manager) ClearByPattern(pattern string) error {
+	return m.ClearByPatternCtx(context.TODO(), pattern)
+}
+
+// ClearByPrefixCtx clears the prefix from every tier that is storage.Clearable, and returns the first error, if any
+func (m *Manager) ClearByPrefixCtx(ctx context.Context, prefix string) error {
+	return m.each(func(s storage.Storage) error {
+		if c, ok := s.(storage.Clearable); ok {
+			return c.ClearByPrefixCtx(ctx, prefix)
+		}
+		return nil
+	})
+}
+
+// ClearByPatternCtx clears the pattern from every tier that is storage.Clearable, and returns the first error, if any
+func (m *Manager) ClearByPatternCtx(ctx context.Context, pattern string) error {
+	return m.each(func(s storage.Storage) error {
+		if c, ok := s.(storage.Clearable); ok {
+			return c.ClearByPatternCtx(ctx, pattern)
+		}
+		return nil
+	})
+}
+
+// each calls fn for every tier, from the bottom up, and returns the first error, if any
+func (m *Manager) each(fn func(s storage.Storage) error) error {
+	var ret error
+	for i := len(m.tiers) - 1; i >= 0; i-- {
+		if err := fn(m.tiers[i].Storage); err != nil && ret == nil {
+			ret = errs.Wrap(err, fmt.Sprintf("tier %d", i))
+		}
+	}
+	return ret
+}
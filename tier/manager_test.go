@@ -0,0 +1,253 @@
+package tier_test
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/chippyash/go-cache-manager/tier"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTiers(t *testing.T) (storage.Storage, storage.Storage, storage.Storage) {
+	top, err := memory.New("top:", time.Minute, time.Minute*2).Open()
+	assert.NoError(t, err)
+	middle, err := memory.New("middle:", time.Hour, time.Hour*2).Open()
+	assert.NoError(t, err)
+	bottom, err := memory.New("bottom:", time.Hour*24, time.Hour*48).Open()
+	assert.NoError(t, err)
+	return top, middle, bottom
+}
+
+func TestManager_Lookup(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle, NoBackfill: true}, tier.Tier{Storage: bottom})
+	_, err := bottom.(storage.Expirable).SetItemWithTTL("foo", "bar", time.Second*30)
+	assert.NoError(t, err)
+	_, err = bottom.SetItem("baz", "qux")
+	assert.NoError(t, err)
+
+	res, err := sut.Lookup(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, tier.Result{Value: "bar", Tier: 2}, res)
+	assert.False(t, middle.HasItem("foo"))
+	//backfilled with the remaining TTL of the value in the bottom tier
+	ttl, err := top.(storage.Expirable).GetTTL("foo")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second*30)
+	assert.Greater(t, ttl, time.Second*25)
+	res, err = sut.Lookup(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, tier.Result{Value: "bar", Tier: 0}, res)
+
+	//backfilled with no more than the default TTL of the top tier
+	val, err := sut.GetItem("baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "qux", val)
+	ttl, err = top.(storage.Expirable).GetTTL("baz")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Minute)
+	assert.Greater(t, ttl, time.Second*55)
+
+	res, err = sut.Lookup(context.Background(), "none")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.Equal(t, tier.NotFound, res.Tier)
+
+	results, err := sut.LookupItems(context.Background(), []string{"foo", "baz", "none"})
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.Equal(t, map[string]tier.Result{"foo": {Value: "bar", Tier: 0}, "baz": {Value: "qux", Tier: 0}}, results)
+}
+
+func TestManager_BackfillTTL(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(
+		tier.Tier{Storage: top, BackfillTTL: time.Second * 10},
+		tier.Tier{Storage: middle, BackfillTTL: tier.OwnTTL},
+		tier.Tier{Storage: bottom},
+	)
+	_, err := bottom.(storage.Expirable).SetItemWithTTL("foo", "bar", time.Second*30)
+	assert.NoError(t, err)
+
+	_, err = sut.GetItem("foo")
+	assert.NoError(t, err)
+	ttl, err := top.(storage.Expirable).GetTTL("foo")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second*10)
+	assert.Greater(t, ttl, time.Second*5)
+	ttl, err = middle.(storage.Expirable).GetTTL("foo")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute*59)
+}
+
+func TestManager_WriteThrough(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle, NoWriteThrough: true}, tier.Tier{Storage: bottom})
+	_, err := middle.SetItem("foo", "stale")
+	assert.NoError(t, err)
+
+	ok, err := sut.SetItem("foo", "bar")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.True(t, top.HasItem("foo"))
+	assert.False(t, middle.HasItem("foo"))
+	assert.True(t, bottom.HasItem("foo"))
+
+	keys, err := sut.SetItems(map[string]any{"key1": 1, "key2": 2})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key1", "key2"}, keys)
+	assert.Equal(t, map[string]bool{"key1": true, "key2": true}, sut.HasItems([]string{"key1", "key2"}))
+	assert.False(t, middle.HasItem("key1"))
+
+	ok, err = sut.SetItemWithTTL("short", "lived", time.Second*5)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ttl, err := bottom.(storage.Expirable).GetTTL("short")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second*5)
+	ttl, err = sut.GetTTL("short")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second*5)
+
+	sut.SetOptions(storage.StorageOptions{storage.OptWritable: false})
+	ok, err = sut.SetItem("foo", "baz")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errors.ErrNotWritable)
+}
+
+func TestManager_ReportsFailures(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle, NoWriteThrough: true}, tier.Tier{Storage: bottom})
+	_, err := middle.SetItem("foo", "stale")
+	assert.NoError(t, err)
+	middle.GetOptions()[storage.OptWritable] = false
+	failed := make(map[storage.Storage][]string)
+	ctx := storage.WithFailureReporter(context.Background(), func(s storage.Storage, err error) {
+		failed[s] = append(failed[s], err.Error())
+	})
+
+	//the value is written, but the stale value cannot be removed from the middle tier
+	ok, err := sut.SetItemCtx(ctx, "foo", "bar")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tier 1: failed to remove foo"}, failed[middle])
+
+	//the value is read, but the top tier cannot be backfilled
+	top.GetOptions()[storage.OptWritable] = false
+	_, err = bottom.SetItem("baz", "qux")
+	assert.NoError(t, err)
+	val, err := sut.GetItemCtx(ctx, "baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "qux", val)
+	assert.Equal(t, []string{"tier 0: failed to backfill baz: " + errors.ErrNotWritable.Error()}, failed[top])
+}
+
+func TestManager_CheckAndSetItem(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle}, tier.Tier{Storage: bottom})
+	_, err := bottom.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	ok, err := sut.CheckAndSetItem("foo", "baz")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	val, err := bottom.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", val)
+	assert.False(t, top.HasItem("foo"))
+
+	ok, err = sut.CheckAndSetItem("none", "baz")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+
+	keys, err := sut.CheckAndSetItems(map[string]any{"foo": "qux", "none": "qux"})
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.Equal(t, []string{"foo"}, keys)
+}
+
+func TestManager_RemoveItem(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle, NoWriteThrough: true}, tier.Tier{Storage: bottom})
+	for _, s := range []storage.Storage{top, middle, bottom} {
+		_, err := s.SetItems(map[string]any{"key1": 1, "key2": 2, "key3": 3})
+		assert.NoError(t, err)
+	}
+
+	assert.True(t, sut.RemoveItem("key1"))
+	assert.False(t, sut.HasItem("key1"))
+	assert.False(t, middle.HasItem("key1"))
+	assert.ElementsMatch(t, []string{"key2", "key3"}, sut.RemoveItems([]string{"key2", "key3"}))
+	assert.Equal(t, map[string]bool{"key2": false, "key3": false}, sut.HasItems([]string{"key2", "key3"}))
+}
+
+func TestManager_Increment(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle}, tier.Tier{Storage: bottom, NoWriteThrough: true})
+	_, err := sut.SetItem("counter", int64(1))
+	assert.NoError(t, err)
+
+	n, err := sut.Increment("counter", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.False(t, top.HasItem("counter"))
+	n, err = sut.Decrement("counter", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	res, err := sut.Lookup(context.Background(), "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, tier.Result{Value: int64(2), Tier: 1}, res)
+}
+
+func TestManager_TouchItem(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle}, tier.Tier{Storage: bottom})
+	_, err := bottom.(storage.Expirable).SetItemWithTTL("foo", "bar", time.Second)
+	assert.NoError(t, err)
+
+	assert.True(t, sut.TouchItem("foo"))
+	ttl, err := bottom.(storage.Expirable).GetTTL("foo")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Hour)
+	assert.True(t, sut.TouchItemWithTTL("foo", time.Minute*5))
+	ttl, err = bottom.(storage.Expirable).GetTTL("foo")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Minute*5)
+	assert.False(t, sut.TouchItem("none"))
+	assert.Equal(t, []string{"foo"}, sut.TouchItems([]string{"foo", "none"}))
+}
+
+func TestManager_FlushAndClear(t *testing.T) {
+	top, middle, bottom := newTiers(t)
+	sut := tier.New(tier.Tier{Storage: top}, tier.Tier{Storage: middle}, tier.Tier{Storage: bottom})
+	_, err := sut.SetItems(map[string]any{"users:1": 1, "users:2": 2, "orders:1": 1, "orders:2": 2})
+	assert.NoError(t, err)
+
+	assert.NoError(t, sut.ClearByPrefix("users:"))
+	assert.False(t, bottom.HasItem("users:1"))
+	assert.True(t, bottom.HasItem("orders:1"))
+	assert.NoError(t, sut.ClearByPattern("orders:1"))
+	assert.False(t, top.HasItem("orders:1"))
+	assert.True(t, top.HasItem("orders:2"))
+	assert.NoError(t, sut.Flush())
+	assert.False(t, sut.HasItem("orders:2"))
+}
+
+func TestManager_OpenAndClose(t *testing.T) {
+	sut := tier.New(
+		tier.Tier{Storage: memory.New("top:", time.Minute, time.Minute*2)},
+		tier.Tier{Storage: memory.New("bottom:", time.Hour, time.Hour*2)},
+	)
+	s, err := sut.Open()
+	assert.NoError(t, err)
+	assert.Same(t, sut, s)
+	assert.Len(t, sut.Tiers(), 2)
+	assert.NoError(t, sut.Close())
+
+	assert.Panics(t, func() {
+		tier.New()
+	})
+	assert.Panics(t, func() {
+		tier.New(tier.Tier{})
+	})
+}
@@ -0,0 +1,86 @@
+// Package otel bridges the adapter tracing hooks to OpenTelemetry
+package otel
+
+import (
+	"context"
+	"fmt"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/tracing"
+	errs "github.com/pkg/errors"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer
+const ScopeName = "github.com/chippyash/go-cache-manager"
+
+// Attribute keys of the spans
+const (
+	AttrOperation = attribute.Key("gcm.operation")
+	AttrKey       = attribute.Key("gcm.key")
+	AttrKeys      = attribute.Key("gcm.keys")
+	AttrNamespace = attribute.Key("gcm.namespace")
+	AttrAdapter   = attribute.Key("gcm.adapter")
+	AttrTier      = attribute.Key("gcm.tier")
+	AttrHit       = attribute.Key("gcm.hit")
+	AttrHits      = attribute.Key("gcm.hits")
+	AttrMisses    = attribute.Key("gcm.misses")
+)
+
+// Tracer is a tracing.Tracer that starts OpenTelemetry spans
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a tracer that starts spans with the trace provider, or the global trace provider if tp is nil
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otelapi.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(ScopeName)}
+}
+
+// Start starts a client span named "<operation> <adapter>", e.g. "getItem valkey"
+func (t *Tracer) Start(ctx context.Context, attrs tracing.Attributes) (context.Context, tracing.Span) {
+	kvs := []attribute.KeyValue{
+		AttrOperation.String(string(attrs.Operation)),
+		AttrNamespace.String(attrs.Namespace),
+		AttrAdapter.String(attrs.Adapter),
+		AttrTier.Int(attrs.Tier),
+	}
+	if attrs.Key != "" {
+		kvs = append(kvs, AttrKey.String(attrs.Key))
+	}
+	if len(attrs.Keys) > 0 {
+		kvs = append(kvs, AttrKeys.StringSlice(attrs.Keys))
+	}
+	ctx, span := t.tracer.Start(ctx, fmt.Sprintf("%s %s", attrs.Operation, attrs.Adapter),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(kvs...),
+	)
+	return ctx, &otelSpan{span: span}
+}
+
+// otelSpan is the tracing.Span returned by a Tracer
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetHit(hits, misses int) {
+	s.span.SetAttributes(
+		AttrHit.Bool(hits > 0 && misses == 0),
+		AttrHits.Int(hits),
+		AttrMisses.Int(misses),
+	)
+}
+
+// End ends the span, recording the error if it is not errors.ErrKeyNotFound
+func (s *otelSpan) End(err error) {
+	if err != nil && !errs.Is(err, errors.ErrKeyNotFound) {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,52 @@
+package otel_test
+
+import (
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/tracing"
+	"github.com/chippyash/go-cache-manager/tracing/otel"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+	"time"
+)
+
+func TestTracer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	sut := tracing.Trace(memory.New("app:", time.Second*60, time.Second*120), otel.NewTracer(tp))
+
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	_, err = sut.GetItem("foo")
+	assert.NoError(t, err)
+	_, err = sut.Increment("foo", 1)
+	assert.Error(t, err)
+
+	spans := rec.Ended()
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range spans {
+		byName[s.Name()] = s
+	}
+	get := byName["getItem memory"]
+	assert.NotNil(t, get)
+	assert.Equal(t, trace.SpanKindClient, get.SpanKind())
+	attrs := attribute.NewSet(get.Attributes()...)
+	for k, v := range map[attribute.Key]attribute.Value{
+		otel.AttrOperation: attribute.StringValue("getItem"),
+		otel.AttrKey:       attribute.StringValue("foo"),
+		otel.AttrNamespace: attribute.StringValue("app:"),
+		otel.AttrAdapter:   attribute.StringValue("memory"),
+		otel.AttrTier:      attribute.IntValue(0),
+		otel.AttrHit:       attribute.BoolValue(true),
+	} {
+		got, ok := attrs.Value(k)
+		assert.True(t, ok, k)
+		assert.Equal(t, v, got, k)
+	}
+	assert.Equal(t, codes.Unset, get.Status().Code)
+	assert.Equal(t, codes.Error, byName["increment memory"].Status().Code)
+}
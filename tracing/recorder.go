@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a span recorded by a Recorder
+type RecordedSpan struct {
+	Attributes
+	//ID the span id, starting from 1 in the order the spans are started
+	ID int
+	//ParentID the ID of the parent span, or 0 if there is none
+	ParentID int
+	Hits     int
+	Misses   int
+	Err      error
+	Start    time.Time
+	//End the time the span ended, or the zero time if it has not
+	End time.Time
+}
+
+// Ended returns true if the span has ended
+func (s RecordedSpan) Ended() bool {
+	return !s.End.IsZero()
+}
+
+// Recorder is a Tracer that records the spans in memory, for use in tests. It is safe for concurrent use
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewRecorder returns a new recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+type parentKey struct{}
+
+// Start starts and records a span
+func (r *Recorder) Start(ctx context.Context, attrs Attributes) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	span := &RecordedSpan{
+		Attributes: attrs,
+		ID:         len(r.spans) + 1,
+		Start:      time.Now(),
+	}
+	span.Keys = slices.Clone(attrs.Keys)
+	if parent, ok := ctx.Value(parentKey{}).(int); ok {
+		span.ParentID = parent
+	}
+	r.spans = append(r.spans, span)
+	return context.WithValue(ctx, parentKey{}, span.ID), &recordedSpan{r: r, span: span}
+}
+
+// Spans returns copies of the recorded spans in the order they were started
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := make([]RecordedSpan, len(r.spans))
+	for i, s := range r.spans {
+		ret[i] = *s
+	}
+	return ret
+}
+
+// Reset removes the recorded spans
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// recordedSpan is the Span returned by a Recorder
+type recordedSpan struct {
+	r    *Recorder
+	span *RecordedSpan
+}
+
+func (s *recordedSpan) SetHit(hits, misses int) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.span.Hits, s.span.Misses = hits, misses
+}
+
+func (s *recordedSpan) End(err error) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.span.Err = err
+	s.span.End = time.Now()
+}
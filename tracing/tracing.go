@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/storage"
+)
+
+// Attributes describe the operation a span is started for
+type Attributes struct {
+	//Operation the operation, e.g. storage.OpGetItem
+	Operation storage.Operation
+	//Key the item key, for single item operations
+	Key string
+	//Keys the item keys, for multiple item operations
+	Keys []string
+	//Namespace the adapter namespace
+	Namespace string
+	//Adapter the adapter Name
+	Adapter string
+	//Tier the position of the adapter in the chain being called. 0 is the adapter that is called, 1 the adapter
+	//chained to it and so on
+	Tier int
+}
+
+// Tracer starts spans for adapter operations
+type Tracer interface {
+	//Start starts a span for an operation and returns a context holding the span, which is passed to the
+	//operation, so that the spans of chained lookups are its children
+	Start(ctx context.Context, attrs Attributes) (context.Context, Span)
+}
+
+// Span is an operation span started by a Tracer
+type Span interface {
+	//SetHit records the number of items found and not found by a read operation
+	SetHit(hits, misses int)
+	//End ends the span with the operation error, if any. A read that does not find the item ends with
+	//errors.ErrKeyNotFound
+	End(err error)
+}
+
+// Traceable is implemented by adapters whose operations can be traced
+type Traceable interface {
+	//SetTracer sets the tracer that the adapter starts a span with for each operation. A nil tracer stops tracing
+	SetTracer(t Tracer)
+}
+
+// Trace sets the tracer on s, and each adapter chained below it, that is Traceable. Decorated adapters are unwrapped.
+// Returns s
+func Trace(s storage.Storage, t Tracer) storage.Storage {
+	for a := s; a != nil; {
+		a = decorator.Unwrap(a)
+		if tr, ok := a.(Traceable); ok {
+			tr.SetTracer(t)
+		}
+		c, ok := a.(storage.Chainable)
+		if !ok {
+			break
+		}
+		a = c.GetChained()
+	}
+	return s
+}
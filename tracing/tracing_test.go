@@ -0,0 +1,78 @@
+package tracing_test
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/metrics"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/chippyash/go-cache-manager/tracing"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTrace_Chain(t *testing.T) {
+	top := memory.New("app:", time.Second*60, time.Second*120)
+	chained := memory.New("app:", time.Second*60, time.Second*120)
+	top.(storage.Chainable).ChainAdapter(chained)
+	_, err := chained.SetItem("foo", "bar")
+	assert.NoError(t, err)
+
+	rec := tracing.NewRecorder()
+	sut := tracing.Trace(top, rec)
+	_, err = sut.(storage.StorageContext).GetItemCtx(context.Background(), "foo")
+	assert.NoError(t, err)
+
+	spans := rec.Spans()
+	assert.NotEmpty(t, spans)
+	root := spans[0]
+	assert.Equal(t, storage.OpGetItem, root.Operation)
+	assert.Equal(t, "foo", root.Key)
+	assert.Equal(t, "app:", root.Namespace)
+	assert.Equal(t, "memory", root.Adapter)
+	assert.Equal(t, 0, root.Tier)
+	assert.Equal(t, 0, root.ParentID)
+	assert.Equal(t, 1, root.Hits)
+	assert.True(t, root.Ended())
+	assert.NoError(t, root.Err)
+
+	//the chained lookup is a child span in tier 1
+	lookup := spans[1]
+	assert.Equal(t, storage.OpGetItem, lookup.Operation)
+	assert.Equal(t, 1, lookup.Tier)
+	assert.Equal(t, root.ID, lookup.ParentID)
+	assert.Equal(t, 1, lookup.Hits)
+
+	//the item found in the chain is set in the top tier
+	set := spans[2]
+	assert.Equal(t, storage.OpSetItem, set.Operation)
+	assert.Equal(t, 0, set.Tier)
+	assert.Equal(t, root.ID, set.ParentID)
+
+	rec.Reset()
+	_, err = sut.GetItem("baz")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	spans = rec.Spans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, 1, spans[0].Misses)
+	assert.ErrorIs(t, spans[0].Err, errors.ErrKeyNotFound)
+	assert.Equal(t, 1, spans[1].Misses)
+
+	//tracing is stopped with a nil tracer
+	rec.Reset()
+	tracing.Trace(sut, nil)
+	_, _ = sut.GetItem("foo")
+	assert.Empty(t, rec.Spans())
+}
+
+func TestTrace_Decorated(t *testing.T) {
+	rec := tracing.NewRecorder()
+	sut := tracing.Trace(metrics.NewCollector().Instrument(memory.New("", time.Second*60, time.Second*120)), rec)
+	_, err := sut.SetItems(map[string]any{"b": 1, "a": 2})
+	assert.NoError(t, err)
+
+	spans := rec.Spans()
+	assert.Equal(t, storage.OpSetItems, spans[0].Operation)
+	assert.Equal(t, []string{"a", "b"}, spans[0].Keys)
+}
@@ -0,0 +1,184 @@
+package writebehind
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// entry is a pending write: a value to set with a ttl, or a removal
+type entry struct {
+	value  any
+	ttl    time.Duration
+	remove bool
+}
+
+// item is an entry taken from the queue to be written
+type item struct {
+	key string
+	entry
+}
+
+// queue is a bounded queue of pending writes that keeps the latest write for each key, in the order the keys were
+// first queued. Entries that are taken are in flight until they are done, and a key that is in flight is not taken
+// again until it is done, so that the writes for a key are made in order
+type queue struct {
+	mu       sync.Mutex
+	max      int
+	order    []string
+	pending  map[string]entry
+	inflight map[string]entry
+	closed   bool
+}
+
+func newQueue(max int) *queue {
+	return &queue{
+		max:      max,
+		pending:  make(map[string]entry),
+		inflight: make(map[string]entry),
+	}
+}
+
+// put queues the entries, replacing any pending entries for their keys. Returns false, and queues nothing, if the
+// queue is closed or does not have room for the keys that are not pending
+func (q *queue) put(entries map[string]entry) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	added := 0
+	for key := range entries {
+		if _, ok := q.pending[key]; !ok {
+			added++
+		}
+	}
+	if len(q.pending)+added > q.max {
+		return false
+	}
+	for key, e := range entries {
+		if _, ok := q.pending[key]; !ok {
+			q.order = append(q.order, key)
+		}
+		q.pending[key] = e
+	}
+	return true
+}
+
+// get returns the latest pending or in flight entry for the key
+func (q *queue) get(key string) (entry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if e, ok := q.pending[key]; ok {
+		return e, true
+	}
+	e, ok := q.inflight[key]
+	return e, ok
+}
+
+// take returns up to n pending entries, oldest first, whose keys are not in flight, and puts them in flight
+func (q *queue) take(n int) []item {
+	return q.takeIf(n, func(string) bool { return true })
+}
+
+// takeKeys returns the pending entries for the keys that are not in flight, and puts them in flight
+func (q *queue) takeKeys(keys []string) []item {
+	return q.takeIf(len(keys), func(key string) bool { return slices.Contains(keys, key) })
+}
+
+func (q *queue) takeIf(n int, match func(key string) bool) []item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ret := make([]item, 0, min(n, len(q.order)))
+	order := q.order[:0]
+	for _, key := range q.order {
+		_, busy := q.inflight[key]
+		if len(ret) == n || busy || !match(key) {
+			order = append(order, key)
+			continue
+		}
+		e := q.pending[key]
+		delete(q.pending, key)
+		q.inflight[key] = e
+		ret = append(ret, item{key: key, entry: e})
+	}
+	clear(q.order[len(order):])
+	q.order = order
+	return ret
+}
+
+// done takes the written items out of flight
+func (q *queue) done(items []item) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, it := range items {
+		delete(q.inflight, it.key)
+	}
+}
+
+// discard removes the pending entries for the keys
+func (q *queue) discard(keys []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, key := range keys {
+		delete(q.pending, key)
+	}
+	q.order = slices.DeleteFunc(q.order, func(key string) bool {
+		_, ok := q.pending[key]
+		return !ok
+	})
+}
+
+// discardAll removes all the pending entries
+func (q *queue) discardAll() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	clear(q.pending)
+	q.order = nil
+}
+
+// busy returns true if any of the keys is pending or in flight, or if keys is nil, if any key is
+func (q *queue) busy(keys []string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if keys == nil {
+		return len(q.pending) > 0 || len(q.inflight) > 0
+	}
+	for _, key := range keys {
+		_, pending := q.pending[key]
+		_, inflight := q.inflight[key]
+		if pending || inflight {
+			return true
+		}
+	}
+	return false
+}
+
+// inflightAny returns true if any of the keys is in flight, or if keys is nil, if any key is
+func (q *queue) inflightAny(keys []string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if keys == nil {
+		return len(q.inflight) > 0
+	}
+	for _, key := range keys {
+		if _, ok := q.inflight[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// len returns the number of pending entries
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// close stops entries being queued
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+}
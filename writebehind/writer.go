@@ -0,0 +1,436 @@
+package writebehind
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	errs "github.com/pkg/errors"
+	"maps"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultQueueSize     = 10000
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultWorkers       = 1
+	DefaultMaxRetries    = 3
+	DefaultRetryInterval = time.Millisecond * 100
+)
+
+// errNotSet is passed to Options.OnError when the storage does not set an item, but does not return an error
+var errNotSet = errs.New("item not set")
+
+// Options configures a Writer. Zero values use the defaults
+type Options struct {
+	//QueueSize is the maximum number of keys with pending writes. A write that does not fit in the queue is made
+	//synchronously. Defaults to DefaultQueueSize
+	QueueSize int
+	//BatchSize is the maximum number of writes made at a time. The workers write as soon as a batch is pending.
+	//Defaults to DefaultBatchSize
+	BatchSize int
+	//FlushInterval is how often the workers write the pending writes that do not fill a batch.
+	//Defaults to DefaultFlushInterval
+	FlushInterval time.Duration
+	//Workers is the number of background workers. Defaults to DefaultWorkers
+	Workers int
+	//MaxRetries is the number of times a failed write is retried. Defaults to DefaultMaxRetries, a negative value
+	//does not retry
+	MaxRetries int
+	//RetryInterval is the wait before the first retry, doubled for each further retry. Defaults to DefaultRetryInterval
+	RetryInterval time.Duration
+	//OnError, if set, is called with the key and the last error of a write that fails after its retries
+	OnError func(key string, err error)
+}
+
+// withDefaults returns the options with the defaults for the zero values
+func (o Options) withDefaults() Options {
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultQueueSize
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DefaultFlushInterval
+	}
+	if o.Workers <= 0 {
+		o.Workers = DefaultWorkers
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = DefaultMaxRetries
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = DefaultRetryInterval
+	}
+	return o
+}
+
+// Writer decorates a storage.Storage so that writes are made asynchronously. SetItem, SetItems, SetItemWithTTL,
+// SetItemsWithTTL, RemoveItem and RemoveItems are queued and return straight away, and background workers write
+// them in batches. Only the latest write for a key is queued, and reads return the queued values. Other
+// operations write the queued writes for their keys, or all the queued writes, before they are called.
+//
+// A Writer is usually chained below another adapter, so that the adapter does not wait on the chained adapter:
+//
+//	wb := writebehind.New(valkeyAdapter, writebehind.Options{})
+//	memoryAdapter.(storage.Chainable).ChainAdapter(wb)
+//
+// Errors from queued writes are passed to Options.OnError, after any retries. Removals are not retried, as
+// storage.Storage does not report why an item is not removed. The Writer must be closed to write the queued writes.
+// Flush writes the queued writes, so Writer does not implement storage.Flushable, but FlushCtx removes all the
+// items from the decorated storage and discards the queued writes
+type Writer struct {
+	*decorator.Decorator
+	inner  storage.Storage
+	opts   Options
+	queue  *queue
+	ready  chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	closed sync.Once
+}
+
+// New returns s decorated as a Writer, and starts its workers
+func New(s storage.Storage, opts Options) *Writer {
+	w := &Writer{
+		inner: s,
+		opts:  opts.withDefaults(),
+		ready: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+	w.queue = newQueue(w.opts.QueueSize)
+	w.Decorator = decorator.New(s, w.intercept)
+	w.wg.Add(w.opts.Workers)
+	for range w.opts.Workers {
+		go w.work()
+	}
+	return w
+}
+
+// Len returns the number of keys with queued writes
+func (w *Writer) Len() int {
+	return w.queue.len()
+}
+
+// Flush writes all the queued writes, and waits for the workers to finish the writes they are making.
+// Returns the context error if the context is done first
+func (w *Writer) Flush(ctx context.Context) error {
+	return w.drain(ctx, nil)
+}
+
+// Open opens the decorated storage and returns the Writer
+func (w *Writer) Open() (storage.Storage, error) {
+	if _, err := w.inner.Open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close writes all the queued writes, stops the workers and closes the decorated storage.
+// Writes made after Close are made synchronously
+func (w *Writer) Close() error {
+	w.closed.Do(func() {
+		w.queue.close()
+		_ = w.Flush(context.Background())
+		close(w.stop)
+		w.wg.Wait()
+	})
+	return w.inner.Close()
+}
+
+// work writes the queued writes, when a batch is pending or every FlushInterval, until the Writer is closed
+func (w *Writer) work() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.ready:
+			for w.queue.len() >= w.opts.BatchSize {
+				if !w.writeBatch(context.Background(), w.queue.take(w.opts.BatchSize)) {
+					break
+				}
+			}
+		case <-ticker.C:
+			for w.writeBatch(context.Background(), w.queue.take(w.opts.BatchSize)) {
+			}
+		}
+	}
+}
+
+// drain writes the queued writes for the keys, or all of them if keys is nil, and waits until none are in flight
+func (w *Writer) drain(ctx context.Context, keys []string) error {
+	for w.queue.busy(keys) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var items []item
+		if keys == nil {
+			items = w.queue.take(w.opts.BatchSize)
+		} else {
+			items = w.queue.takeKeys(keys)
+		}
+		if w.writeBatch(ctx, items) {
+			continue
+		}
+		//the remaining writes are being made by the workers
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// wait waits until none of the keys is in flight
+func (w *Writer) wait(ctx context.Context, keys []string) error {
+	for w.queue.inflightAny(keys) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// enqueue queues the entries, and signals the workers if a batch is pending. If the entries cannot be queued, the
+// pending writes for their keys are discarded, as they are superseded, and false is returned once the keys are not
+// in flight, so that the caller can make the write synchronously
+func (w *Writer) enqueue(ctx context.Context, entries map[string]entry) (bool, error) {
+	if w.queue.put(entries) {
+		if w.queue.len() >= w.opts.BatchSize {
+			select {
+			case w.ready <- struct{}{}:
+			default:
+			}
+		}
+		return true, nil
+	}
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	w.queue.discard(keys)
+	return false, w.wait(ctx, keys)
+}
+
+// writeBatch writes the items, and takes them out of flight. Sets with the same TTL are made together.
+// Returns false if there are no items
+func (w *Writer) writeBatch(ctx context.Context, items []item) bool {
+	if len(items) == 0 {
+		return false
+	}
+	defer w.queue.done(items)
+	sets := make(map[time.Duration]map[string]any)
+	for _, it := range items {
+		if it.remove {
+			storage.WithContext(w.inner).RemoveItemCtx(ctx, it.key)
+			continue
+		}
+		if sets[it.ttl] == nil {
+			sets[it.ttl] = make(map[string]any)
+		}
+		sets[it.ttl][it.key] = it.value
+	}
+	for ttl, values := range sets {
+		if len(values) == 1 {
+			for key, value := range values {
+				w.write(ctx, key, value, ttl)
+			}
+			continue
+		}
+		if _, err := storage.SetItemsWithTTL(ctx, w.inner, values, ttl); err != nil {
+			//the storage does not say reliably which items are set, so each one is written again
+			for key, value := range values {
+				w.write(ctx, key, value, ttl)
+			}
+		}
+	}
+	return true
+}
+
+// write sets the item, retrying if it fails
+func (w *Writer) write(ctx context.Context, key string, value any, ttl time.Duration) {
+	wait := w.opts.RetryInterval
+	for attempt := 0; ; attempt++ {
+		ok, err := storage.SetItemWithTTL(ctx, w.inner, key, value, ttl)
+		if err == nil && ok {
+			return
+		}
+		if err == nil {
+			err = errNotSet
+		}
+		if attempt >= w.opts.MaxRetries || permanent(err) || ctx.Err() != nil {
+			if w.opts.OnError != nil {
+				w.opts.OnError(key, err)
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// permanent returns true if retrying the write cannot succeed
+func permanent(err error) bool {
+	for _, target := range []error{
+		errors.ErrKeyInvalid, errors.ErrKeyTooLong, errors.ErrValueTooLarge, errors.ErrUnsupportedDataType,
+		errors.ErrNotWritable,
+	} {
+		if errs.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// intercept queues the writes and serves the reads of queued keys. Other operations write the queued writes that
+// they depend on first
+func (w *Writer) intercept(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+	switch op {
+	case storage.OpSetItem, storage.OpSetItemWithTTL:
+		queued, err := w.enqueue(ctx, map[string]entry{p.Key: {value: p.Value, ttl: p.TTL}})
+		if queued || err != nil {
+			return queued, err
+		}
+	case storage.OpSetItems, storage.OpSetItemsWithTTL:
+		entries := make(map[string]entry, len(p.Values))
+		keys := make([]string, 0, len(p.Values))
+		for key, value := range p.Values {
+			entries[key] = entry{value: value, ttl: p.TTL}
+			keys = append(keys, key)
+		}
+		queued, err := w.enqueue(ctx, entries)
+		if queued {
+			return keys, nil
+		}
+		if err != nil {
+			return []string{}, err
+		}
+	case storage.OpRemoveItem:
+		queued, err := w.enqueue(ctx, map[string]entry{p.Key: {remove: true}})
+		if queued || err != nil {
+			return queued, err
+		}
+	case storage.OpRemoveItems:
+		entries := make(map[string]entry, len(p.Keys))
+		for _, key := range p.Keys {
+			entries[key] = entry{remove: true}
+		}
+		queued, err := w.enqueue(ctx, entries)
+		if queued {
+			return p.Keys, nil
+		}
+		if err != nil {
+			return []string{}, err
+		}
+	case storage.OpGetItem:
+		if e, ok := w.queue.get(p.Key); ok {
+			if e.remove {
+				return nil, errors.ErrKeyNotFound
+			}
+			return e.value, nil
+		}
+	case storage.OpHasItem:
+		if e, ok := w.queue.get(p.Key); ok {
+			return !e.remove, nil
+		}
+	case storage.OpGetItems:
+		return w.getItems(ctx, p, next)
+	case storage.OpHasItems:
+		return w.hasItems(ctx, p, next)
+	case storage.OpFlush:
+		//the queued writes would be made after the flush
+		w.queue.discardAll()
+		if err := w.wait(ctx, nil); err != nil {
+			return nil, err
+		}
+	default:
+		if err := w.drain(ctx, dependsOn(p)); err != nil {
+			return nil, err
+		}
+	}
+	return next(ctx)
+}
+
+// getItems returns the queued values for the keys that are queued, and gets the others
+func (w *Writer) getItems(ctx context.Context, p *event.Params, next decorator.Next) (any, error) {
+	ret := make(map[string]any)
+	rest := make([]string, 0, len(p.Keys))
+	missing := false
+	for _, key := range p.Keys {
+		e, ok := w.queue.get(key)
+		switch {
+		case !ok:
+			rest = append(rest, key)
+		case e.remove:
+			missing = true
+		default:
+			ret[key] = e.value
+		}
+	}
+	var err error
+	if len(rest) > 0 {
+		keys := p.Keys
+		p.Keys = rest
+		var found any
+		found, err = next(ctx)
+		p.Keys = keys
+		m, _ := found.(map[string]any)
+		maps.Copy(ret, m)
+	}
+	if err == nil && missing {
+		err = errors.ErrKeyNotFound
+	}
+	return ret, err
+}
+
+// hasItems returns whether the keys that are queued are set, and checks the others
+func (w *Writer) hasItems(ctx context.Context, p *event.Params, next decorator.Next) (any, error) {
+	ret := make(map[string]bool)
+	rest := make([]string, 0, len(p.Keys))
+	for _, key := range p.Keys {
+		if e, ok := w.queue.get(key); ok {
+			ret[key] = !e.remove
+			continue
+		}
+		rest = append(rest, key)
+	}
+	if len(rest) > 0 {
+		keys := p.Keys
+		p.Keys = rest
+		found, _ := next(ctx)
+		p.Keys = keys
+		m, _ := found.(map[string]bool)
+		maps.Copy(ret, m)
+	}
+	return ret, nil
+}
+
+// dependsOn returns the keys that an operation with the params depends on, or nil if it may depend on any key
+func dependsOn(p *event.Params) []string {
+	switch {
+	case p.Key != "":
+		return []string{p.Key}
+	case len(p.Keys) > 0:
+		return p.Keys
+	case len(p.Values) > 0:
+		keys := make([]string, 0, len(p.Values))
+		for key := range p.Values {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+	return nil
+}
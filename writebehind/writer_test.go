@@ -0,0 +1,187 @@
+package writebehind_test
+
+import (
+	"context"
+	"github.com/chippyash/go-cache-manager/adapter/memory"
+	"github.com/chippyash/go-cache-manager/decorator"
+	"github.com/chippyash/go-cache-manager/errors"
+	"github.com/chippyash/go-cache-manager/event"
+	"github.com/chippyash/go-cache-manager/storage"
+	"github.com/chippyash/go-cache-manager/writebehind"
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWriter_QueuesWrites(t *testing.T) {
+	inner := memory.New("", time.Minute, time.Minute*2)
+	sut := writebehind.New(inner, writebehind.Options{FlushInterval: time.Hour})
+	defer sut.Close()
+
+	ok, err := sut.SetItem("foo", "bar")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	_, err = sut.SetItem("foo", "baz")
+	assert.NoError(t, err)
+	keys, err := sut.SetItems(map[string]any{"key1": 1, "key2": 2})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key1", "key2"}, keys)
+	_, err = sut.SetItemWithTTL("short", "lived", time.Second*5)
+	assert.NoError(t, err)
+	assert.True(t, sut.RemoveItem("key2"))
+	assert.Equal(t, 4, sut.Len())
+	assert.False(t, inner.HasItem("foo"))
+
+	//reads return the queued writes
+	val, err := sut.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", val)
+	_, err = sut.GetItem("key2")
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	vals, err := sut.GetItems([]string{"foo", "key1", "key2"})
+	assert.ErrorIs(t, err, errors.ErrKeyNotFound)
+	assert.Equal(t, map[string]any{"foo": "baz", "key1": 1}, vals)
+	assert.Equal(t, map[string]bool{"foo": true, "key2": false, "none": false}, sut.HasItems([]string{"foo", "key2", "none"}))
+
+	assert.NoError(t, sut.Flush(context.Background()))
+	assert.Equal(t, 0, sut.Len())
+	val, err = inner.GetItem("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", val)
+	assert.True(t, inner.HasItem("key1"))
+	assert.False(t, inner.HasItem("key2"))
+	ttl, err := inner.(storage.Expirable).GetTTL("short")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second*5)
+}
+
+func TestWriter_Workers(t *testing.T) {
+	inner := memory.New("", time.Minute, time.Minute*2)
+	sut := writebehind.New(inner, writebehind.Options{BatchSize: 2, FlushInterval: time.Millisecond * 10, Workers: 2})
+	defer sut.Close()
+
+	//written when the flush interval elapses
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return inner.HasItem("foo") }, time.Second, time.Millisecond)
+	//written when a batch is pending
+	_, err = sut.SetItems(map[string]any{"key1": 1, "key2": 2, "key3": 3})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return inner.HasItem("key1") && inner.HasItem("key2") && inner.HasItem("key3")
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 0, sut.Len())
+}
+
+func TestWriter_Retries(t *testing.T) {
+	failures := atomic.Int32{}
+	failures.Store(2)
+	inner := memory.New("", time.Minute, time.Minute*2, storage.WithMaxKeyLength(10))
+	failing := decorator.New(inner, func(ctx context.Context, op storage.Operation, p *event.Params, next decorator.Next) (any, error) {
+		if op == storage.OpSetItemWithTTL && failures.Add(-1) >= 0 {
+			return false, errs.New("backend unavailable")
+		}
+		return next(ctx)
+	})
+	mu := sync.Mutex{}
+	failed := make(map[string]error)
+	sut := writebehind.New(failing, writebehind.Options{
+		FlushInterval: time.Hour,
+		RetryInterval: time.Millisecond,
+		OnError: func(key string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed[key] = err
+		},
+	})
+	defer sut.Close()
+
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Flush(context.Background()))
+	assert.True(t, inner.HasItem("foo"))
+	assert.Empty(t, failed)
+
+	//not retried
+	_, err = sut.SetItem("a-very-long-key", "bar")
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Flush(context.Background()))
+	assert.ErrorIs(t, failed["a-very-long-key"], errors.ErrKeyTooLong)
+
+	//retried until MaxRetries
+	failures.Store(10)
+	_, err = sut.SetItem("baz", "qux")
+	assert.NoError(t, err)
+	assert.NoError(t, sut.Flush(context.Background()))
+	assert.EqualError(t, failed["baz"], "backend unavailable")
+	assert.Equal(t, int32(6), failures.Load())
+}
+
+func TestWriter_QueueFull(t *testing.T) {
+	inner := memory.New("", time.Minute, time.Minute*2)
+	sut := writebehind.New(inner, writebehind.Options{QueueSize: 2, BatchSize: 10, FlushInterval: time.Hour})
+	defer sut.Close()
+
+	_, err := sut.SetItems(map[string]any{"key1": 1, "key2": 2})
+	assert.NoError(t, err)
+	_, err = sut.SetItem("key1", 10)
+	assert.NoError(t, err)
+	assert.False(t, inner.HasItem("key1"))
+	//made synchronously
+	_, err = sut.SetItem("key3", 3)
+	assert.NoError(t, err)
+	assert.True(t, inner.HasItem("key3"))
+	_, err = sut.SetItems(map[string]any{"key2": 20, "key4": 4})
+	assert.NoError(t, err)
+	assert.True(t, inner.HasItem("key2"))
+	assert.Equal(t, 1, sut.Len())
+
+	assert.NoError(t, sut.Flush(context.Background()))
+	vals, err := inner.GetItems([]string{"key1", "key2", "key3", "key4"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"key1": 10, "key2": 20, "key3": 3, "key4": 4}, vals)
+}
+
+func TestWriter_OtherOperationsWriteFirst(t *testing.T) {
+	inner := memory.New("", time.Minute, time.Minute*2)
+	sut := writebehind.New(inner, writebehind.Options{FlushInterval: time.Hour})
+	defer sut.Close()
+
+	_, err := sut.SetItems(map[string]any{"counter": 1, "users:1": "one"})
+	assert.NoError(t, err)
+	n, err := sut.Increment("counter", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, 1, sut.Len())
+	assert.NoError(t, sut.ClearByPrefix("users:"))
+	assert.False(t, sut.HasItem("users:1"))
+
+	_, err = sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.NoError(t, sut.FlushCtx(context.Background()))
+	assert.Equal(t, 0, sut.Len())
+	assert.False(t, sut.HasItem("foo"))
+}
+
+func TestWriter_Chained(t *testing.T) {
+	chained := memory.New("", time.Hour, time.Hour*2)
+	wb := writebehind.New(chained, writebehind.Options{FlushInterval: time.Hour})
+	sut := memory.New("", time.Minute, time.Minute*2)
+	sut.(storage.Chainable).ChainAdapter(wb)
+
+	_, err := sut.SetItem("foo", "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, wb.Len())
+	assert.False(t, chained.HasItem("foo"))
+
+	//Close writes the queued writes
+	assert.NoError(t, wb.Close())
+	assert.True(t, chained.HasItem("foo"))
+	//writes after Close are made synchronously
+	_, err = sut.SetItem("baz", "qux")
+	assert.NoError(t, err)
+	assert.True(t, chained.HasItem("baz"))
+}